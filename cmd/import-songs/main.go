@@ -0,0 +1,87 @@
+// Command import-songs adds songs to the catalog from a CSV or JSONL file, the same way
+// POST /admin/songs/import does, and prints a report line for every row that was not
+// imported.
+//
+//	import-songs [-format csv|jsonl] [-dry-run] songs.csv
+//
+// The format defaults to the file's extension. Use - to read standard input. The exit status
+// is 1 if any row was invalid or failed.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	_ "github.com/lib/pq"
+	"io"
+	"log"
+	"louderspace/config"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	format := flag.String("format", "", "csv or jsonl; defaults to the file's extension")
+	dryRun := flag.Bool("dry-run", false, "validate the file without importing anything")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatalf("usage: import-songs [-format csv|jsonl] [-dry-run] FILE")
+	}
+	path := flag.Arg(0)
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = string(models.SongImportCSV)
+		case ".jsonl", ".ndjson":
+			*format = string(models.SongImportJSONL)
+		default:
+			log.Fatalf("cannot tell the format of %s; pass -format csv or -format jsonl", path)
+		}
+	}
+
+	var file io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("failed to open %s: %v", path, err)
+		}
+		defer f.Close()
+		file = f
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	songService := services.NewSongService(repositories.NewSongDatabase(db))
+	importService := services.NewSongImportService(songService, repositories.NewTagDatabase(db))
+	report, err := importService.Import(file, models.SongImportFormat(*format), *dryRun)
+	if err != nil {
+		log.Fatalf("failed to import %s: %v", path, err)
+	}
+
+	for _, row := range report.Rows {
+		if row.Status == models.SongImportInvalid || row.Status == models.SongImportFailed {
+			fmt.Printf("line %d: %s: %s\n", row.Line, row.Status, strings.Join(row.Errors, "; "))
+		}
+	}
+	if report.DryRun {
+		fmt.Printf("dry run: %d rows, %d valid, %d invalid\n", report.Total, report.Valid, report.Invalid)
+	} else {
+		fmt.Printf("%d rows, %d created, %d invalid, %d failed\n", report.Total, report.Created, report.Invalid, report.Failed)
+	}
+	if report.Invalid > 0 || report.Failed > 0 {
+		os.Exit(1)
+	}
+}
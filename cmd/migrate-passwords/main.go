@@ -0,0 +1,45 @@
+// Command migrate-passwords flags the accounts whose passwords were hashed twice at
+// registration, so they are asked to reset their password.
+//
+// Registration used to bcrypt the password in the handler and again in the user service.
+// The inner hash was never stored, so these passwords cannot be checked or recovered. Every
+// account with a password created before -before is flagged; login then answers "password
+// reset required" instead of "invalid password" for them. Accounts that were hashed
+// correctly, such as seeded ones, are unflagged on their next successful login.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	_ "github.com/lib/pq"
+	"log"
+	"louderspace/config"
+	"louderspace/internal/repositories"
+	"time"
+)
+
+func main() {
+	before := flag.String("before", "", "flag accounts created before this RFC 3339 time, i.e. when the fix was deployed")
+	flag.Parse()
+
+	cutoff, err := time.Parse(time.RFC3339, *before)
+	if err != nil {
+		log.Fatalf("-before must be an RFC 3339 time such as 2024-06-01T00:00:00Z: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	flagged, err := repositories.NewUserDatabase(db).RequirePasswordReset(cutoff)
+	if err != nil {
+		log.Fatalf("failed to flag accounts: %v", err)
+	}
+	log.Printf("flagged %d accounts created before %s for a password reset", flagged, cutoff.Format(time.RFC3339))
+}
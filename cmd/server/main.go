@@ -2,19 +2,28 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
 	"log"
 	"louderspace/config"
 	"louderspace/internal/api"
 	"louderspace/internal/logger"
+	"louderspace/internal/mailer"
 	"louderspace/internal/middleware"
 	"louderspace/internal/models"
+	"louderspace/internal/oidc"
+	"louderspace/internal/passwords"
 	"louderspace/internal/repositories"
 	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"louderspace/suno"
 	"net/http"
 	"os"
+	"time"
 )
 
 func main() {
@@ -30,6 +39,33 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	var signingKeys []*utils.SigningKey
+	for _, k := range cfg.JWTKeys {
+		key, err := utils.LoadSigningKey(k.ID, k.Algorithm, k.Secret, k.PrivateKeyFile, k.PublicKeyFile)
+		if err != nil {
+			log.Fatalf("failed to load JWT signing key: %v", err)
+		}
+		signingKeys = append(signingKeys, key)
+	}
+	keySet, err := utils.NewKeySet(cfg.JWTActiveKeyID, signingKeys...)
+	if err != nil {
+		log.Fatalf("failed to build JWT key set: %v", err)
+	}
+
+	var mail mailer.Mailer
+	switch cfg.Mailer {
+	case "smtp":
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "file":
+		fileMailer, err := mailer.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+		if err != nil {
+			log.Fatalf("failed to create mail directory: %v", err)
+		}
+		mail = fileMailer
+	default:
+		log.Fatalf("unknown mailer %q", cfg.Mailer)
+	}
+
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
@@ -42,28 +78,128 @@ func main() {
 	playEventStorage := repositories.NewPlayEventDatabase(db)
 	feedbackStorage := repositories.NewFeedbackDatabase(db)
 	pomodoroSessionStorage := repositories.NewPomodoroSessionDatabase(db)
+	tokenStorage := repositories.NewTokenDatabase(db)
+	auditStorage := repositories.NewAuditDatabase(db)
+	oneTimeTokenStorage := repositories.NewOneTimeTokenDatabase(db)
+	loginAttemptStorage := repositories.NewLoginAttemptDatabase(db)
+	apiKeyStorage := repositories.NewAPIKeyDatabase(db)
+	twoFactorStorage := repositories.NewTwoFactorDatabase(db)
+	identityStorage := repositories.NewIdentityDatabase(db)
+	inviteStorage := repositories.NewInviteDatabase(db)
+	subscriptionStorage := repositories.NewSubscriptionDatabase(db)
 
-	userService := services.NewUserService(userStorage)
+	passwordPolicy := &passwords.Policy{MinLength: cfg.Passwords.MinLength, MaxLength: passwords.MaxBytes}
+	for _, class := range cfg.Passwords.CharacterClasses {
+		if !passwords.CharacterClass(class).Valid() {
+			log.Fatalf("invalid password character class %q", class)
+		}
+		passwordPolicy.RequiredClasses = append(passwordPolicy.RequiredClasses, passwords.CharacterClass(class))
+	}
+	if cfg.Passwords.ScreenBreached {
+		passwordPolicy.Breached = passwords.BundledRanges()
+		if cfg.Passwords.BreachedList != "" {
+			file, err := os.Open(cfg.Passwords.BreachedList)
+			if err != nil {
+				log.Fatalf("failed to open breached password list: %v", err)
+			}
+			passwordPolicy.Breached, err = passwords.LoadRanges(file)
+			file.Close()
+			if err != nil {
+				log.Fatalf("failed to load breached password list: %v", err)
+			}
+		}
+	}
+
+	passwordHasher, err := newPasswordHasher(cfg.Passwords)
+	if err != nil {
+		log.Fatalf("invalid password hashing configuration: %v", err)
+	}
+
+	userService := services.NewUserService(userStorage, passwordPolicy, passwordHasher)
 	stationService := services.NewStationService(stationStorage, feedbackStorage, songStorage)
 	playbackService := services.NewPlaybackService(stationStorage)
 	songService := services.NewSongService(songStorage)
+	songIngestService := services.NewSongIngestService(songService, suno.GetClip)
+	songImportService := services.NewSongImportService(songService, tagStorage)
+	catalogExportService := services.NewCatalogExportService(songStorage, stationStorage)
 	tagService := services.NewTagService(tagStorage)
+	searchService := services.NewSearchService(songStorage, stationStorage, tagStorage)
 	playEventService := services.NewPlayEventService(playEventStorage)
 	feedbackService := services.NewFeedbackService(feedbackStorage)
 	pomodoroSessionService := services.NewPomodoroSessionService(pomodoroSessionStorage)
+	tokenService := services.NewTokenService(tokenStorage, userStorage, keySet, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	impersonationService := services.NewImpersonationService(userStorage, auditStorage)
+	roleService := services.NewRoleService(userStorage, tokenService)
+	userAdminService := services.NewUserAdminService(userStorage, auditStorage, tokenService)
+	loginThrottleService := services.NewLoginThrottleService(loginAttemptStorage, userStorage, auditStorage)
+	apiKeyService := services.NewAPIKeyService(apiKeyStorage, userStorage)
+	exportService := services.NewExportService(feedbackStorage, playEventStorage, pomodoroSessionStorage, playbackService, auditStorage)
+	accountService := services.NewAccountService(userStorage, oneTimeTokenStorage, tokenService, mail, cfg.AppBaseURL, passwordPolicy, passwordHasher)
+	twoFactorService := services.NewTwoFactorService(twoFactorStorage, oneTimeTokenStorage, userStorage, auditStorage)
 
-	userAPI := api.NewUserAPI(userService)
-	authAPI := api.NewAuthAPI(userService)
+	registrationMode := models.RegistrationMode(cfg.RegistrationMode)
+	if !registrationMode.Valid() {
+		log.Fatalf("invalid registration mode %q", cfg.RegistrationMode)
+	}
+	inviteService := services.NewInviteService(inviteStorage, userService, registrationMode)
+
+	sessionCookies := api.NewSessionCookies(cfg.CookieSessions, cfg.CookieSecure, cfg.CookieSameSite, cfg.CookieDomain)
+
+	userAPI := api.NewUserAPI(roleService, loginThrottleService, userAdminService)
+	authAPI := api.NewAuthAPI(userService, tokenService, accountService, loginThrottleService, twoFactorService, inviteService, sessionCookies)
+	accountAPI := api.NewAccountAPI(accountService, sessionCookies)
+	apiKeyAPI := api.NewAPIKeyAPI(apiKeyService)
+	twoFactorAPI := api.NewTwoFactorAPI(twoFactorService)
+	inviteAPI := api.NewInviteAPI(inviteService)
+	exportAPI := api.NewExportAPI(userService, exportService)
 	stationAPI := api.NewStationAPI(stationService)
 	playbackAPI := api.NewPlaybackAPI(playbackService)
 	songAPI := api.NewSongAPI(songService)
+	songIngestAPI := api.NewSongIngestAPI(songIngestService)
+	songImportAPI := api.NewSongImportAPI(songImportService)
+	catalogExportAPI := api.NewCatalogExportAPI(catalogExportService)
 	tagAPI := api.NewTagAPI(tagService)
+	searchAPI := api.NewSearchAPI(searchService)
 	playEventAPI := api.NewPlayEventAPI(playEventService)
 	feedbackAPI := api.NewFeedbackAPI(feedbackService)
 	pomodoroAPI := api.NewPomodoroSessionAPI(pomodoroSessionService)
+	keysAPI := api.NewKeysAPI(keySet)
+
+	var ssoAPI *api.SSOAPI
+	if cfg.OIDC.Issuer != "" {
+		groupRoles := make(map[string]models.Role)
+		for group, role := range cfg.OIDC.GroupRoles {
+			if !models.Role(role).Valid() {
+				log.Fatalf("invalid role %q for OIDC group %q", role, group)
+			}
+			groupRoles[group] = models.Role(role)
+		}
+		provider := oidc.NewProvider(oidc.Config{
+			Name:         cfg.OIDC.ProviderName,
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+			GroupsClaim:  cfg.OIDC.GroupsClaim,
+		}, nil)
+		ssoService := services.NewSSOService(provider, identityStorage, userStorage, tokenService, groupRoles)
+		ssoAPI = api.NewSSOAPI(ssoService, authAPI)
+	}
+
+	var billingAPI *api.BillingAPI
+	if cfg.Billing.WebhookSecret != "" {
+		if cfg.Billing.ExpiryInterval <= 0 {
+			log.Fatalf("invalid billing expiry interval %s", cfg.Billing.ExpiryInterval)
+		}
+		billingService := services.NewBillingService(subscriptionStorage, userStorage, tokenService, cfg.Billing.GracePeriod)
+		billingAPI = api.NewBillingAPI(billingService, cfg.Billing.WebhookSecret)
+		go expireSubscriptions(billingService, cfg.Billing.ExpiryInterval)
+	}
 
 	r := mux.NewRouter()
 
+	r.Use(middleware.RealIP(cfg.TrustProxy))
 	// Use logging middleware
 	r.Use(middleware.LoggingMiddleware)
 
@@ -75,20 +211,64 @@ func main() {
 			return
 		}
 	}).Methods("GET")
+	r.HandleFunc("/registration", authAPI.Registration).Methods("GET")
 	r.HandleFunc("/register", authAPI.Register).Methods("POST")
 	r.HandleFunc("/login", authAPI.Login).Methods("POST")
+	r.HandleFunc("/login/2fa", authAPI.LoginTwoFactor).Methods("POST")
+	r.HandleFunc("/login/2fa/setup", authAPI.LoginTwoFactorSetup).Methods("POST")
+	r.HandleFunc("/token/refresh", authAPI.Refresh).Methods("POST")
+	if ssoAPI != nil {
+		r.HandleFunc("/login/sso", ssoAPI.Start).Methods("POST")
+		r.HandleFunc("/login/sso/callback", ssoAPI.Callback).Methods("POST")
+	}
+	if billingAPI != nil {
+		r.HandleFunc("/billing/webhook", billingAPI.Webhook).Methods("POST")
+	}
+	r.HandleFunc("/.well-known/jwks.json", keysAPI.JWKS).Methods("GET")
+	r.HandleFunc("/password/reset/request", accountAPI.RequestPasswordReset).Methods("POST")
+	r.HandleFunc("/password/reset/confirm", accountAPI.ResetPassword).Methods("POST")
+	r.HandleFunc("/email/verify/confirm", accountAPI.VerifyEmail).Methods("POST")
 
 	protected := r.PathPrefix("/").Subrouter()
-	protected.Use(middleware.WithUser)
+	protected.Use(middleware.SessionCookie(cfg.CookieSessions))
+	protected.Use(middleware.WithUser(tokenService, apiKeyService, userAdminService))
+	protected.Use(middleware.RequireAPIKeyScope)
+	protected.Use(middleware.Impersonate(impersonationService))
+
+	protected.HandleFunc("/logout", authAPI.Logout).Methods("POST")
+	protected.HandleFunc("/me/sessions", authAPI.Sessions).Methods("GET")
+	protected.HandleFunc("/me/sessions/{id:[0-9]+}", authAPI.RevokeSession).Methods("DELETE")
+	if ssoAPI != nil {
+		protected.HandleFunc("/me/identities", ssoAPI.Identities).Methods("GET")
+	}
+	if billingAPI != nil {
+		protected.HandleFunc("/me/subscription", billingAPI.Subscription).Methods("GET")
+	}
+	protected.HandleFunc("/email/verify/request", accountAPI.RequestEmailVerification).Methods("POST")
 
 	protected.HandleFunc("/feedback", feedbackAPI.SaveFeedback).Methods("POST")
 	protected.HandleFunc("/feedback", feedbackAPI.DeleteFeedback).Methods("DELETE")
 	protected.HandleFunc("/feedback", feedbackAPI.GetFeedback).Methods("GET")
 
 	protected.HandleFunc("/me", http.HandlerFunc(authAPI.Me)).Methods("GET")
+	protected.HandleFunc("/me", accountAPI.UpdateProfile).Methods("PUT")
+	protected.HandleFunc("/me", accountAPI.DeleteAccount).Methods("DELETE")
+	protected.HandleFunc("/me/password", accountAPI.ChangePassword).Methods("POST")
+	protected.HandleFunc("/me/export", exportAPI.ExportMe).Methods("GET")
+	protected.HandleFunc("/me/entitlements", accountAPI.Entitlements).Methods("GET")
+	protected.HandleFunc("/me/api_keys", apiKeyAPI.CreateKey).Methods("POST")
+	protected.HandleFunc("/me/api_keys", apiKeyAPI.Keys).Methods("GET")
+	protected.HandleFunc("/me/api_keys/{id:[0-9]+}", apiKeyAPI.RevokeKey).Methods("DELETE")
+	protected.HandleFunc("/me/2fa", twoFactorAPI.Status).Methods("GET")
+	protected.HandleFunc("/me/2fa", twoFactorAPI.Enroll).Methods("POST")
+	protected.HandleFunc("/me/2fa", twoFactorAPI.Disable).Methods("DELETE")
+	protected.HandleFunc("/me/2fa/confirm", twoFactorAPI.Confirm).Methods("POST")
+	protected.HandleFunc("/me/2fa/recovery_codes", twoFactorAPI.RegenerateRecoveryCodes).Methods("POST")
 
-	protected.HandleFunc("/stations", stationAPI.GetAllStations).Methods("GET")
-	protected.HandleFunc("/stations/{id:[0-9]+}/songs", stationAPI.GetSongsForStationByID).Methods("GET")
+	protected.Handle("/stations", middleware.Scoped(models.ScopeStationsRead, stationAPI.GetAllStations)).Methods("GET")
+	protected.Handle("/stations/{id:[0-9]+}/songs", middleware.Scoped(models.ScopeStationsRead, stationAPI.GetSongsForStationByID)).Methods("GET")
+	protected.HandleFunc("/me/stations", stationAPI.CreatePersonalStation).Methods("POST")
+	protected.HandleFunc("/me/stations/{id:[0-9]+}", stationAPI.DeletePersonalStation).Methods("DELETE")
 
 	protected.HandleFunc("/playback/play", playbackAPI.Play).Methods("POST")
 	protected.HandleFunc("/playback/pause", playbackAPI.Pause).Methods("POST")
@@ -98,42 +278,107 @@ func main() {
 
 	protected.HandleFunc("/pomodoro/start", pomodoroAPI.StartSession).Methods("POST")
 	protected.HandleFunc("/pomodoro/end", pomodoroAPI.EndSession).Methods("POST")
+	protected.HandleFunc("/pomodoro/sessions", pomodoroAPI.History).Methods("GET")
 
-	protected.HandleFunc("/songs", songAPI.GetAllSongs).Methods("GET")
+	protected.Handle("/songs", middleware.Scoped(models.ScopeSongsRead, songAPI.ListSongs)).Methods("GET")
+	protected.HandleFunc("/search", searchAPI.Search).Methods("GET")
 
+	// Every admin route declares the permission it needs; see models.Permission for which
+	// roles hold which permissions.
 	adminRouter := protected.PathPrefix("/admin").Subrouter()
-	adminRouter.Use(middleware.RequireRole(models.RoleAdmin))
+	can := middleware.RequirePermission
 
-	adminRouter.HandleFunc("/pomodoro/user/{user_id}/sessions", pomodoroAPI.GetSessionsByUser).Methods("GET")
-	adminRouter.HandleFunc("/pomodoro/user/{user_id}/metrics", pomodoroAPI.GetFocusMetrics).Methods("GET")
+	adminRouter.HandleFunc("/pomodoro/user/{user_id}/sessions", can(models.PermissionAnalyticsRead, pomodoroAPI.GetSessionsByUser)).Methods("GET")
+	adminRouter.HandleFunc("/pomodoro/user/{user_id}/metrics", can(models.PermissionAnalyticsRead, pomodoroAPI.GetFocusMetrics)).Methods("GET")
 
-	adminRouter.HandleFunc("/users", userAPI.Users).Methods("GET")
+	adminRouter.HandleFunc("/users", can(models.PermissionUsersRead, userAPI.Users)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id:[0-9]+}/role", can(models.PermissionUsersManage, userAPI.ChangeRole)).Methods("PUT")
+	adminRouter.HandleFunc("/users/{id:[0-9]+}/unlock", can(models.PermissionUsersManage, userAPI.Unlock)).Methods("POST")
+	adminRouter.HandleFunc("/users/{id:[0-9]+}/suspend", can(models.PermissionUsersManage, userAPI.Suspend)).Methods("POST")
+	adminRouter.HandleFunc("/users/{id:[0-9]+}/reactivate", can(models.PermissionUsersManage, userAPI.Reactivate)).Methods("POST")
+	adminRouter.HandleFunc("/users/{id:[0-9]+}/audit", can(models.PermissionUsersRead, userAPI.Audit)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id:[0-9]+}/export", can(models.PermissionUsersManage, exportAPI.ExportUser)).Methods("GET")
+	adminRouter.HandleFunc("/invites", can(models.PermissionUsersRead, inviteAPI.Invites)).Methods("GET")
+	adminRouter.HandleFunc("/invites", can(models.PermissionUsersManage, inviteAPI.CreateInvite)).Methods("POST")
+	adminRouter.HandleFunc("/invites/{id:[0-9]+}", can(models.PermissionUsersManage, inviteAPI.RevokeInvite)).Methods("DELETE")
+	adminRouter.HandleFunc("/2fa/roles", can(models.PermissionUsersRead, twoFactorAPI.RequiredRoles)).Methods("GET")
+	adminRouter.HandleFunc("/2fa/roles/{role}", can(models.PermissionUsersManage, twoFactorAPI.SetRoleRequired)).Methods("PUT")
 
-	adminRouter.HandleFunc("/play_events", playEventAPI.LogPlayEvent).Methods("POST")
-	adminRouter.HandleFunc("/update_aggregates", playEventAPI.UpdateAggregates).Methods("POST")
+	adminRouter.HandleFunc("/play_events", can(models.PermissionAnalyticsWrite, playEventAPI.LogPlayEvent)).Methods("POST")
+	adminRouter.HandleFunc("/update_aggregates", can(models.PermissionAnalyticsWrite, playEventAPI.UpdateAggregates)).Methods("POST")
 
-	adminRouter.HandleFunc("/tags", tagAPI.CreateTag).Methods("POST")
-	adminRouter.HandleFunc("/tags", tagAPI.GetTags).Methods("GET")
-	adminRouter.HandleFunc("/tags/{id:[0-9]+}", tagAPI.UpdateTag).Methods("PUT")
-	adminRouter.HandleFunc("/tags/{id:[0-9]+}", tagAPI.DeleteTag).Methods("DELETE")
+	adminRouter.Handle("/tags", middleware.Scoped(models.ScopeTagsWrite, can(models.PermissionCatalogWrite, tagAPI.CreateTag))).Methods("POST")
+	adminRouter.Handle("/tags", middleware.Scoped(models.ScopeTagsRead, can(models.PermissionCatalogRead, tagAPI.GetTags))).Methods("GET")
+	adminRouter.Handle("/tags/{id:[0-9]+}", middleware.Scoped(models.ScopeTagsWrite, can(models.PermissionCatalogWrite, tagAPI.UpdateTag))).Methods("PUT")
+	adminRouter.Handle("/tags/{id:[0-9]+}", middleware.Scoped(models.ScopeTagsWrite, can(models.PermissionCatalogWrite, tagAPI.DeleteTag))).Methods("DELETE")
 
-	adminRouter.HandleFunc("/songs", songAPI.CreateSong).Methods("POST")
-	adminRouter.HandleFunc("/songs/{id:[0-9]+}", songAPI.DeleteSong).Methods("DELETE")
-	adminRouter.HandleFunc("/songs/{id:[0-9]+}", songAPI.UpdateSong).Methods("PUT")
-	adminRouter.HandleFunc("/songs/{id:[0-9]+}", songAPI.GetSong).Methods("GET")
-	adminRouter.HandleFunc("/songs/suno", songAPI.GetSongBySunoID).Methods("GET")
+	adminRouter.Handle("/songs", middleware.Scoped(models.ScopeSongsWrite, can(models.PermissionCatalogWrite, songAPI.CreateSong))).Methods("POST")
+	adminRouter.Handle("/songs", middleware.Scoped(models.ScopeSongsRead, can(models.PermissionCatalogRead, songAPI.ListSongs))).Methods("GET")
+	adminRouter.Handle("/songs/{id:[0-9]+}", middleware.Scoped(models.ScopeSongsWrite, can(models.PermissionCatalogWrite, songAPI.DeleteSong))).Methods("DELETE")
+	adminRouter.Handle("/songs/{id:[0-9]+}", middleware.Scoped(models.ScopeSongsWrite, can(models.PermissionCatalogWrite, songAPI.UpdateSong))).Methods("PUT")
+	adminRouter.Handle("/songs/{id:[0-9]+}", middleware.Scoped(models.ScopeSongsRead, can(models.PermissionCatalogRead, songAPI.GetSong))).Methods("GET")
+	adminRouter.Handle("/songs/suno", middleware.Scoped(models.ScopeSongsRead, can(models.PermissionCatalogRead, songAPI.GetSongBySunoID))).Methods("GET")
+	adminRouter.Handle("/songs/ingest", middleware.Scoped(models.ScopeSongsWrite, can(models.PermissionCatalogWrite, songIngestAPI.IngestTask))).Methods("POST")
+	adminRouter.Handle("/songs/import", middleware.Scoped(models.ScopeSongsWrite, can(models.PermissionCatalogWrite, songImportAPI.Import))).Methods("POST")
+	adminRouter.Handle("/catalog/export", middleware.Scoped(models.ScopeSongsRead, can(models.PermissionCatalogRead, catalogExportAPI.Export))).Methods("GET")
 
-	adminRouter.HandleFunc("/stations", stationAPI.CreateStation).Methods("POST")
-	adminRouter.HandleFunc("/stations/{id:[0-9]+}", stationAPI.UpdateStation).Methods("PUT")
-	adminRouter.HandleFunc("/stations/{id:[0-9]+}", stationAPI.DeleteStation).Methods("DELETE")
+	adminRouter.Handle("/stations", middleware.Scoped(models.ScopeStationsWrite, can(models.PermissionCatalogWrite, stationAPI.CreateStation))).Methods("POST")
+	adminRouter.Handle("/stations/{id:[0-9]+}", middleware.Scoped(models.ScopeStationsWrite, can(models.PermissionCatalogWrite, stationAPI.UpdateStation))).Methods("PUT")
+	adminRouter.Handle("/stations/{id:[0-9]+}", middleware.Scoped(models.ScopeStationsWrite, can(models.PermissionCatalogWrite, stationAPI.DeleteStation))).Methods("DELETE")
 
-	corsMiddleware := handlers.CORS(
-		handlers.AllowedOrigins([]string{"*"}),
+	corsOptions := []handlers.CORSOption{
+		handlers.AllowedOrigins(cfg.CORSOrigins),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
-	)
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", middleware.ImpersonateHeader, middleware.CSRFHeader}),
+	}
+	if cfg.CookieSessions {
+		// Browsers only send cookies cross-origin with credentials allowed, which in turn
+		// requires CORS_ALLOWED_ORIGINS to list the UI origin instead of "*".
+		corsOptions = append(corsOptions, handlers.AllowCredentials())
+	}
+	corsMiddleware := handlers.CORS(corsOptions...)
 
 	log.Printf("server is running on port %s", port)
 
 	log.Fatal(http.ListenAndServe(":"+port, corsMiddleware(r)))
 }
+
+// newPasswordHasher hashes with the configured algorithm and accepts hashes made by the
+// other one, so switching algorithms does not lock anyone out.
+func newPasswordHasher(cfg config.PasswordConfig) (*passwords.Hasher, error) {
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d is out of range", cfg.BcryptCost)
+	}
+	if cfg.Argon2Memory <= 0 || cfg.Argon2Iterations <= 0 || cfg.Argon2Parallelism <= 0 || cfg.Argon2Parallelism > 255 {
+		return nil, errors.New("argon2id parameters must be positive")
+	}
+	bcryptHasher := passwords.NewBcrypt(cfg.BcryptCost)
+	argon2Hasher := passwords.NewArgon2id(passwords.Argon2idParams{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+	})
+	switch cfg.HashAlgorithm {
+	case "bcrypt":
+		return passwords.NewHasher(bcryptHasher, argon2Hasher), nil
+	case "argon2id":
+		return passwords.NewHasher(argon2Hasher, bcryptHasher), nil
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", cfg.HashAlgorithm)
+}
+
+// expireSubscriptions moves users whose subscription lapsed back to free, every interval.
+func expireSubscriptions(billingService services.BillingManagement, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		expired, err := billingService.ExpireSubscriptions(now)
+		if err != nil {
+			log.Printf("failed to expire subscriptions: %v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Printf("moved %d lapsed subscribers back to free", expired)
+		}
+	}
+}
@@ -1,15 +1,96 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/joho/godotenv"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	DatabaseURL string
-	JwtSecret   string
+	DatabaseURL     string
+	JwtSecret       string
+	JWTKeys         []JWTKey
+	JWTActiveKeyID  string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	Mailer          string
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUsername    string
+	SMTPPassword    string
+	MailFrom        string
+	MailDir         string
+	AppBaseURL      string
+	TrustProxy      bool
+	CookieSessions  bool
+	CookieSecure    bool
+	CookieSameSite  string
+	CookieDomain    string
+	CORSOrigins     []string
+	// RegistrationMode is "open", "invite_only" or "closed".
+	RegistrationMode string
+	Passwords        PasswordConfig
+	OIDC             OIDCConfig
+	Billing          BillingConfig
+}
+
+// PasswordConfig sets the password policy for new and changed passwords.
+type PasswordConfig struct {
+	MinLength int
+	// CharacterClasses lists the required classes: lower, upper, digit, symbol.
+	CharacterClasses []string
+	// BreachedList is a file of SHA-1 hashes replacing the bundled breached password list.
+	BreachedList   string
+	ScreenBreached bool
+	// HashAlgorithm is "bcrypt" or "argon2id". Hashes made with the other one, or with other
+	// parameters, are upgraded when their owner logs in.
+	HashAlgorithm     string
+	BcryptCost        int
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider. SSO is off while
+// Issuer is empty.
+type OIDCConfig struct {
+	ProviderName string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	// GroupRoles maps provider groups to role names, from OIDC_GROUP_ROLES
+	// ("staff=admin,data=analyst").
+	GroupRoles map[string]string
+}
+
+// BillingConfig configures the payment provider's subscription webhooks. Billing is off
+// while WebhookSecret is empty.
+type BillingConfig struct {
+	WebhookSecret string
+	// GracePeriod is how long a subscriber stays premium after a renewal is due but unpaid.
+	GracePeriod time.Duration
+	// ExpiryInterval is how often lapsed subscriptions are moved back to free.
+	ExpiryInterval time.Duration
+}
+
+// JWTKey describes one token signing key. Keys are listed in the JSON file named by
+// JWT_KEYS_FILE; a key without a private key file only verifies tokens.
+type JWTKey struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -26,9 +107,146 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config := &Config{
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		JwtSecret:   os.Getenv("JWT_SECRET"),
+		DatabaseURL:      os.Getenv("DATABASE_URL"),
+		JwtSecret:        os.Getenv("JWT_SECRET"),
+		AccessTokenTTL:   durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:  durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		Mailer:           stringEnv("MAILER", "file"),
+		SMTPHost:         os.Getenv("SMTP_HOST"),
+		SMTPPort:         stringEnv("SMTP_PORT", "587"),
+		SMTPUsername:     os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		MailFrom:         stringEnv("MAIL_FROM", "no-reply@louderspace.local"),
+		MailDir:          stringEnv("MAIL_DIR", "mail"),
+		AppBaseURL:       stringEnv("APP_BASE_URL", "http://localhost:3000"),
+		TrustProxy:       os.Getenv("TRUST_PROXY") == "true",
+		CookieSessions:   os.Getenv("COOKIE_SESSIONS") == "true",
+		CookieSecure:     os.Getenv("COOKIE_SECURE") != "false",
+		CookieSameSite:   stringEnv("COOKIE_SAMESITE", "lax"),
+		CookieDomain:     os.Getenv("COOKIE_DOMAIN"),
+		CORSOrigins:      strings.Split(stringEnv("CORS_ALLOWED_ORIGINS", "*"), ","),
+		RegistrationMode: stringEnv("REGISTRATION_MODE", "open"),
+		Passwords: PasswordConfig{
+			MinLength:         intEnv("PASSWORD_MIN_LENGTH", 10),
+			CharacterClasses:  strings.FieldsFunc(stringEnv("PASSWORD_CHARACTER_CLASSES", "lower,upper,digit"), func(r rune) bool { return r == ',' || r == ' ' }),
+			BreachedList:      os.Getenv("PASSWORD_BREACHED_LIST"),
+			ScreenBreached:    os.Getenv("PASSWORD_SCREEN_BREACHED") != "false",
+			HashAlgorithm:     stringEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+			BcryptCost:        intEnv("PASSWORD_BCRYPT_COST", 10),
+			Argon2Memory:      intEnv("PASSWORD_ARGON2_MEMORY_KIB", 64*1024),
+			Argon2Iterations:  intEnv("PASSWORD_ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: intEnv("PASSWORD_ARGON2_PARALLELISM", 2),
+		},
+		OIDC: OIDCConfig{
+			ProviderName: stringEnv("OIDC_PROVIDER_NAME", "oidc"),
+			Issuer:       os.Getenv("OIDC_ISSUER"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       strings.Fields(stringEnv("OIDC_SCOPES", "openid email profile")),
+			GroupsClaim:  stringEnv("OIDC_GROUPS_CLAIM", "groups"),
+			GroupRoles:   pairsEnv("OIDC_GROUP_ROLES"),
+		},
+		Billing: BillingConfig{
+			WebhookSecret:  os.Getenv("BILLING_WEBHOOK_SECRET"),
+			GracePeriod:    durationEnv("BILLING_GRACE_PERIOD", 72*time.Hour),
+			ExpiryInterval: durationEnv("BILLING_EXPIRY_INTERVAL", 15*time.Minute),
+		},
+	}
+	if config.OIDC.Issuer != "" && config.OIDC.RedirectURL == "" {
+		config.OIDC.RedirectURL = strings.TrimSuffix(config.AppBaseURL, "/") + "/sso/callback"
+	}
+
+	if err := loadJWTKeys(config); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
+
+// loadJWTKeys reads the signing keys from JWT_KEYS_FILE, or falls back to a single HS256 key
+// built from JWT_SECRET when no key file is configured.
+func loadJWTKeys(config *Config) error {
+	config.JWTActiveKeyID = os.Getenv("JWT_ACTIVE_KEY_ID")
+
+	keysFile := os.Getenv("JWT_KEYS_FILE")
+	if keysFile == "" {
+		if config.JwtSecret == "" {
+			return errors.New("no JWT signing keys configured: set JWT_KEYS_FILE or JWT_SECRET")
+		}
+		keyID := os.Getenv("JWT_KEY_ID")
+		if keyID == "" {
+			keyID = "default"
+		}
+		config.JWTKeys = []JWTKey{{ID: keyID, Algorithm: "HS256", Secret: config.JwtSecret}}
+		if config.JWTActiveKeyID == "" {
+			config.JWTActiveKeyID = keyID
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(keysFile)
+	if err != nil {
+		return fmt.Errorf("failed to read JWT keys file: %w", err)
+	}
+	if err := json.Unmarshal(data, &config.JWTKeys); err != nil {
+		return fmt.Errorf("failed to parse JWT keys file: %w", err)
+	}
+	if len(config.JWTKeys) == 0 {
+		return errors.New("JWT keys file contains no keys")
+	}
+	if config.JWTActiveKeyID == "" {
+		config.JWTActiveKeyID = config.JWTKeys[0].ID
+	}
+	return nil
+}
+
+func stringEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// pairsEnv reads a comma-separated list of key=value pairs from the environment.
+func pairsEnv(key string) map[string]string {
+	pairs := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		pairs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return pairs
+}
+
+// intEnv reads an integer from the environment, falling back to def when the variable is
+// unset or malformed.
+func intEnv(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// durationEnv reads a Go duration string (e.g. "15m") from the environment, falling back
+// to def when the variable is unset or malformed.
+func durationEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, def)
+		return def
+	}
+	return d
+}
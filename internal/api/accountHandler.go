@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/services"
+	"net/http"
+)
+
+type AccountAPI struct {
+	accountService services.AccountManagement
+	cookies        *SessionCookies
+}
+
+func NewAccountAPI(accountService services.AccountManagement, cookies *SessionCookies) *AccountAPI {
+	return &AccountAPI{accountService, cookies}
+}
+
+// RequestPasswordReset always answers 202 so callers cannot tell whether the email exists.
+func (a *AccountAPI) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Missing email", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.accountService.RequestPasswordReset(req.Email); err != nil {
+		logger.Error("Failed to request password reset", err)
+		http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Password reset requested")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *AccountAPI) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Missing token or password", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.accountService.ResetPassword(req.Token, req.Password); err != nil {
+		logger.Error("Failed to reset password", err)
+		if passwordPolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidOneTimeToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Password reset")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AccountAPI) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.accountService.RequestEmailVerification(user.ID); err != nil {
+		logger.Error("Failed to request email verification", err)
+		http.Error(w, "Failed to request email verification", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Email verification requested for user", user.ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *AccountAPI) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.accountService.VerifyEmail(req.Token); err != nil {
+		logger.Error("Failed to verify email", err)
+		if errors.Is(err, services.ErrInvalidOneTimeToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Email verified")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AccountAPI) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.accountService.UpdateProfile(user.ID, req.Username, req.Email)
+	if err != nil {
+		logger.Error("Failed to update profile", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidProfile):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("Profile updated", updated)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (a *AccountAPI) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+		DeviceName      string `json:"device_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.NewPassword == "" {
+		http.Error(w, "Missing new password", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := a.accountService.ChangePassword(user.ID, req.CurrentPassword, req.NewPassword, newSession(r, req.DeviceName))
+	if err != nil {
+		logger.Error("Failed to change password", err)
+		if passwordPolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrIncorrectPassword) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := a.cookies.Issue(w, tokens)
+	if err != nil {
+		logger.Error("Failed to set session cookies", err)
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Password changed for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *AccountAPI) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.accountService.DeleteAccount(user.ID, req.Password); err != nil {
+		logger.Error("Failed to delete account", err)
+		if errors.Is(err, services.ErrIncorrectPassword) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	a.cookies.Clear(w)
+
+	logger.Info("Account deleted for user", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Entitlements returns the limits of the user's tier so the UI can show them up front.
+func (a *AccountAPI) Entitlements(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Tier models.Role `json:"tier"`
+		models.Entitlements
+	}{user.Role, user.Role.Entitlements()})
+}
+
+// passwordPolicyError answers 422 with the broken rules if err is a password policy failure,
+// so clients can point at each one.
+func passwordPolicyError(w http.ResponseWriter, err error) bool {
+	var validationErr *passwords.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      passwords.ErrPolicyViolation.Error(),
+		"violations": validationErr.Violations,
+	})
+	return true
+}
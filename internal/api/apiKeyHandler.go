@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"louderspace/internal/logger"
+	"louderspace/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type APIKeyAPI struct {
+	apiKeyService services.APIKeyManagement
+}
+
+func NewAPIKeyAPI(apiKeyService services.APIKeyManagement) *APIKeyAPI {
+	return &APIKeyAPI{apiKeyService}
+}
+
+func (h *APIKeyAPI) CreateKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request body:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plaintext, key, err := h.apiKeyService.CreateKey(user, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		logger.Error("Failed to create api key:", err)
+		switch {
+		case errors.Is(err, services.ErrScopeNotAllowed):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrInvalidScope), errors.Is(err, services.ErrInvalidKeyName),
+			errors.Is(err, services.ErrMissingKeyScopes), errors.Is(err, services.ErrExpiryInThePast):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to create api key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("Created api key", key.ID, "for user", user.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"key":     plaintext,
+		"api_key": key,
+	})
+}
+
+func (h *APIKeyAPI) Keys(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.Keys(user.ID)
+	if err != nil {
+		logger.Error("Failed to get api keys:", err)
+		http.Error(w, "Failed to get api keys", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Got api keys for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (h *APIKeyAPI) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	keyID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		logger.Error("Invalid api key ID:", err)
+		http.Error(w, "Invalid api key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(user.ID, keyID); err != nil {
+		logger.Error("Failed to revoke api key:", err)
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke api key", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Revoked api key", keyID, "for user", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	userStorage.Save(user)
+
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	apiKeyService := services.NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage)
+	apiKeyAPI := NewAPIKeyAPI(apiKeyService)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := mux.NewRouter()
+	r.Use(middleware.WithUser(tokenService, apiKeyService, services.NewUserAdminService(userStorage, repositories.NewMockAuditStorage(), tokenService)))
+	r.Use(middleware.RequireAPIKeyScope)
+	r.Handle("/songs", middleware.Scoped(models.ScopeSongsRead, ok)).Methods("GET")
+	r.Handle("/stations", middleware.Scoped(models.ScopeStationsRead, ok)).Methods("GET")
+	r.HandleFunc("/me/api_keys", apiKeyAPI.CreateKey).Methods("POST")
+
+	tokens, _ := tokenService.IssueTokens(user, &models.Session{})
+	body, _ := json.Marshal(map[string]interface{}{"name": "script", "scopes": []string{models.ScopeSongsRead}})
+	req, _ := http.NewRequest("POST", "/me/api_keys", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	request := func(method, path string) int {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString("{}"))
+		req.Header.Set("Authorization", "Bearer "+created.Key)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("GET", "/songs"))
+	assert.Equal(t, http.StatusForbidden, request("GET", "/stations"))
+	// Endpoints without a declared scope are closed to API keys.
+	assert.Equal(t, http.StatusForbidden, request("POST", "/me/api_keys"))
+}
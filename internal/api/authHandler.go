@@ -2,30 +2,47 @@ package api
 
 import (
 	"encoding/json"
-	"golang.org/x/crypto/bcrypt"
+	"errors"
+	"github.com/gorilla/mux"
+	"io"
 	"louderspace/internal/logger"
 	"louderspace/internal/middleware"
 	"louderspace/internal/models"
 	"louderspace/internal/services"
 	"louderspace/internal/utils"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 type AuthAPI struct {
-	userService services.UserManagement
+	userService    services.UserManagement
+	tokenService   services.TokenManagement
+	accountService services.AccountManagement
+	loginThrottle  services.LoginThrottling
+	twoFactor      services.TwoFactorManagement
+	invites        services.InviteManagement
+	cookies        *SessionCookies
 }
 
-func NewAuthAPI(userService services.UserManagement) *AuthAPI {
-	return &AuthAPI{userService}
+func NewAuthAPI(userService services.UserManagement, tokenService services.TokenManagement, accountService services.AccountManagement, loginThrottle services.LoginThrottling, twoFactor services.TwoFactorManagement, invites services.InviteManagement, cookies *SessionCookies) *AuthAPI {
+	return &AuthAPI{userService, tokenService, accountService, loginThrottle, twoFactor, invites, cookies}
 }
 
+// Registration tells clients whether registration is open, needs an invite code or is closed.
+func (a *AuthAPI) Registration(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]models.RegistrationMode{"mode": a.invites.Mode()})
+}
+
+// Register creates an account as far as the registration mode allows; see
+// services.InviteManagement.
 func (a *AuthAPI) Register(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Username string      `json:"username"`
-		Password string      `json:"password"`
-		Email    string      `json:"email"`
-		Role     models.Role `json:"role"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		Email      string `json:"email"`
+		InviteCode string `json:"invite_code"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("Failed to decode request", err)
@@ -33,18 +50,25 @@ func (a *AuthAPI) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	user, err := a.invites.Register(req.Username, req.Password, req.Email, req.InviteCode)
 	if err != nil {
-		logger.Error("Failed to hash password", err)
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		logger.Error("Failed to register user", err)
+		if passwordPolicyError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrRegistrationClosed), errors.Is(err, services.ErrInviteRequired), errors.Is(err, services.ErrInvalidInvite):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	user, err := a.userService.Register(req.Username, string(hashedPassword), req.Email, req.Role)
-	if err != nil {
-		logger.Error("Failed to register user", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// The account is usable before the address is confirmed, so a mail failure must not fail
+	// the registration; the user can ask for a new link later.
+	if err := a.accountService.RequestEmailVerification(user.ID); err != nil {
+		logger.Error("Failed to send verification email", err)
 	}
 
 	logger.Info("User registered", user)
@@ -54,48 +78,347 @@ func (a *AuthAPI) Register(w http.ResponseWriter, r *http.Request) {
 
 func (a *AuthAPI) Login(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("Failed to decode request", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	logger.Info(req.Username, req.Password)
+	logger.Info("Login attempt for", req.Username)
 
-	user, err := a.userService.GetUserByUsername(req.Username)
+	ip := middleware.ClientIP(r)
+	lockedUntil, err := a.loginThrottle.Reserve(req.Username, ip)
 	if err != nil {
-		logger.Error("Failed to get user", err)
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		logger.Error("Login rejected for", req.Username, "from", ip, err)
+		if errors.Is(err, services.ErrTooManyLoginAttempts) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		logger.Error("Failed to compare password", err)
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+	user, err := a.userService.Login(req.Username, req.Password)
+	if err != nil {
+		logger.Error("Login failed for", req.Username, "from", ip, err)
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrPasswordResetRequired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			// Not a wrong guess, so it does not count towards the lockout.
+			if err := a.loginThrottle.Release(req.Username, ip); err != nil {
+				logger.Error("Failed to release login attempt", err)
+			}
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		}
+		return
+	}
+	if user.Suspended() {
+		logger.Error("Login of suspended user", user.ID)
+		if err := a.loginThrottle.Release(user.Username, ip); err != nil {
+			logger.Error("Failed to release login attempt", err)
+		}
+		http.Error(w, services.ErrUserSuspended.Error(), http.StatusForbidden)
 		return
 	}
 
-	token, err := utils.GenerateToken(user)
+	// With 2FA the password only earns a challenge; the attempt is taken back, but failures are
+	// not reset until the second factor has been checked too.
+	challenge, err := a.twoFactor.StartLogin(user)
+	if err != nil {
+		logger.Error("Failed to start two-factor login", err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		logger.Info("Two-factor challenge issued for user", user.ID)
+		if err := a.loginThrottle.Release(user.Username, ip); err != nil {
+			logger.Error("Failed to release login attempt", err)
+		}
+		writeLoginChallenge(w, challenge)
+		return
+	}
+
+	a.completeLogin(w, user, newSession(r, req.DeviceName), nil)
+}
+
+func writeLoginChallenge(w http.ResponseWriter, challenge *services.LoginChallenge) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		TwoFactorRequired bool `json:"two_factor_required"`
+		*services.LoginChallenge
+	}{true, challenge})
+}
+
+// LoginTwoFactor finishes a login that returned a challenge by checking a TOTP or recovery
+// code. Wrong codes count towards the same lockout as wrong passwords.
+func (a *AuthAPI) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Challenge  string `json:"challenge"`
+		Code       string `json:"code"`
+		DeviceName string `json:"device_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := a.twoFactor.ChallengeUser(req.Challenge)
+	if err != nil {
+		logger.Error("Invalid login challenge", err)
+		http.Error(w, "Invalid or expired login challenge", http.StatusUnauthorized)
+		return
+	}
+	// The user may have been suspended since the challenge was issued.
+	if user.Suspended() {
+		logger.Error("Two-factor login of suspended user", user.ID)
+		http.Error(w, services.ErrUserSuspended.Error(), http.StatusForbidden)
+		return
+	}
+
+	ip := middleware.ClientIP(r)
+	lockedUntil, err := a.loginThrottle.Reserve(user.Username, ip)
+	if err != nil {
+		logger.Error("Two-factor login rejected for", user.Username, "from", ip, err)
+		if errors.Is(err, services.ErrTooManyLoginAttempts) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	loggedIn, recoveryCodes, err := a.twoFactor.CompleteLogin(req.Challenge, req.Code)
+	if err != nil {
+		logger.Error("Two-factor login failed for", user.Username, "from", ip, err)
+		switch {
+		case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrInvalidLoginChallenge), errors.Is(err, services.ErrTwoFactorNotPending):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	a.completeLogin(w, loggedIn, newSession(r, req.DeviceName), recoveryCodes)
+}
+
+// LoginTwoFactorSetup enrolls a user whose role requires 2FA during the login that found
+// they have not set it up. The login is then completed with LoginTwoFactor.
+func (a *AuthAPI) LoginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := a.twoFactor.EnrollForChallenge(req.Challenge)
+	if err != nil {
+		logger.Error("Failed to enroll two-factor during login", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidLoginChallenge):
+			http.Error(w, "Invalid or expired login challenge", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to enroll two-factor authentication", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("Two-factor enrollment started during login")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// completeLogin resets the login failures and starts the session. recoveryCodes are included
+// in the response when the login also finished a 2FA enrollment.
+func (a *AuthAPI) completeLogin(w http.ResponseWriter, user *models.User, session *models.Session, recoveryCodes []string) {
+	if err := a.loginThrottle.RecordSuccess(user.Username, session.IP); err != nil {
+		logger.Error("Failed to reset login failures", err)
+	}
+
+	tokens, err := a.tokenService.IssueTokens(user, session)
 	if err != nil {
 		logger.Error("Failed to generate token", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:    "token",
-		Value:   token,
-		Expires: time.Now().Add(24 * time.Hour),
-	})
+	resp, err := a.cookies.Issue(w, tokens)
+	if err != nil {
+		logger.Error("Failed to set session cookies", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	resp["user"] = user
+	if recoveryCodes != nil {
+		resp["recovery_codes"] = recoveryCodes
+	}
 
 	logger.Info("User logged in", user)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token": token,
-		"user":  user,
-	})
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *AuthAPI) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RefreshToken == "" {
+		// Browsers in cookie mode send the refresh token as a cookie, which makes this a
+		// state-changing request that needs the CSRF check.
+		req.RefreshToken = a.cookies.RefreshToken(r)
+		if req.RefreshToken != "" && !middleware.ValidCSRF(r) {
+			logger.Error("Missing or invalid CSRF token on refresh")
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		http.Error(w, "Missing refresh token", http.StatusBadRequest)
+		return
+	}
+
+	tokens, user, err := a.tokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		logger.Error("Failed to refresh token", err)
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrTokenRevoked) {
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := a.cookies.Issue(w, tokens)
+	if err != nil {
+		logger.Error("Failed to set session cookies", err)
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	resp["user"] = user
+
+	logger.Info("Token refreshed for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Logout revokes the access token used for the request and the refresh token in the body or
+// cookie, and clears the session cookies. With "all" set, every token of the user is revoked
+// instead.
+func (a *AuthAPI) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsContextKey).(*utils.Claims)
+	if !ok {
+		logger.Error("No claims in context")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+		All          bool   `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		req.RefreshToken = a.cookies.RefreshToken(r)
+	}
+
+	var err error
+	if req.All {
+		err = a.tokenService.RevokeUserTokens(claims.UserID)
+	} else {
+		err = a.tokenService.Logout(claims, req.RefreshToken)
+	}
+	if err != nil {
+		logger.Error("Failed to log out", err)
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			http.Error(w, "Invalid refresh token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	a.cookies.Clear(w)
+
+	logger.Info("User logged out", claims.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sessions lists the devices the user is signed in on. API keys have no session, so the
+// current flag is only set for token-authenticated requests.
+func (a *AuthAPI) Sessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	currentSessionID := 0
+	if claims, ok := r.Context().Value(middleware.ClaimsContextKey).(*utils.Claims); ok && claims.UserID == user.ID {
+		currentSessionID = claims.SessionID
+	}
+
+	sessions, err := a.tokenService.Sessions(user.ID, currentSessionID)
+	if err != nil {
+		logger.Error("Failed to list sessions", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession signs the user out on one device.
+func (a *AuthAPI) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.tokenService.RevokeSession(user.ID, sessionID); err != nil {
+		logger.Error("Failed to revoke session", err)
+		if errors.Is(err, services.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Session revoked", sessionID, "for user", user.ID)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (a *AuthAPI) Me(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"louderspace/internal/billing"
+	"louderspace/internal/logger"
+	"louderspace/internal/services"
+	"net/http"
+	"time"
+)
+
+// maxWebhookBytes bounds the webhook payloads read. Provider events are a few KB.
+const maxWebhookBytes = 1 << 16
+
+type BillingAPI struct {
+	billing       services.BillingManagement
+	webhookSecret string
+}
+
+func NewBillingAPI(billing services.BillingManagement, webhookSecret string) *BillingAPI {
+	return &BillingAPI{billing, webhookSecret}
+}
+
+// Webhook receives the payment provider's events. The provider retries any delivery not
+// answered with a 2xx, so only events that can succeed later get an error status.
+func (h *BillingAPI) Webhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		logger.Error("Failed to read webhook body:", err)
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	event, err := billing.ParseEvent(payload, r.Header.Get(billing.SignatureHeader), h.webhookSecret, billing.DefaultTolerance, time.Now())
+	if err != nil {
+		logger.Error("Rejected billing webhook:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.billing.HandleEvent(event); err != nil {
+		logger.Error("Failed to handle billing event", event.ID, err)
+		switch {
+		case errors.Is(err, billing.ErrInvalidPayload):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrUnknownSubscription):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to handle event", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("Handled billing event", event.ID, event.Type)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{"received": true})
+}
+
+func (h *BillingAPI) Subscription(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.billing.Subscription(user.ID)
+	if err != nil {
+		logger.Error("Failed to get subscription:", err)
+		if errors.Is(err, services.ErrSubscriptionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get subscription", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Retrieved subscription for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscription)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/billing/billingtest"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBillingWebhook(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	billingService := services.NewBillingService(repositories.NewMockSubscriptionStorage(), userStorage, tokenService, 72*time.Hour)
+	billingAPI := NewBillingAPI(billingService, "whsec_test")
+
+	user := &models.User{Username: "listener", Email: "listener@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	userStorage.Save(user)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/billing/webhook", billingAPI.Webhook).Methods("POST")
+	r.HandleFunc("/me/subscription", billingAPI.Subscription).Methods("GET")
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	role := func() models.Role {
+		stored, _ := userStorage.UserByID(user.ID)
+		return stored.Role
+	}
+
+	req, _ := http.NewRequest("GET", "/me/subscription", http.NoBody)
+	assert.Equal(t, http.StatusNotFound, serve(withUser(req, user.ID, models.RoleFree)).Code)
+
+	provider := billingtest.NewProvider("whsec_test")
+	periodEnd := time.Now().Add(30 * 24 * time.Hour)
+	created := provider.SubscriptionCreated("sub_1", "cus_1", user.ID, periodEnd)
+
+	// Unsigned, wrongly signed and stale deliveries are rejected.
+	unsigned := provider.Request("/billing/webhook", created)
+	unsigned.Header.Del("Stripe-Signature")
+	assert.Equal(t, http.StatusBadRequest, serve(unsigned).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(billingtest.NewProvider("whsec_other").Request("/billing/webhook", created)).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(provider.RequestAt("/billing/webhook", created, time.Now().Add(-time.Hour))).Code)
+	assert.Equal(t, models.RoleFree, role())
+
+	// A renewal for a subscription not seen yet is refused so the provider retries it.
+	assert.Equal(t, http.StatusConflict, serve(provider.Request("/billing/webhook", provider.InvoicePaid("sub_2", "cus_1", periodEnd))).Code)
+
+	assert.Equal(t, http.StatusOK, serve(provider.Request("/billing/webhook", created)).Code)
+	assert.Equal(t, models.RolePremium, role())
+	assert.Equal(t, http.StatusOK, serve(provider.Request("/billing/webhook", created)).Code)
+
+	req, _ = http.NewRequest("GET", "/me/subscription", http.NoBody)
+	rr := serve(withUser(req, user.ID, models.RolePremium))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"provider_subscription_id":"sub_1"`)
+	assert.Contains(t, rr.Body.String(), `"status":"active"`)
+
+	assert.Equal(t, http.StatusOK, serve(provider.Request("/billing/webhook", provider.SubscriptionDeleted("sub_1", "cus_1", time.Now().Add(-time.Minute)))).Code)
+	assert.Equal(t, models.RoleFree, role())
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var catalogContentTypes = map[models.CatalogFormat]string{
+	models.CatalogCSV:  "text/csv; charset=utf-8",
+	models.CatalogJSON: "application/json",
+	models.CatalogM3U:  "audio/x-mpegurl; charset=utf-8",
+}
+
+type CatalogExportAPI struct {
+	exportService services.CatalogExportManagement
+}
+
+func NewCatalogExportAPI(exportService services.CatalogExportManagement) *CatalogExportAPI {
+	return &CatalogExportAPI{exportService}
+}
+
+// attachmentWriter sends the download headers with the first write, so errors returned
+// before any output can still get their own status.
+type attachmentWriter struct {
+	http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.Header().Set("Content-Type", w.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Export streams the catalog, filtered by ?station= and ?tag=, as ?format=csv|json|m3u
+// (csv by default). Once streaming has started the status can no longer change, so later
+// failures only show up as a truncated file.
+func (h *CatalogExportAPI) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := models.CatalogFormat(query.Get("format"))
+	if format == "" {
+		format = models.CatalogCSV
+	}
+
+	stationID := 0
+	if value := query.Get("station"); value != "" {
+		var err error
+		if stationID, err = strconv.Atoi(value); err != nil || stationID <= 0 {
+			logger.Error("Invalid station ID:", value)
+			http.Error(w, "Invalid station ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	out := &attachmentWriter{
+		ResponseWriter: w,
+		contentType:    catalogContentTypes[format],
+		filename:       fmt.Sprintf("louderspace-catalog-%s.%s", time.Now().UTC().Format("2006-01-02"), format),
+	}
+	if err := h.exportService.ExportCatalog(out, format, stationID, query.Get("tag")); err != nil {
+		logger.Error("Failed to export catalog:", err)
+		if out.started {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrInvalidCatalogFormat):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrStationNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to export catalog", http.StatusInternalServerError)
+		}
+		return
+	}
+	logger.Info("Exported catalog as", format)
+}
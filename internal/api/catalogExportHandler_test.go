@@ -0,0 +1,42 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportCatalog(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	exportAPI := NewCatalogExportAPI(services.NewCatalogExportService(songStorage, repositories.NewStationStorageMock()))
+	_, err := services.NewSongService(songStorage).CreateSong("Night Drive", "Nova", "lofi", "suno-1", true, []string{"focus"},
+		models.SongMetadata{AudioURL: "https://cdn.example.com/1.mp3"})
+	assert.NoError(t, err)
+
+	export := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/admin/catalog/export?"+query, http.NoBody)
+		rr := httptest.NewRecorder()
+		exportAPI.Export(rr, withUser(req, 1, models.RoleAdmin))
+		return rr
+	}
+
+	rr := export("")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".csv")
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "id,title,artist"))
+
+	rr = export("format=m3u&tag=focus")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "audio/x-mpegurl; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "https://cdn.example.com/1.mp3")
+
+	assert.Equal(t, http.StatusBadRequest, export("format=xlsx").Code)
+	assert.Equal(t, http.StatusBadRequest, export("station=abc").Code)
+	assert.Equal(t, http.StatusNotFound, export("station=42").Code)
+}
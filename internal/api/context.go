@@ -0,0 +1,20 @@
+package api
+
+import (
+	"louderspace/internal/logger"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"net/http"
+)
+
+// currentUser returns the user the request acts as, which is the impersonated user when an
+// admin impersonates someone. It writes a 401 response when there is none.
+func currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		logger.Error("No user in context")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"louderspace/internal/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SessionCookies issues the browser session cookies when cookie mode is enabled.
+type SessionCookies struct {
+	enabled  bool
+	secure   bool
+	sameSite http.SameSite
+	domain   string
+}
+
+func NewSessionCookies(enabled, secure bool, sameSite, domain string) *SessionCookies {
+	mode := http.SameSiteLaxMode
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		mode = http.SameSiteStrictMode
+	case "none":
+		mode = http.SameSiteNoneMode
+	}
+	return &SessionCookies{enabled, secure, mode, domain}
+}
+
+func (c *SessionCookies) Enabled() bool {
+	return c.enabled
+}
+
+// Issue returns the token fields of the response body. In cookie mode the tokens stay out
+// of the body and only the CSRF token is returned.
+func (c *SessionCookies) Issue(w http.ResponseWriter, tokens *models.TokenPair) (map[string]interface{}, error) {
+	if !c.enabled {
+		return map[string]interface{}{
+			"token":         tokens.AccessToken,
+			"expires_at":    tokens.AccessExpiresAt,
+			"refresh_token": tokens.RefreshToken,
+		}, nil
+	}
+
+	csrfToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, c.cookie(middleware.AccessTokenCookie, tokens.AccessToken, true, tokens.AccessExpiresAt))
+	http.SetCookie(w, c.cookie(middleware.RefreshTokenCookie, tokens.RefreshToken, true, tokens.RefreshExpiresAt))
+	// The CSRF cookie must be readable by the UI so it can echo it in the X-CSRF-Token header.
+	http.SetCookie(w, c.cookie(middleware.CSRFCookie, csrfToken, false, tokens.RefreshExpiresAt))
+
+	return map[string]interface{}{
+		"expires_at": tokens.AccessExpiresAt,
+		"csrf_token": csrfToken,
+	}, nil
+}
+
+// Clear expires every session cookie.
+func (c *SessionCookies) Clear(w http.ResponseWriter) {
+	for _, name := range []string{middleware.AccessTokenCookie, middleware.RefreshTokenCookie, middleware.CSRFCookie} {
+		cookie := c.cookie(name, "", name != middleware.CSRFCookie, time.Time{})
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+// RefreshToken returns the refresh token cookie, or "" when there is none.
+func (c *SessionCookies) RefreshToken(r *http.Request) string {
+	if !c.enabled {
+		return ""
+	}
+	cookie, err := r.Cookie(middleware.RefreshTokenCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// ssoBindingCookie ties an SSO login to the browser that started it.
+const ssoBindingCookie = "sso_binding"
+
+// SetSSOBinding is always SameSite=Lax so the cookie survives the redirect back from the
+// identity provider.
+func (c *SessionCookies) SetSSOBinding(w http.ResponseWriter, binding string, expires time.Time) {
+	cookie := c.cookie(ssoBindingCookie, binding, true, expires)
+	cookie.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, cookie)
+}
+
+// SSOBinding returns the SSO binding cookie, or "" when it is missing.
+func (c *SessionCookies) SSOBinding(r *http.Request) string {
+	cookie, err := r.Cookie(ssoBindingCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func (c *SessionCookies) ClearSSOBinding(w http.ResponseWriter) {
+	cookie := c.cookie(ssoBindingCookie, "", true, time.Time{})
+	cookie.SameSite = http.SameSiteLaxMode
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+func (c *SessionCookies) cookie(name, value string, httpOnly bool, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   c.domain,
+		Expires:  expires,
+		HttpOnly: httpOnly,
+		Secure:   c.secure,
+		SameSite: c.sameSite,
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"louderspace/internal/mailer"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCookieSession(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	loginThrottle := services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage())
+	twoFactor := services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, loginThrottle, twoFactor, services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(true, true, "strict", ""))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	userStorage.Save(&models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", CreatedAt: time.Now()})
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := mux.NewRouter()
+	r.HandleFunc("/login", authAPI.Login).Methods("POST")
+	protected := r.PathPrefix("/").Subrouter()
+	protected.Use(middleware.SessionCookie(true))
+	protected.Use(middleware.WithUser(tokenService, services.NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage), services.NewUserAdminService(userStorage, repositories.NewMockAuditStorage(), tokenService)))
+	protected.HandleFunc("/me", ok).Methods("GET")
+	protected.HandleFunc("/feedback", ok).Methods("POST")
+	protected.HandleFunc("/logout", authAPI.Logout).Methods("POST")
+
+	body, _ := json.Marshal(map[string]string{"username": "testuser", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.NotContains(t, resp, "token")
+	assert.NotContains(t, resp, "refresh_token")
+	assert.NotEmpty(t, resp["csrf_token"])
+
+	cookies := rr.Result().Cookies()
+	byName := map[string]*http.Cookie{}
+	for _, c := range cookies {
+		byName[c.Name] = c
+	}
+	assert.True(t, byName[middleware.AccessTokenCookie].HttpOnly)
+	assert.True(t, byName[middleware.AccessTokenCookie].Secure)
+	assert.Equal(t, http.SameSiteStrictMode, byName[middleware.AccessTokenCookie].SameSite)
+	assert.True(t, byName[middleware.RefreshTokenCookie].HttpOnly)
+	assert.False(t, byName[middleware.CSRFCookie].HttpOnly)
+
+	request := func(method, path, csrf string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString("{}"))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		if csrf != "" {
+			req.Header.Set(middleware.CSRFHeader, csrf)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, request("GET", "/me", "").Code)
+	assert.Equal(t, http.StatusForbidden, request("POST", "/feedback", "").Code)
+	assert.Equal(t, http.StatusForbidden, request("POST", "/feedback", "forged").Code)
+	assert.Equal(t, http.StatusOK, request("POST", "/feedback", byName[middleware.CSRFCookie].Value).Code)
+
+	rr = request("POST", "/logout", byName[middleware.CSRFCookie].Value)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	for _, c := range rr.Result().Cookies() {
+		assert.Equal(t, "", c.Value)
+		assert.True(t, c.MaxAge < 0)
+	}
+	assert.Len(t, rr.Result().Cookies(), 3)
+
+	// The access token in the cookie was revoked by the logout.
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/me", "").Code)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/services"
+	"net/http"
+	"strconv"
+)
+
+type ExportAPI struct {
+	userService   services.UserManagement
+	exportService services.ExportManagement
+}
+
+func NewExportAPI(userService services.UserManagement, exportService services.ExportManagement) *ExportAPI {
+	return &ExportAPI{userService, exportService}
+}
+
+func (h *ExportAPI) ExportMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	h.export(w, user, user.ID)
+}
+
+func (h *ExportAPI) ExportUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		logger.Error("Invalid user ID:", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	h.export(w, admin, userID)
+}
+
+// export streams the archive straight into the response. Once the first byte is written
+// the status can no longer change, so later failures only show up as a truncated archive.
+func (h *ExportAPI) export(w http.ResponseWriter, actor *models.User, userID int) {
+	user, err := h.userService.User(userID)
+	if err != nil {
+		logger.Error("Failed to get user", err)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="louderspace-export-%d.zip"`, user.ID))
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.exportService.Export(actor, user, w); err != nil {
+		logger.Error("Failed to export data for user", user.ID, err)
+		return
+	}
+	logger.Info("Exported data for user", user.ID, "by user", actor.ID)
+}
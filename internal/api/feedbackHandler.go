@@ -19,12 +19,18 @@ func NewFeedbackAPI(feedbackService *services.FeedbackService) *FeedbackAPI {
 }
 
 func (a *FeedbackAPI) SaveFeedback(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	var feedback models.Feedback
 	if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
 		logger.Error("Failed to decode request body:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	feedback.UserID = user.ID
 	if err := a.feedbackService.SaveFeedback(&feedback); err != nil {
 		logger.Error("Failed to save feedback:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -40,10 +46,8 @@ func (a *FeedbackAPI) SaveFeedback(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *FeedbackAPI) DeleteFeedback(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		logger.Error("Failed to parse user ID:", err)
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 	songID, err := strconv.Atoi(r.URL.Query().Get("song_id"))
@@ -52,20 +56,18 @@ func (a *FeedbackAPI) DeleteFeedback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid song ID", http.StatusBadRequest)
 		return
 	}
-	if err := a.feedbackService.DeleteFeedback(userID, songID); err != nil {
+	if err := a.feedbackService.DeleteFeedback(user.ID, songID); err != nil {
 		logger.Error("Failed to delete feedback:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logger.Info("Deleted feedback for user", userID, "and song", songID)
+	logger.Info("Deleted feedback for user", user.ID, "and song", songID)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (a *FeedbackAPI) GetFeedback(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		logger.Error("Failed to parse user ID:", err)
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 	songID, err := strconv.Atoi(r.URL.Query().Get("song_id"))
@@ -74,7 +76,7 @@ func (a *FeedbackAPI) GetFeedback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid song ID", http.StatusBadRequest)
 		return
 	}
-	feedback, err := a.feedbackService.GetFeedback(userID, songID)
+	feedback, err := a.feedbackService.GetFeedback(user.ID, songID)
 	if err != nil {
 		logger.Error("Failed to get feedback:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
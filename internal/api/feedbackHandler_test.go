@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedbackAPI_SaveFeedbackUsesTokenUser(t *testing.T) {
+	storage := repositories.NewMockFeedbackStorage()
+	feedbackAPI := NewFeedbackAPI(services.NewFeedbackService(storage))
+
+	body, _ := json.Marshal(models.Feedback{UserID: 1, SongID: 5, Liked: true})
+	req, err := http.NewRequest("POST", "/feedback", bytes.NewBuffer(body))
+	assert.NoError(t, err)
+	req = withUser(req, 2, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(feedbackAPI.SaveFeedback).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	_, err = storage.GetFeedback(1, 5)
+	assert.Error(t, err)
+	saved, err := storage.GetFeedback(2, 5)
+	assert.NoError(t, err)
+	assert.True(t, saved.Liked)
+}
+
+func TestFeedbackAPI_DeleteFeedbackOfAnotherUser(t *testing.T) {
+	storage := repositories.NewMockFeedbackStorage()
+	feedbackAPI := NewFeedbackAPI(services.NewFeedbackService(storage))
+	storage.SaveFeedback(&models.Feedback{UserID: 1, SongID: 5, Liked: true})
+
+	req, err := http.NewRequest("DELETE", "/feedback?user_id=1&song_id=5", nil)
+	assert.NoError(t, err)
+	req = withUser(req, 2, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(feedbackAPI.DeleteFeedback).ServeHTTP(rr, req)
+	assert.NotEqual(t, http.StatusNoContent, rr.Code)
+
+	_, err = storage.GetFeedback(1, 5)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type InviteAPI struct {
+	invites services.InviteManagement
+}
+
+func NewInviteAPI(invites services.InviteManagement) *InviteAPI {
+	return &InviteAPI{invites}
+}
+
+func (h *InviteAPI) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role      models.Role `json:"role"`
+		MaxUses   int         `json:"max_uses"`
+		ExpiresAt *time.Time  `json:"expires_at"`
+		Note      string      `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request body:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	code, invite, err := h.invites.CreateInvite(admin, req.Role, req.MaxUses, req.ExpiresAt, req.Note)
+	if err != nil {
+		logger.Error("Failed to create invite:", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrInvalidMaxUses), errors.Is(err, services.ErrExpiryInThePast):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to create invite", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("Created invite", invite.ID, "by admin", admin.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":   code,
+		"invite": invite,
+	})
+}
+
+func (h *InviteAPI) Invites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.invites.Invites()
+	if err != nil {
+		logger.Error("Failed to get invites:", err)
+		http.Error(w, "Failed to get invites", http.StatusInternalServerError)
+		return
+	}
+	if invites == nil {
+		invites = []*models.Invite{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
+
+func (h *InviteAPI) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	inviteID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		logger.Error("Invalid invite ID:", err)
+		http.Error(w, "Invalid invite ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.invites.RevokeInvite(inviteID); err != nil {
+		logger.Error("Failed to revoke invite:", err)
+		if errors.Is(err, services.ErrInviteNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke invite", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Revoked invite", inviteID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/mailer"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInviteOnlyRegistration(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	loginThrottle := services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage())
+	twoFactor := services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+	invites := services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationInviteOnly)
+	authAPI := NewAuthAPI(userService, tokenService, accountService, loginThrottle, twoFactor, invites, NewSessionCookies(false, false, "lax", ""))
+	inviteAPI := NewInviteAPI(invites)
+
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	userStorage.Save(admin)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/registration", authAPI.Registration).Methods("GET")
+	r.HandleFunc("/register", authAPI.Register).Methods("POST")
+	r.HandleFunc("/admin/invites", inviteAPI.CreateInvite).Methods("POST")
+	r.HandleFunc("/admin/invites", inviteAPI.Invites).Methods("GET")
+	r.HandleFunc("/admin/invites/{id:[0-9]+}", inviteAPI.RevokeInvite).Methods("DELETE")
+	send := func(method, path string, payload interface{}, asAdmin bool) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		if asAdmin {
+			req = withUser(req, admin.ID, models.RoleAdmin)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	register := func(username, code string) *httptest.ResponseRecorder {
+		return send("POST", "/register", map[string]string{
+			"username":    username,
+			"password":    "Tr0ub4dor-and-3",
+			"email":       username + "@example.com",
+			"invite_code": code,
+		}, false)
+	}
+
+	rr := send("GET", "/registration", nil, false)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"mode":"invite_only"}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, register("testuser", "").Code)
+
+	rr = send("POST", "/admin/invites", map[string]interface{}{"role": "superuser"}, true)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = send("POST", "/admin/invites", map[string]interface{}{"role": "premium", "max_uses": 1, "note": "beta"}, true)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created struct {
+		Code   string        `json:"code"`
+		Invite models.Invite `json:"invite"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Code)
+	assert.NotContains(t, rr.Body.String(), "code_hash")
+
+	rr = register("testuser", created.Code)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var user models.User
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &user))
+	assert.Equal(t, models.RolePremium, user.Role)
+
+	assert.Equal(t, http.StatusForbidden, register("another", created.Code).Code)
+
+	rr = send("GET", "/admin/invites", nil, true)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var listed []models.Invite
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1)
+	assert.Equal(t, 1, listed[0].Uses)
+
+	path := "/admin/invites/" + strconv.Itoa(created.Invite.ID)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", path, nil, true).Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", path, nil, true).Code)
+}
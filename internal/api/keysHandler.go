@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/json"
+	"louderspace/internal/logger"
+	"louderspace/internal/utils"
+	"net/http"
+)
+
+type KeysAPI struct {
+	keys *utils.KeySet
+}
+
+func NewKeysAPI(keys *utils.KeySet) *KeysAPI {
+	return &KeysAPI{keys}
+}
+
+// JWKS publishes the public token verification keys.
+func (h *KeysAPI) JWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": h.keys.JWKS(),
+	})
+	if err != nil {
+		logger.Error("Failed to encode response:", err)
+		return
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := mux.NewRouter()
+	r.HandleFunc("/admin/songs/{id:[0-9]+}", middleware.RequirePermission(models.PermissionCatalogRead, ok)).Methods("GET")
+	r.HandleFunc("/admin/songs/{id:[0-9]+}", middleware.RequirePermission(models.PermissionCatalogWrite, ok)).Methods("PUT")
+	r.HandleFunc("/admin/pomodoro/user/{user_id}/metrics", middleware.RequirePermission(models.PermissionAnalyticsRead, ok)).Methods("GET")
+	r.HandleFunc("/admin/users", middleware.RequirePermission(models.PermissionUsersRead, ok)).Methods("GET")
+
+	request := func(role models.Role, method, path string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req = withUser(req, 1, role)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Analysts get a read-only view of the catalog and analytics.
+	assert.Equal(t, http.StatusOK, request(models.RoleAnalyst, "GET", "/admin/songs/1"))
+	assert.Equal(t, http.StatusOK, request(models.RoleAnalyst, "GET", "/admin/pomodoro/user/1/metrics"))
+	assert.Equal(t, http.StatusForbidden, request(models.RoleAnalyst, "PUT", "/admin/songs/1"))
+	assert.Equal(t, http.StatusForbidden, request(models.RoleAnalyst, "GET", "/admin/users"))
+
+	assert.Equal(t, http.StatusForbidden, request(models.RolePremium, "GET", "/admin/songs/1"))
+	assert.Equal(t, http.StatusOK, request(models.RoleAdmin, "PUT", "/admin/songs/1"))
+	assert.Equal(t, http.StatusOK, request(models.RoleAdmin, "GET", "/admin/users"))
+}
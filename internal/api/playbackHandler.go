@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"louderspace/internal/services"
 	"net/http"
 	"strconv"
@@ -16,9 +17,8 @@ func NewPlaybackAPI(playbackService services.PlaybackManagement) *PlaybackAPI {
 }
 
 func (h *PlaybackAPI) Play(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
@@ -28,8 +28,15 @@ func (h *PlaybackAPI) Play(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playbackState, err := h.playbackService.Play(userID, stationID)
+	playbackState, err := h.playbackService.Play(user, stationID)
 	if err != nil {
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -39,14 +46,20 @@ func (h *PlaybackAPI) Play(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlaybackAPI) Pause(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	playbackState, err := h.playbackService.Pause(userID)
+	playbackState, err := h.playbackService.Pause(user)
 	if err != nil {
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -56,14 +69,20 @@ func (h *PlaybackAPI) Pause(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlaybackAPI) Skip(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	playbackState, err := h.playbackService.Skip(userID)
+	playbackState, err := h.playbackService.Skip(user)
 	if err != nil {
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -73,14 +92,20 @@ func (h *PlaybackAPI) Skip(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlaybackAPI) Rewind(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	playbackState, err := h.playbackService.Rewind(userID)
+	playbackState, err := h.playbackService.Rewind(user)
 	if err != nil {
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -90,14 +115,20 @@ func (h *PlaybackAPI) Rewind(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlaybackAPI) GetPlaybackState(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	playbackState, err := h.playbackService.GetPlaybackState(userID)
+	playbackState, err := h.playbackService.GetPlaybackState(user)
 	if err != nil {
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
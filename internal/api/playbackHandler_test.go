@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"louderspace/internal/middleware"
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
 	"louderspace/internal/services"
@@ -13,6 +15,11 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func withUser(req *http.Request, userID int, role models.Role) *http.Request {
+	user := &models.User{ID: userID, Role: role}
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+}
+
 func TestPlaybackAPI_Play(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
 	playbackService := services.NewPlaybackService(storage)
@@ -29,9 +36,11 @@ func TestPlaybackAPI_Play(t *testing.T) {
 		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
 	}
 
-	req, err := http.NewRequest("GET", "/playback/play?user_id=1&station_id="+strconv.Itoa(station.ID), nil)
+	req, err := http.NewRequest("GET", "/playback/play?station_id="+strconv.Itoa(station.ID), nil)
 	assert.NoError(t, err)
 
+	req = withUser(req, 1, models.RoleFree)
+
 	rr := httptest.NewRecorder()
 	http.HandlerFunc(playbackAPI.Play).ServeHTTP(rr, req)
 
@@ -60,10 +69,12 @@ func TestPlaybackAPI_Pause(t *testing.T) {
 		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
 	}
 
-	playbackService.Play(1, station.ID)
-	req, err := http.NewRequest("GET", "/playback/pause?user_id=1", nil)
+	playbackService.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	req, err := http.NewRequest("GET", "/playback/pause", nil)
 	assert.NoError(t, err)
 
+	req = withUser(req, 1, models.RoleFree)
+
 	rr := httptest.NewRecorder()
 	http.HandlerFunc(playbackAPI.Pause).ServeHTTP(rr, req)
 
@@ -91,10 +102,12 @@ func TestPlaybackAPI_Skip(t *testing.T) {
 		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
 	}
 
-	playbackService.Play(1, station.ID)
-	req, err := http.NewRequest("GET", "/playback/skip?user_id=1", nil)
+	playbackService.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	req, err := http.NewRequest("GET", "/playback/skip", nil)
 	assert.NoError(t, err)
 
+	req = withUser(req, 1, models.RoleFree)
+
 	rr := httptest.NewRecorder()
 	http.HandlerFunc(playbackAPI.Skip).ServeHTTP(rr, req)
 
@@ -121,10 +134,12 @@ func TestPlaybackAPI_Rewind(t *testing.T) {
 		{ID: 1, Title: "Chill Song 1", Artist: "Artist 1", Genre: "chill, beats"},
 	}
 
-	playbackService.Play(1, station.ID)
-	req, err := http.NewRequest("GET", "/playback/rewind?user_id=1", nil)
+	playbackService.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	req, err := http.NewRequest("GET", "/playback/rewind", nil)
 	assert.NoError(t, err)
 
+	req = withUser(req, 1, models.RoleFree)
+
 	rr := httptest.NewRecorder()
 	http.HandlerFunc(playbackAPI.Rewind).ServeHTTP(rr, req)
 
@@ -151,10 +166,12 @@ func TestPlaybackAPI_GetPlaybackState(t *testing.T) {
 		{ID: 1, Title: "Chill Song 1", Artist: "Artist 1", Genre: "chill, beats"},
 	}
 
-	playbackService.Play(1, station.ID)
-	req, err := http.NewRequest("GET", "/playback/state?user_id=1", nil)
+	playbackService.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	req, err := http.NewRequest("GET", "/playback/state", nil)
 	assert.NoError(t, err)
 
+	req = withUser(req, 1, models.RoleFree)
+
 	rr := httptest.NewRecorder()
 	http.HandlerFunc(playbackAPI.GetPlaybackState).ServeHTTP(rr, req)
 
@@ -165,3 +182,78 @@ func TestPlaybackAPI_GetPlaybackState(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Chill Song 1", playbackState.CurrentSong.Title)
 }
+
+func TestPlaybackAPI_IgnoresUserIDParam(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	playbackService := services.NewPlaybackService(storage)
+	playbackAPI := NewPlaybackAPI(playbackService)
+
+	station := &models.Station{
+		Name: "Chill Beats",
+		Tags: []string{"chill", "beats"},
+	}
+	storage.Create(station)
+
+	storage.Songs = []*models.Song{
+		{ID: 1, Title: "Chill Song 1", Artist: "Artist 1", Genre: "chill, beats"},
+		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
+	}
+
+	playbackService.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+
+	// User 2 asks for user 1's state and tries to pause it.
+	req, err := http.NewRequest("GET", "/playback/state?user_id=1", nil)
+	assert.NoError(t, err)
+	req = withUser(req, 2, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(playbackAPI.GetPlaybackState).ServeHTTP(rr, req)
+	assert.NotEqual(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "Chill Song 1")
+
+	req, err = http.NewRequest("POST", "/playback/pause?user_id=1", nil)
+	assert.NoError(t, err)
+	req = withUser(req, 2, models.RoleFree)
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(playbackAPI.Pause).ServeHTTP(rr, req)
+	assert.NotEqual(t, http.StatusOK, rr.Code)
+
+	state, err := playbackService.GetPlaybackState(&models.User{ID: 1, Role: models.RoleFree})
+	assert.NoError(t, err)
+	assert.True(t, state.IsPlaying)
+}
+
+func TestPlaybackAPI_RequiresUser(t *testing.T) {
+	playbackAPI := NewPlaybackAPI(services.NewPlaybackService(repositories.NewStationStorageMock()))
+
+	req, err := http.NewRequest("GET", "/playback/state?user_id=1", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(playbackAPI.GetPlaybackState).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestPlaybackAPI_UpgradeRequired(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	playbackAPI := NewPlaybackAPI(services.NewPlaybackService(storage))
+
+	station := &models.Station{Name: "Deep Focus", Tags: []string{"focus"}, Premium: true}
+	storage.Create(station)
+	storage.Songs = []*models.Song{{ID: 1, Title: "Focus Song", Genre: "focus"}}
+
+	req, err := http.NewRequest("GET", "/playback/play?station_id="+strconv.Itoa(station.ID), nil)
+	assert.NoError(t, err)
+	req = withUser(req, 1, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(playbackAPI.Play).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "upgrade_required", body["error"])
+	assert.Equal(t, models.FeaturePremiumStations, body["feature"])
+	assert.Equal(t, string(models.RolePremium), body["upgrade_to"])
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"louderspace/internal/logger"
 	"louderspace/internal/models"
@@ -20,17 +21,13 @@ func NewPomodoroSessionAPI(pomodoroService services.PomodoroSessionManagement) *
 }
 
 func (h *PomodoroSessionAPI) StartSession(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		UserID int `json:"user_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("Failed to decode request body:", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
 	session := &models.PomodoroSession{
-		UserID:    req.UserID,
+		UserID:    user.ID,
 		StartTime: time.Now(),
 		Status:    "ongoing",
 	}
@@ -52,6 +49,11 @@ func (h *PomodoroSessionAPI) StartSession(w http.ResponseWriter, r *http.Request
 }
 
 func (h *PomodoroSessionAPI) EndSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	var req struct {
 		SessionID int `json:"session_id"`
 	}
@@ -61,8 +63,12 @@ func (h *PomodoroSessionAPI) EndSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.pomodoroService.EndSession(req.SessionID); err != nil {
+	if err := h.pomodoroService.EndSession(user.ID, req.SessionID); err != nil {
 		logger.Error("Failed to end pomodoro session:", err)
+		if errors.Is(err, services.ErrSessionNotOwned) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -71,6 +77,38 @@ func (h *PomodoroSessionAPI) EndSession(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// History returns the current user's sessions, optionally from ?since= (RFC 3339) onwards.
+func (h *PomodoroSessionAPI) History(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	sessions, err := h.pomodoroService.History(user, since)
+	if err != nil {
+		logger.Error("Failed to get pomodoro history:", err)
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Got pomodoro history for user:", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
 func (h *PomodoroSessionAPI) GetSessionsByUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID, err := strconv.Atoi(vars["user_id"])
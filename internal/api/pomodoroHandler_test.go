@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPomodoroSessionAPI_StartSessionUsesTokenUser(t *testing.T) {
+	storage := repositories.NewMockPomodoroSessionStorage()
+	pomodoroAPI := NewPomodoroSessionAPI(services.NewPomodoroSessionService(storage))
+
+	body, _ := json.Marshal(map[string]int{"user_id": 1})
+	req, err := http.NewRequest("POST", "/pomodoro/start", bytes.NewBuffer(body))
+	assert.NoError(t, err)
+	req = withUser(req, 2, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(pomodoroAPI.StartSession).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var session models.PomodoroSession
+	err = json.NewDecoder(rr.Body).Decode(&session)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, session.UserID)
+}
+
+func TestPomodoroSessionAPI_EndSessionOfAnotherUser(t *testing.T) {
+	storage := repositories.NewMockPomodoroSessionStorage()
+	pomodoroAPI := NewPomodoroSessionAPI(services.NewPomodoroSessionService(storage))
+
+	session := &models.PomodoroSession{UserID: 1, StartTime: time.Now(), Status: "ongoing"}
+	storage.Create(session)
+
+	body, _ := json.Marshal(map[string]int{"session_id": session.ID})
+	req, err := http.NewRequest("POST", "/pomodoro/end", bytes.NewBuffer(body))
+	assert.NoError(t, err)
+	req = withUser(req, 2, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(pomodoroAPI.EndSession).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	stored, err := storage.ByID(session.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "ongoing", stored.Status)
+}
+
+func TestPomodoroSessionAPI_AdminImpersonation(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := repositories.NewMockAuditStorage()
+	target := &models.User{Username: "listener", Role: models.RoleFree}
+	userStorage.Save(target)
+
+	storage := repositories.NewMockPomodoroSessionStorage()
+	pomodoroAPI := NewPomodoroSessionAPI(services.NewPomodoroSessionService(storage))
+	impersonate := middleware.Impersonate(services.NewImpersonationService(userStorage, auditStorage))
+	handler := impersonate(http.HandlerFunc(pomodoroAPI.StartSession))
+
+	// A regular user cannot impersonate.
+	req, err := http.NewRequest("POST", "/pomodoro/start", nil)
+	assert.NoError(t, err)
+	req.Header.Set(middleware.ImpersonateHeader, "1")
+	req = withUser(req, 99, models.RoleFree)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Empty(t, auditStorage.Entries)
+
+	// An admin can, and the request is audited.
+	req, err = http.NewRequest("POST", "/pomodoro/start", nil)
+	assert.NoError(t, err)
+	req.Header.Set(middleware.ImpersonateHeader, "1")
+	req = withUser(req, 42, models.RoleAdmin)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var session models.PomodoroSession
+	err = json.NewDecoder(rr.Body).Decode(&session)
+	assert.NoError(t, err)
+	assert.Equal(t, target.ID, session.UserID)
+
+	assert.Len(t, auditStorage.Entries, 1)
+	assert.Equal(t, 42, auditStorage.Entries[0].ActorID)
+	assert.Equal(t, target.ID, auditStorage.Entries[0].TargetUserID)
+	assert.Equal(t, models.AuditActionImpersonate, auditStorage.Entries[0].Action)
+	assert.Equal(t, "POST /pomodoro/start", auditStorage.Entries[0].Details)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/services"
+	"net/http"
+	"strconv"
+)
+
+type SearchAPI struct {
+	searchService services.SearchManagement
+}
+
+func NewSearchAPI(searchService services.SearchManagement) *SearchAPI {
+	return &SearchAPI{searchService}
+}
+
+func (h *SearchAPI) Search(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			logger.Error("Invalid search limit:", err)
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.searchService.Search(user.ID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		logger.Error("Failed to search:", err)
+		if errors.Is(err, services.ErrInvalidSearch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Searched for", results.Query)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	stationStorage := repositories.NewStationStorageMock()
+	searchAPI := NewSearchAPI(services.NewSearchService(songStorage, stationStorage, repositories.NewMockTagStorage()))
+	_, err := services.NewSongService(songStorage).CreateSong("Midnight Chill", "Nova", "lofi", "1", true, []string{"focus"}, models.SongMetadata{})
+	assert.NoError(t, err)
+	assert.NoError(t, stationStorage.Create(&models.Station{Name: "Chill Beats", Tags: []string{"chill"}}))
+
+	search := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/search?"+query, http.NoBody)
+		rr := httptest.NewRecorder()
+		searchAPI.Search(rr, withUser(req, 1, models.RoleFree))
+		return rr
+	}
+
+	rr := search("q=chil")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var results models.SearchResults
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&results))
+	assert.Len(t, results.Songs, 1)
+	assert.Len(t, results.Stations, 1)
+	assert.Empty(t, results.Tags)
+
+	assert.Equal(t, http.StatusBadRequest, search("q=").Code)
+	assert.Equal(t, http.StatusBadRequest, search("q=chill&limit=many").Code)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"net/http"
+	"strings"
+)
+
+// newSession describes the device a request comes from, for a session about to be started.
+// deviceName is what the client calls itself; without one a name is derived from the
+// user agent.
+func newSession(r *http.Request, deviceName string) *models.Session {
+	userAgent := r.UserAgent()
+	deviceName = strings.TrimSpace(deviceName)
+	if deviceName == "" {
+		deviceName = deviceNameFromUserAgent(userAgent)
+	}
+	if len(deviceName) > 100 {
+		deviceName = deviceName[:100]
+	}
+	return &models.Session{
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		IP:         middleware.ClientIP(r),
+	}
+}
+
+// deviceNameFromUserAgent produces a rough "Browser on OS" label. It only has to be good
+// enough for people to recognise their own devices in the session list.
+func deviceNameFromUserAgent(userAgent string) string {
+	browser := firstMatch(userAgent, [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	})
+	os := firstMatch(userAgent, [][2]string{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	})
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}
+
+func firstMatch(userAgent string, candidates [][2]string) string {
+	for _, candidate := range candidates {
+		if strings.Contains(userAgent, candidate[0]) {
+			return candidate[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"louderspace/internal/mailer"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	loginThrottle := services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage())
+	twoFactor := services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, loginThrottle, twoFactor, services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, false, "lax", ""))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	userStorage.Save(&models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", CreatedAt: time.Now()})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/login", authAPI.Login).Methods("POST")
+	protected := r.PathPrefix("/").Subrouter()
+	protected.Use(middleware.WithUser(tokenService, services.NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage), services.NewUserAdminService(userStorage, repositories.NewMockAuditStorage(), tokenService)))
+	protected.HandleFunc("/me/sessions", authAPI.Sessions).Methods("GET")
+	protected.HandleFunc("/me/sessions/{id:[0-9]+}", authAPI.RevokeSession).Methods("DELETE")
+
+	login := func(deviceName, userAgent string) string {
+		body, _ := json.Marshal(map[string]string{"username": "testuser", "password": "password123", "device_name": deviceName})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		req.Header.Set("User-Agent", userAgent)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp["token"].(string)
+	}
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	laptop := login("Work laptop", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Firefox/128.0")
+	phone := login("", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile Safari/604.1")
+
+	rr := request("GET", "/me/sessions", laptop)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var sessions []*models.Session
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sessions))
+	assert.Len(t, sessions, 2)
+
+	var phoneSession *models.Session
+	for _, session := range sessions {
+		if session.Current {
+			assert.Equal(t, "Work laptop", session.DeviceName)
+		} else {
+			phoneSession = session
+		}
+	}
+	assert.NotNil(t, phoneSession)
+	assert.NotEmpty(t, phoneSession.DeviceName)
+
+	assert.Equal(t, http.StatusNotFound, request("DELETE", "/me/sessions/999", laptop).Code)
+	assert.Equal(t, http.StatusNoContent, request("DELETE", "/me/sessions/"+strconv.Itoa(phoneSession.ID), laptop).Code)
+
+	// The phone's access token stops working as soon as its session is revoked.
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/me/sessions", phone).Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/me/sessions", laptop).Code)
+}
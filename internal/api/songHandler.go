@@ -2,12 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
 	"louderspace/internal/logger"
 	"louderspace/internal/models"
 	"louderspace/internal/services"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 )
 
 type SongAPI struct {
@@ -26,6 +30,7 @@ func (h *SongAPI) CreateSong(w http.ResponseWriter, r *http.Request) {
 		SunoID      string   `json:"suno_id"`
 		IsGenerated bool     `json:"is_generated"`
 		Tags        []string `json:"tags"`
+		models.SongMetadata
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("Failed to decode request body:", err)
@@ -33,9 +38,13 @@ func (h *SongAPI) CreateSong(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	song, err := h.songService.CreateSong(req.Title, req.Artist, req.Genre, req.SunoID, req.IsGenerated, req.Tags)
+	song, err := h.songService.CreateSong(req.Title, req.Artist, req.Genre, req.SunoID, req.IsGenerated, req.Tags, req.SongMetadata)
 	if err != nil {
 		logger.Error("Failed to create song:", err)
+		if errors.Is(err, services.ErrInvalidSongMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -90,15 +99,92 @@ func (h *SongAPI) GetSongBySunoID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(song)
 }
 
-func (h *SongAPI) GetAllSongs(w http.ResponseWriter, r *http.Request) {
-	songs, err := h.songService.GetAllSongs()
+// ListSongs returns a page of songs, filtered by the tag, genre, artist, is_generated,
+// created_after and created_before parameters and ordered by sort and order. The next page
+// is fetched by passing the page's next_cursor as cursor with the same sort and order.
+func (h *SongAPI) ListSongs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSongFilter(r.URL.Query())
 	if err != nil {
+		logger.Error("Invalid song filter:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.songService.ListSongs(filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		logger.Error("Failed to list songs:", err)
+		if errors.Is(err, services.ErrInvalidSongFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	logger.Info("Listed", len(page.Songs), "songs")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(songs)
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseSongFilter(query url.Values) (models.SongFilter, error) {
+	filter := models.SongFilter{
+		Tag:    query.Get("tag"),
+		Genre:  query.Get("genre"),
+		Artist: query.Get("artist"),
+		Sort:   query.Get("sort"),
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, fmt.Errorf("%w: order must be asc or desc", services.ErrInvalidSongFilter)
+	}
+
+	if value := query.Get("is_generated"); value != "" {
+		isGenerated, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("%w: is_generated must be true or false", services.ErrInvalidSongFilter)
+		}
+		filter.IsGenerated = &isGenerated
+	}
+
+	if err := parseCreatedRange(query, services.ErrInvalidSongFilter, &filter.CreatedAfter, &filter.CreatedBefore); err != nil {
+		return filter, err
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("%w: limit must be a number", services.ErrInvalidSongFilter)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// parseCreatedRange reads created_after and created_before, in that order, as RFC 3339 times
+// or plain dates. Errors wrap invalid.
+func parseCreatedRange(query url.Values, invalid error, after, before **time.Time) error {
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"created_after", after}, {"created_before", before}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", value); err != nil {
+				return fmt.Errorf("%w: %s must be a date", invalid, param.name)
+			}
+		}
+		*param.dest = &t
+	}
+	return nil
 }
 
 func (h *SongAPI) UpdateSong(w http.ResponseWriter, r *http.Request) {
@@ -117,6 +203,7 @@ func (h *SongAPI) UpdateSong(w http.ResponseWriter, r *http.Request) {
 		SunoID      string   `json:"suno_id"`
 		IsGenerated bool     `json:"is_generated"`
 		Tags        []string `json:"tags"` // Add tags field
+		models.SongMetadata
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("Failed to decode request body:", err)
@@ -125,16 +212,21 @@ func (h *SongAPI) UpdateSong(w http.ResponseWriter, r *http.Request) {
 	}
 
 	song := &models.Song{
-		ID:          id,
-		Title:       req.Title,
-		Artist:      req.Artist,
-		Genre:       req.Genre,
-		SunoID:      req.SunoID,
-		IsGenerated: req.IsGenerated,
+		ID:           id,
+		Title:        req.Title,
+		Artist:       req.Artist,
+		Genre:        req.Genre,
+		SunoID:       req.SunoID,
+		IsGenerated:  req.IsGenerated,
+		SongMetadata: req.SongMetadata,
 	}
 
 	if _, err := h.songService.UpdateSong(song, req.Tags); err != nil {
 		logger.Error("Failed to update song:", err)
+		if errors.Is(err, services.ErrInvalidSongMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
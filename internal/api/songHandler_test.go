@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListSongs(t *testing.T) {
+	songService := services.NewSongService(repositories.NewSongStorageMock())
+	songAPI := NewSongAPI(songService)
+	for _, title := range []string{"Bravo", "Alpha", "Charlie"} {
+		_, err := songService.CreateSong(title, "Artist", "synth", title, false, []string{"focus"}, models.SongMetadata{})
+		assert.NoError(t, err)
+	}
+
+	list := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/songs?"+query, http.NoBody)
+		rr := httptest.NewRecorder()
+		songAPI.ListSongs(rr, req)
+		return rr
+	}
+
+	rr := list("sort=title&order=desc&limit=2")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var page models.SongPage
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+	assert.Len(t, page.Songs, 2)
+	assert.Equal(t, "Charlie", page.Songs[0].Title)
+	assert.NotEmpty(t, page.NextCursor)
+
+	rr = list("sort=title&order=desc&limit=2&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	page = models.SongPage{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+	assert.Len(t, page.Songs, 1)
+	assert.Equal(t, "Alpha", page.Songs[0].Title)
+	assert.Empty(t, page.NextCursor)
+
+	for _, query := range []string{"order=sideways", "is_generated=maybe", "created_after=yesterday", "limit=ten", "sort=duration", "cursor=bogus"} {
+		assert.Equal(t, http.StatusBadRequest, list(query).Code, query)
+	}
+	// With both dates invalid the error always names created_after.
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, list("created_after=soon&created_before=later").Body.String(), "created_after must be a date")
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/services"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const maxSongImportBytes = 32 << 20
+
+type SongImportAPI struct {
+	importService services.SongImportManagement
+}
+
+func NewSongImportAPI(importService services.SongImportManagement) *SongImportAPI {
+	return &SongImportAPI{importService}
+}
+
+// Import takes the file as the request body. The format comes from ?format=csv|jsonl or else
+// the Content-Type; ?dry_run=true only validates.
+func (h *SongImportAPI) Import(w http.ResponseWriter, r *http.Request) {
+	format := models.SongImportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = models.SongImportCSV
+		case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+			format = models.SongImportJSONL
+		}
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			logger.Error("Invalid dry_run:", err)
+			http.Error(w, "dry_run must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSongImportBytes))
+	if err != nil {
+		logger.Error("Failed to read import file:", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "The import file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read import file", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.importService.Import(bytes.NewReader(body), format, dryRun)
+	if err != nil {
+		logger.Error("Failed to import songs:", err)
+		if errors.Is(err, services.ErrInvalidSongImport) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to import songs", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
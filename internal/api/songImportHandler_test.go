@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportSongs(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	tagStorage := repositories.NewMockTagStorage()
+	assert.NoError(t, tagStorage.Create(&models.Tag{Name: "focus"}))
+	importAPI := NewSongImportAPI(services.NewSongImportService(services.NewSongService(songStorage), tagStorage))
+
+	upload := func(query, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/songs/import?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		importAPI.Import(rr, withUser(req, 1, models.RoleAdmin))
+		return rr
+	}
+	csv := "title,artist,suno_id,tags\nNight Drive,Nova,suno-1,focus\nRain,Nova,suno-2,jazz\n"
+
+	rr := upload("dry_run=true", "text/csv", csv)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report models.SongImportReport
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Valid)
+	assert.Equal(t, 1, report.Invalid)
+
+	rr = upload("format=csv", "application/octet-stream", csv)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, models.SongImportInvalid, report.Rows[1].Status)
+
+	rr = upload("", "application/x-ndjson", `{"title": "Rain", "artist": "Nova", "suno_id": "suno-2", "tags": ["focus"]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, 1, report.Created)
+
+	assert.Equal(t, http.StatusBadRequest, upload("", "text/plain", csv).Code)
+	assert.Equal(t, http.StatusBadRequest, upload("dry_run=maybe", "text/csv", csv).Code)
+	assert.Equal(t, http.StatusBadRequest, upload("", "text/csv", "title\nNight Drive\n").Code)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/services"
+	"net/http"
+)
+
+type SongIngestAPI struct {
+	ingestService services.SongIngestion
+}
+
+func NewSongIngestAPI(ingestService services.SongIngestion) *SongIngestAPI {
+	return &SongIngestAPI{ingestService}
+}
+
+func (h *SongIngestAPI) IngestTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string   `json:"task_id"`
+		Genre  string   `json:"genre"`
+		Tags   []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request body:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	songs, err := h.ingestService.IngestTask(req.TaskID, req.Genre, req.Tags)
+	if err != nil {
+		logger.Error("Failed to ingest Suno task:", err)
+		switch {
+		case errors.Is(err, services.ErrMissingTaskID), errors.Is(err, services.ErrInvalidSongMetadata):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrClipsNotReady), errors.Is(err, services.ErrNoClipsIngested):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to ingest Suno task", http.StatusBadGateway)
+		}
+		return
+	}
+
+	logger.Info("Ingested songs from Suno task", req.TaskID, len(songs))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"songs": songs})
+}
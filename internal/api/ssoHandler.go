@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/oidc"
+	"louderspace/internal/services"
+	"net/http"
+	"time"
+)
+
+// SSOAPI signs users in through an OpenID Connect provider. The provider redirects back to
+// the app's callback page, which posts the code and state to Callback; from there the login
+// ends like a password login, including the two-factor challenge.
+type SSOAPI struct {
+	sso  services.SSOManagement
+	auth *AuthAPI
+}
+
+func NewSSOAPI(sso services.SSOManagement, auth *AuthAPI) *SSOAPI {
+	return &SSOAPI{sso, auth}
+}
+
+// Start returns the provider URL the browser should be sent to, and sets the cookie that
+// binds the login to this browser.
+func (h *SSOAPI) Start(w http.ResponseWriter, r *http.Request) {
+	authURL, binding, err := h.sso.StartLogin()
+	if err != nil {
+		logger.Error("Failed to start SSO login", err)
+		http.Error(w, "Failed to start sign-in", http.StatusBadGateway)
+		return
+	}
+	h.auth.cookies.SetSSOBinding(w, binding, time.Now().Add(services.SSOLoginStateTTL))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"provider":          h.sso.Provider(),
+		"authorization_url": authURL,
+	})
+}
+
+func (h *SSOAPI) Callback(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		State      string `json:"state"`
+		Code       string `json:"code"`
+		DeviceName string `json:"device_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Without the binding cookie the login was not started in this browser, e.g. a callback
+	// link an attacker sent to the user.
+	binding := h.auth.cookies.SSOBinding(r)
+	if binding == "" {
+		logger.Error("SSO callback without a binding cookie")
+		http.Error(w, services.ErrInvalidSSOState.Error(), http.StatusBadRequest)
+		return
+	}
+	h.auth.cookies.ClearSSOBinding(w)
+
+	user, err := h.sso.CompleteLogin(req.State, binding, req.Code)
+	if err != nil {
+		logger.Error("SSO login failed", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidSSOState), errors.Is(err, services.ErrSSOEmailRequired):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, oidc.ErrExchangeFailed), errors.Is(err, oidc.ErrInvalidIDToken):
+			http.Error(w, "Sign-in was rejected by the identity provider", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrSSOAccountExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, services.ErrUserSuspended):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	challenge, err := h.auth.twoFactor.StartLogin(user)
+	if err != nil {
+		logger.Error("Failed to start two-factor login", err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		logger.Info("Two-factor challenge issued for SSO user", user.ID)
+		writeLoginChallenge(w, challenge)
+		return
+	}
+
+	logger.Info("SSO login for user", user.ID, "via", h.sso.Provider())
+	h.auth.completeLogin(w, user, newSession(r, req.DeviceName), nil)
+}
+
+// Identities lists the identity provider accounts linked to the user.
+func (h *SSOAPI) Identities(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	identities, err := h.sso.Identities(user.ID)
+	if err != nil {
+		logger.Error("Failed to list identities", err)
+		http.Error(w, "Failed to list identities", http.StatusInternalServerError)
+		return
+	}
+	if identities == nil {
+		identities = []*models.Identity{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(identities)
+}
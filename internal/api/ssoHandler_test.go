@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/mailer"
+	"louderspace/internal/models"
+	"louderspace/internal/oidc"
+	"louderspace/internal/oidc/oidctest"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"louderspace/internal/services"
+	"louderspace/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSOLogin(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	auditStorage := userStorage.AuditLog
+	twoFactor := services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, auditStorage)
+	authAPI := NewAuthAPI(userService, tokenService, accountService, services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, auditStorage), twoFactor, services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, true, "lax", ""))
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+	ssoService := services.NewSSOService(provider, repositories.NewMockIdentityStorage(), userStorage, tokenService, map[string]models.Role{"staff": models.RoleAnalyst})
+	ssoAPI := NewSSOAPI(ssoService, authAPI)
+
+	req, _ := http.NewRequest("POST", "/login/sso", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(ssoAPI.Start).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var start struct {
+		Provider         string `json:"provider"`
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &start))
+	assert.Equal(t, "corp", start.Provider)
+	bindings := rr.Result().Cookies()
+	assert.Len(t, bindings, 1)
+	assert.Equal(t, ssoBindingCookie, bindings[0].Name)
+	assert.True(t, bindings[0].HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, bindings[0].SameSite)
+
+	code, state, err := idp.Authorize(start.AuthorizationURL, map[string]interface{}{
+		"sub":            "jane-1",
+		"email":          "jane@corp.example",
+		"email_verified": true,
+		"groups":         []string{"staff"},
+	})
+	assert.NoError(t, err)
+
+	callback := func(state, code string, binding *http.Cookie) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"state": state, "code": code})
+		req, _ := http.NewRequest("POST", "/login/sso/callback", bytes.NewBuffer(body))
+		if binding != nil {
+			req.AddCookie(binding)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(ssoAPI.Callback).ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, callback("forged", code, bindings[0]).Code)
+	// A callback from a browser that did not start the login is rejected.
+	assert.Equal(t, http.StatusBadRequest, callback(state, code, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, callback(state, code, &http.Cookie{Name: ssoBindingCookie, Value: "other-browser"}).Code)
+
+	rr = callback(state, code, bindings[0])
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Token)
+	assert.Equal(t, "jane", resp.User.Username)
+	assert.Equal(t, models.RoleAnalyst, resp.User.Role)
+
+	claims, err := tokenService.ValidateAccessToken(resp.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAnalyst, claims.Role)
+
+	// Once the user has enabled 2FA, signing in through the provider needs the second factor too.
+	enrollment, err := twoFactor.Enroll(&resp.User)
+	assert.NoError(t, err)
+	totp, _ := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(time.Now()), utils.TOTPDigits)
+	_, err = twoFactor.Confirm(resp.User.ID, totp)
+	assert.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/login/sso", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(ssoAPI.Start).ServeHTTP(rr, req)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &start))
+	code, state, err = idp.Authorize(start.AuthorizationURL, map[string]interface{}{"sub": "jane-1", "email": "jane@corp.example"})
+	assert.NoError(t, err)
+	rr = callback(state, code, rr.Result().Cookies()[0])
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var challenge struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		Challenge         string `json:"challenge"`
+		Token             string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &challenge))
+	assert.True(t, challenge.TwoFactorRequired)
+	assert.NotEmpty(t, challenge.Challenge)
+	assert.Empty(t, challenge.Token)
+}
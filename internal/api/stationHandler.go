@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"louderspace/internal/logger"
 	"louderspace/internal/services"
@@ -19,8 +20,9 @@ func NewStationAPI(stationService services.StationManagement) *StationAPI {
 
 func (h *StationAPI) CreateStation(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name string   `json:"name"`
-		Tags []string `json:"tags"`
+		Name    string   `json:"name"`
+		Tags    []string `json:"tags"`
+		Premium bool     `json:"premium"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("Failed to decode request body:", err)
@@ -28,7 +30,7 @@ func (h *StationAPI) CreateStation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	station, err := h.stationService.CreateStation(req.Name, req.Tags)
+	station, err := h.stationService.CreateStation(req.Name, req.Tags, req.Premium)
 	if err != nil {
 		logger.Error("Failed to create station:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -45,9 +47,15 @@ func (h *StationAPI) CreateStation(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *StationAPI) GetAllStations(w http.ResponseWriter, _ *http.Request) {
+// GetAllStations lists the stations the user can see. Premium stations are included for
+// every tier so the UI can offer them as an upgrade.
+func (h *StationAPI) GetAllStations(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
 
-	stations, err := h.stationService.GetAllStations()
+	stations, err := h.stationService.GetStationsForUser(user.ID)
 	if err != nil {
 		logger.Error("Failed to get all stations:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -73,24 +81,21 @@ func (h *StationAPI) GetSongsForStationByID(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Extract user ID from the request context or query parameters
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		logger.Error("Missing user ID")
-		http.Error(w, "Missing user ID", http.StatusBadRequest)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		logger.Error("Invalid user ID:", err)
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	songs, err := h.stationService.GetSongsForStationWithFeedback(stationID, userID)
+	songs, err := h.stationService.GetSongsForStationWithFeedback(stationID, user)
 	if err != nil {
 		logger.Error("Failed to get songs for station:", err)
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -126,8 +131,9 @@ func (h *StationAPI) DeleteStation(w http.ResponseWriter, r *http.Request) {
 
 func (h *StationAPI) UpdateStation(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name string   `json:"name"`
-		Tags []string `json:"tags"`
+		Name    string   `json:"name"`
+		Tags    []string `json:"tags"`
+		Premium *bool    `json:"premium"`
 	}
 	stationID, err := strconv.Atoi(r.URL.Path[len("/admin/stations/"):])
 	if err != nil {
@@ -142,9 +148,13 @@ func (h *StationAPI) UpdateStation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	station, err := h.stationService.UpdateStation(stationID, req.Name, req.Tags)
+	station, err := h.stationService.UpdateStation(stationID, req.Name, req.Tags, req.Premium)
 	if err != nil {
 		logger.Error("Failed to update station:", err)
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -158,3 +168,69 @@ func (h *StationAPI) UpdateStation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *StationAPI) CreatePersonalStation(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request body:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	station, err := h.stationService.CreatePersonalStation(user, req.Name, req.Tags)
+	if err != nil {
+		logger.Error("Failed to create personal station:", err)
+		if writeUpgradeRequired(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidStation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Created personal station:", station)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(station)
+}
+
+func (h *StationAPI) DeletePersonalStation(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	stationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.Error("Invalid station ID:", err)
+		http.Error(w, "Invalid station ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.stationService.DeletePersonalStation(user.ID, stationID); err != nil {
+		logger.Error("Failed to delete personal station:", err)
+		if errors.Is(err, services.ErrStationNotOwned) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, services.ErrStationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Deleted personal station:", stationID)
+	w.WriteHeader(http.StatusNoContent)
+}
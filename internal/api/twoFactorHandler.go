@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"louderspace/internal/logger"
+	"louderspace/internal/middleware"
+	"louderspace/internal/models"
+	"louderspace/internal/services"
+	"net/http"
+)
+
+type TwoFactorAPI struct {
+	twoFactor services.TwoFactorManagement
+}
+
+func NewTwoFactorAPI(twoFactor services.TwoFactorManagement) *TwoFactorAPI {
+	return &TwoFactorAPI{twoFactor}
+}
+
+func (h *TwoFactorAPI) Status(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactor.Status(user)
+	if err != nil {
+		logger.Error("Failed to get two-factor status", err)
+		http.Error(w, "Failed to get two-factor status", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// Enroll returns a new secret and provisioning URI. 2FA stays off until Confirm.
+func (h *TwoFactorAPI) Enroll(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(user)
+	if err != nil {
+		logger.Error("Failed to enroll two-factor", err)
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to enroll two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Two-factor enrollment started for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *TwoFactorAPI) Confirm(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := h.twoFactor.Confirm(user.ID, code)
+	if err != nil {
+		logger.Error("Failed to confirm two-factor", err)
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	logger.Info("Two-factor enabled for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": recoveryCodes})
+}
+
+func (h *TwoFactorAPI) Disable(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactor.Disable(user, code); err != nil {
+		logger.Error("Failed to disable two-factor", err)
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	logger.Info("Two-factor disabled for user", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TwoFactorAPI) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := h.twoFactor.RegenerateRecoveryCodes(user.ID, code)
+	if err != nil {
+		logger.Error("Failed to regenerate recovery codes", err)
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	logger.Info("Recovery codes regenerated for user", user.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": recoveryCodes})
+}
+
+func (h *TwoFactorAPI) RequiredRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.twoFactor.RequiredRoles()
+	if err != nil {
+		logger.Error("Failed to get two-factor requirements", err)
+		http.Error(w, "Failed to get two-factor requirements", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]models.Role{"roles": roles})
+}
+
+// SetRoleRequired turns the 2FA requirement for the role in the path on or off.
+func (h *TwoFactorAPI) SetRoleRequired(w http.ResponseWriter, r *http.Request) {
+	admin, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		logger.Error("No user in context")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Required bool `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role := models.Role(mux.Vars(r)["role"])
+	if err := h.twoFactor.SetRoleRequired(admin, role, req.Required); err != nil {
+		logger.Error("Failed to change two-factor requirement", err)
+		if errors.Is(err, services.ErrInvalidRole) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to change two-factor requirement", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Two-factor requirement changed for role", role, req.Required)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if req.Code == "" {
+		http.Error(w, "Missing code", http.StatusBadRequest)
+		return "", false
+	}
+	return req.Code, true
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorNotPending), errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Two-factor request failed", http.StatusInternalServerError)
+	}
+}
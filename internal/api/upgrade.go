@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// writeUpgradeRequired answers 402 with the tier limit that was hit when err is an
+// UpgradeRequiredError, and reports whether it did.
+func writeUpgradeRequired(w http.ResponseWriter, err error) bool {
+	var upgrade *services.UpgradeRequiredError
+	if !errors.As(err, &upgrade) {
+		return false
+	}
+
+	logger.Info("Upgrade required:", upgrade)
+	if upgrade.RetryAfter != nil {
+		seconds := int(time.Until(*upgrade.RetryAfter).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPaymentRequired)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+		*services.UpgradeRequiredError
+	}{"upgrade_required", upgrade})
+	return true
+}
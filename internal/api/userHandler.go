@@ -2,28 +2,266 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
 	"louderspace/internal/logger"
+	"louderspace/internal/models"
 	"louderspace/internal/services"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 type UserAPI struct {
-	userService services.UserManagement
+	roleService   services.RoleManagement
+	loginThrottle services.LoginThrottling
+	userAdmin     services.UserAdministration
 }
 
-func NewUserAPI(userService services.UserManagement) *UserAPI {
-	return &UserAPI{userService}
+func NewUserAPI(roleService services.RoleManagement, loginThrottle services.LoginThrottling, userAdmin services.UserAdministration) *UserAPI {
+	return &UserAPI{roleService, loginThrottle, userAdmin}
 }
 
+// Users lists the user directory. Query parameters: username and email (substring matches),
+// role, suspended (true/false), created_after and created_before (RFC 3339 or YYYY-MM-DD),
+// sort (id, username, email, role, created_at), order (asc/desc), limit and offset.
 func (h *UserAPI) Users(w http.ResponseWriter, r *http.Request) {
-	users, err := h.userService.Users()
+	filter, err := parseUserFilter(r.URL.Query())
 	if err != nil {
-		logger.Error("Failed to get all users:", err)
+		logger.Error("Invalid user filter:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.userAdmin.Search(filter)
+	if err != nil {
+		logger.Error("Failed to search users:", err)
+		if errors.Is(err, services.ErrInvalidUserFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Got", len(page.Users), "of", page.Total, "users")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseUserFilter(query url.Values) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		Username: query.Get("username"),
+		Email:    query.Get("email"),
+		Role:     models.Role(query.Get("role")),
+		Sort:     query.Get("sort"),
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, fmt.Errorf("%w: order must be asc or desc", services.ErrInvalidUserFilter)
+	}
+
+	if value := query.Get("suspended"); value != "" {
+		suspended, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("%w: suspended must be true or false", services.ErrInvalidUserFilter)
+		}
+		filter.Suspended = &suspended
+	}
+
+	if err := parseCreatedRange(query, services.ErrInvalidUserFilter, &filter.CreatedAfter, &filter.CreatedBefore); err != nil {
+		return filter, err
+	}
+
+	for _, param := range []struct {
+		name string
+		dest *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %s must be a number", services.ErrInvalidUserFilter, param.name)
+		}
+		*param.dest = n
+	}
+
+	return filter, nil
+}
+
+func (h *UserAPI) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		logger.Error("Invalid user ID:", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Role models.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request body:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.roleService.ChangeRole(admin, userID, req.Role)
+	if err != nil {
+		logger.Error("Failed to change role:", err)
+		if errors.Is(err, services.ErrInvalidRole) || errors.Is(err, services.ErrCannotChangeOwnRole) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	logger.Info("Got all users:", users)
+	logger.Info("Changed role of user", user.ID, "to", user.Role, "by admin", admin.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// Unlock lifts a login lockout of the user's username.
+func (h *UserAPI) Unlock(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		logger.Error("Invalid user ID:", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.loginThrottle.Unlock(admin, userID); err != nil {
+		logger.Error("Failed to unlock user:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Unlocked login of user", userID, "by admin", admin.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Suspend blocks a user from the API until they are reactivated. The optional reason is
+// kept in the audit trail.
+func (h *UserAPI) Suspend(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.Error("Invalid user ID:", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Failed to decode request body:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userAdmin.Suspend(admin, userID, req.Reason)
+	if err != nil {
+		logger.Error("Failed to suspend user:", err)
+		if errors.Is(err, services.ErrCannotSuspendSelf) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Suspended user", user.ID, "by admin", admin.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAPI) Reactivate(w http.ResponseWriter, r *http.Request) {
+	admin, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.Error("Invalid user ID:", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userAdmin.Reactivate(admin, userID)
+	if err != nil {
+		logger.Error("Failed to reactivate user:", err)
+		if errors.Is(err, services.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Reactivated user", user.ID, "by admin", admin.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// Audit returns the admin actions taken on a user, newest first.
+func (h *UserAPI) Audit(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.Error("Invalid user ID:", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.userAdmin.AuditTrail(userID)
+	if err != nil {
+		logger.Error("Failed to get audit trail:", err)
+		if errors.Is(err, services.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	logger.Info("Got audit trail of user", userID)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(entries)
 }
@@ -3,32 +3,45 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"louderspace/internal/mailer"
+	"louderspace/internal/middleware"
 	"louderspace/internal/models"
+	"louderspace/internal/passwords"
 	"louderspace/internal/repositories"
 	"louderspace/internal/services"
+	"louderspace/internal/utils"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestRegister(t *testing.T) {
 	userStorage := repositories.NewMockUserStorage()
-	userService := services.NewUserService(userStorage)
-	userAPI := NewUserAPI(userService)
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, true, "lax", ""))
 
 	payload := map[string]string{
 		"username": "testuser",
-		"password": "password123",
+		"password": "Tr0ub4dor-and-3",
 		"email":    "test@example.com",
+		"role":     "admin",
 	}
 	body, _ := json.Marshal(payload)
 
 	req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(body))
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(userAPI.Register)
+	handler := http.HandlerFunc(authAPI.Register)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusCreated, rr.Code)
@@ -38,12 +51,41 @@ func TestRegister(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "testuser", user.Username)
 	assert.Equal(t, "test@example.com", user.Email)
+	assert.Equal(t, models.RoleFree, user.Role)
+	assert.False(t, user.EmailVerified)
+
+	messages, err := mail.Messages()
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// The password is hashed once, so the new account can log in.
+	body, _ = json.Marshal(map[string]string{"username": "testuser", "password": "Tr0ub4dor-and-3"})
+	req, _ = http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(authAPI.Login).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, _ = json.Marshal(map[string]string{"username": "another", "password": "Password123", "email": "another@example.com"})
+	req, _ = http.NewRequest("POST", "/register", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var rejected struct {
+		Violations []passwords.Violation `json:"violations"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rejected))
+	assert.Equal(t, []passwords.Violation{{Rule: passwords.RuleBreached, Message: "is too common or has appeared in a data breach"}}, rejected.Violations)
 }
 
 func TestLogin(t *testing.T) {
 	userStorage := repositories.NewMockUserStorage()
-	userService := services.NewUserService(userStorage)
-	userAPI := NewUserAPI(userService)
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, true, "lax", ""))
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	userStorage.Save(&models.User{
@@ -61,14 +103,215 @@ func TestLogin(t *testing.T) {
 
 	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(userAPI.Login)
+	handler := http.HandlerFunc(authAPI.Login)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
-	var user models.User
-	err := json.Unmarshal(rr.Body.Bytes(), &user)
+	var resp struct {
+		Token        string      `json:"token"`
+		RefreshToken string      `json:"refresh_token"`
+		User         models.User `json:"user"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
 	assert.NoError(t, err)
-	assert.Equal(t, "testuser", user.Username)
-	assert.Equal(t, "test@example.com", user.Email)
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Equal(t, "testuser", resp.User.Username)
+	assert.Equal(t, "test@example.com", resp.User.Email)
+}
+
+func TestLoginLockout(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, true, "lax", ""))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	userStorage.Save(&models.User{
+		Username:  "testuser",
+		Password:  string(hashedPassword),
+		Email:     "test@example.com",
+		CreatedAt: time.Now(),
+	})
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"username": "testuser", "password": password})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(authAPI.Login).ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+	}
+
+	rr := login("password123")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
+
+func TestLoginReportsServerErrors(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage()), services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, true, "lax", ""))
+
+	// A hash in a format no hasher reads is a server problem, not a wrong password.
+	userStorage.Save(&models.User{Username: "testuser", Password: "md5:5f4dcc3b5aa765d61d8327deb882cf99", Email: "test@example.com", CreatedAt: time.Now()})
+
+	login := func(username string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"username": username, "password": "password123"})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(authAPI.Login).ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, http.StatusInternalServerError, login("testuser").Code, "server errors do not lock the account")
+	}
+	assert.Equal(t, http.StatusUnauthorized, login(strings.Repeat("a", 1000)).Code)
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := services.NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	mail, _ := mailer.NewFileMailer(t.TempDir(), "test@louderspace.local")
+	accountService := services.NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	twoFactor := services.NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+	authAPI := NewAuthAPI(userService, tokenService, accountService, services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, repositories.NewMockAuditStorage()), twoFactor, services.NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen), NewSessionCookies(false, true, "lax", ""))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", CreatedAt: time.Now()}
+	userStorage.Save(user)
+	enrollment, _ := twoFactor.Enroll(user)
+	code, _ := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(time.Now())-1, utils.TOTPDigits)
+	_, err := twoFactor.Confirm(user.ID, code)
+	assert.NoError(t, err)
+
+	post := func(handler http.HandlerFunc, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/", bytes.NewBuffer(body))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post(authAPI.Login, map[string]string{"username": "testuser", "password": "password123"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var challenge struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		Challenge         string `json:"challenge"`
+		Token             string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &challenge))
+	assert.True(t, challenge.TwoFactorRequired)
+	assert.NotEmpty(t, challenge.Challenge)
+	assert.Empty(t, challenge.Token)
+
+	rr = post(authAPI.LoginTwoFactor, map[string]string{"challenge": challenge.Challenge, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	code, _ = utils.TOTPCode(enrollment.Secret, utils.TOTPStep(time.Now()), utils.TOTPDigits)
+	rr = post(authAPI.LoginTwoFactor, map[string]string{"challenge": challenge.Challenge, "code": code})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Token)
+	assert.Equal(t, "testuser", resp.User.Username)
+
+	// A user suspended after the password step cannot finish the login.
+	rr = post(authAPI.Login, map[string]string{"username": "testuser", "password": "password123"})
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &challenge))
+	now := time.Now()
+	assert.NoError(t, userStorage.SetSuspended(user.ID, &now, &models.AuditEntry{ActorID: user.ID, TargetUserID: user.ID, Action: models.AuditActionSuspend, CreatedAt: now}))
+	code, _ = utils.TOTPCode(enrollment.Secret, utils.TOTPStep(time.Now())+1, utils.TOTPDigits)
+	rr = post(authAPI.LoginTwoFactor, map[string]string{"challenge": challenge.Challenge, "code": code})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestUserDirectoryAndSuspension(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	key, _ := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	keys, _ := utils.NewKeySet("test", key)
+	tokenService := services.NewTokenService(repositories.NewMockTokenStorage(), userStorage, keys, 15*time.Minute, time.Hour)
+	auditStorage := userStorage.AuditLog
+	userAdmin := services.NewUserAdminService(userStorage, auditStorage, tokenService)
+	userAPI := NewUserAPI(services.NewRoleService(userStorage, tokenService), services.NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, auditStorage), userAdmin)
+	apiKeyService := services.NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage)
+
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	userStorage.Save(admin)
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	userStorage.Save(user)
+	apiKey, _, err := apiKeyService.CreateKey(user, "script", []string{models.ScopeSongsRead}, nil)
+	assert.NoError(t, err)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/admin/users", userAPI.Users).Methods("GET")
+	r.HandleFunc("/admin/users/{id:[0-9]+}/suspend", userAPI.Suspend).Methods("POST")
+	r.HandleFunc("/admin/users/{id:[0-9]+}/reactivate", userAPI.Reactivate).Methods("POST")
+	r.HandleFunc("/admin/users/{id:[0-9]+}/audit", userAPI.Audit).Methods("GET")
+	asAdmin := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, http.NoBody)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, withUser(req, admin.ID, models.RoleAdmin))
+		return rr
+	}
+
+	protected := middleware.WithUser(tokenService, apiKeyService, userAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	withAPIKey := func() int {
+		req, _ := http.NewRequest("GET", "/songs", nil)
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		rr := httptest.NewRecorder()
+		protected.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	rr := asAdmin("GET", "/admin/users?role=free&sort=username&order=desc&limit=10")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var page models.UserPage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, "testuser", page.Users[0].Username)
+	assert.Equal(t, 10, page.Limit)
+
+	assert.Equal(t, http.StatusBadRequest, asAdmin("GET", "/admin/users?created_after=yesterday").Code)
+	assert.Equal(t, http.StatusBadRequest, asAdmin("GET", "/admin/users?sort=password").Code)
+
+	assert.Equal(t, http.StatusOK, withAPIKey())
+	assert.Equal(t, http.StatusOK, asAdmin("POST", "/admin/users/"+strconv.Itoa(user.ID)+"/suspend").Code)
+	assert.Equal(t, http.StatusForbidden, withAPIKey())
+
+	assert.Equal(t, http.StatusOK, asAdmin("POST", "/admin/users/"+strconv.Itoa(user.ID)+"/reactivate").Code)
+	assert.Equal(t, http.StatusOK, withAPIKey())
+
+	rr = asAdmin("GET", "/admin/users/"+strconv.Itoa(user.ID)+"/audit")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var trail []models.AuditEntry
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &trail))
+	assert.Len(t, trail, 2)
+	assert.Equal(t, models.AuditActionReactivate, trail[0].Action)
+
+	assert.Equal(t, http.StatusNotFound, asAdmin("POST", "/admin/users/999/suspend").Code)
+	assert.Equal(t, http.StatusNotFound, asAdmin("GET", "/admin/users/999/audit").Code)
 }
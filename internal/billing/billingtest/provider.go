@@ -0,0 +1,106 @@
+// Package billingtest provides a fake payment provider that sends signed webhooks in tests.
+package billingtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"louderspace/internal/billing"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Provider builds subscription events the way a Stripe-compatible provider sends them and
+// signs them with Secret.
+type Provider struct {
+	Secret string
+
+	mu     sync.Mutex
+	nextID int
+}
+
+func NewProvider(secret string) *Provider {
+	return &Provider{Secret: secret, nextID: 1}
+}
+
+// Request returns a webhook delivery of payload to target, signed now.
+func (p *Provider) Request(target string, payload []byte) *http.Request {
+	return p.RequestAt(target, payload, time.Now())
+}
+
+// RequestAt returns a webhook delivery signed at the given time.
+func (p *Provider) RequestAt(target string, payload []byte, at time.Time) *http.Request {
+	req, _ := http.NewRequest("POST", target, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(billing.SignatureHeader, billing.Sign(payload, p.Secret, at))
+	return req
+}
+
+// SubscriptionCreated is sent when userID starts a subscription paid up to periodEnd.
+func (p *Provider) SubscriptionCreated(subscriptionID, customerID string, userID int, periodEnd time.Time) []byte {
+	return p.event(billing.EventSubscriptionCreated, map[string]interface{}{
+		"object":             "subscription",
+		"id":                 subscriptionID,
+		"customer":           customerID,
+		"status":             "active",
+		"current_period_end": periodEnd.Unix(),
+		"metadata":           map[string]string{billing.UserIDMetadataKey: strconv.Itoa(userID)},
+	})
+}
+
+// InvoicePaid is sent when a renewal is paid, extending the subscription to periodEnd.
+func (p *Provider) InvoicePaid(subscriptionID, customerID string, periodEnd time.Time) []byte {
+	return p.invoice(billing.EventInvoicePaid, subscriptionID, customerID, periodEnd)
+}
+
+// PaymentFailed is sent for every failed attempt to collect a renewal.
+func (p *Provider) PaymentFailed(subscriptionID, customerID string, periodEnd time.Time) []byte {
+	return p.invoice(billing.EventInvoicePaymentFailed, subscriptionID, customerID, periodEnd)
+}
+
+// SubscriptionDeleted is sent when a subscription ends, either at once or at periodEnd.
+func (p *Provider) SubscriptionDeleted(subscriptionID, customerID string, periodEnd time.Time) []byte {
+	return p.event(billing.EventSubscriptionDeleted, map[string]interface{}{
+		"object":             "subscription",
+		"id":                 subscriptionID,
+		"customer":           customerID,
+		"status":             "canceled",
+		"current_period_end": periodEnd.Unix(),
+		"metadata":           map[string]string{},
+	})
+}
+
+func (p *Provider) invoice(eventType, subscriptionID, customerID string, periodEnd time.Time) []byte {
+	p.mu.Lock()
+	invoiceID := fmt.Sprintf("in_%d", p.nextID)
+	p.mu.Unlock()
+	return p.event(eventType, map[string]interface{}{
+		"object":       "invoice",
+		"id":           invoiceID,
+		"customer":     customerID,
+		"subscription": subscriptionID,
+		"lines": map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"period": map[string]int64{"start": periodEnd.AddDate(0, -1, 0).Unix(), "end": periodEnd.Unix()}},
+			},
+		},
+	})
+}
+
+func (p *Provider) event(eventType string, object map[string]interface{}) []byte {
+	p.mu.Lock()
+	id := fmt.Sprintf("evt_%d", p.nextID)
+	p.nextID++
+	p.mu.Unlock()
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"id":      id,
+		"object":  "event",
+		"type":    eventType,
+		"created": time.Now().Unix(),
+		"data":    map[string]interface{}{"object": object},
+	})
+	return payload
+}
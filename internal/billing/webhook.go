@@ -0,0 +1,166 @@
+// Package billing reads subscription webhooks from a Stripe-compatible payment provider.
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook signature, "t=<unix time>,v1=<hex HMAC-SHA256>". The
+// MAC covers "<unix time>.<payload>" and is keyed with the endpoint's signing secret.
+const SignatureHeader = "Stripe-Signature"
+
+// DefaultTolerance is how old a signed payload may be, which limits replays.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidPayload   = errors.New("invalid webhook payload")
+)
+
+// Event types the app acts on.
+const (
+	EventSubscriptionCreated  = "customer.subscription.created"
+	EventSubscriptionDeleted  = "customer.subscription.deleted"
+	EventInvoicePaid          = "invoice.paid"
+	EventInvoicePaymentFailed = "invoice.payment_failed"
+)
+
+// UserIDMetadataKey is the subscription metadata entry naming the app user who subscribed.
+// Checkout sessions have to set it.
+const UserIDMetadataKey = "user_id"
+
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+func (e *Event) CreatedAt() time.Time {
+	return time.Unix(e.Created, 0)
+}
+
+// Subscription is the object of customer.subscription.* events.
+type Subscription struct {
+	ID               string            `json:"id"`
+	Customer         string            `json:"customer"`
+	Status           string            `json:"status"`
+	CurrentPeriodEnd int64             `json:"current_period_end"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// UserID returns the app user the subscription belongs to, or 0 if it does not say.
+func (s *Subscription) UserID() int {
+	id, _ := strconv.Atoi(s.Metadata[UserIDMetadataKey])
+	return id
+}
+
+// Invoice is the object of invoice.* events.
+type Invoice struct {
+	ID           string `json:"id"`
+	Customer     string `json:"customer"`
+	Subscription string `json:"subscription"`
+	Lines        struct {
+		Data []struct {
+			Period struct {
+				Start int64 `json:"start"`
+				End   int64 `json:"end"`
+			} `json:"period"`
+		} `json:"data"`
+	} `json:"lines"`
+}
+
+// PeriodEnd returns the end of the latest period the invoice pays for.
+func (i *Invoice) PeriodEnd() time.Time {
+	var end int64
+	for _, line := range i.Lines.Data {
+		if line.Period.End > end {
+			end = line.Period.End
+		}
+	}
+	return time.Unix(end, 0)
+}
+
+func (e *Event) Subscription() (*Subscription, error) {
+	subscription := &Subscription{}
+	if err := json.Unmarshal(e.Data.Object, subscription); err != nil || subscription.ID == "" {
+		return nil, fmt.Errorf("%w: event %s has no subscription", ErrInvalidPayload, e.ID)
+	}
+	return subscription, nil
+}
+
+func (e *Event) Invoice() (*Invoice, error) {
+	invoice := &Invoice{}
+	if err := json.Unmarshal(e.Data.Object, invoice); err != nil || invoice.Subscription == "" {
+		return nil, fmt.Errorf("%w: event %s has no subscription invoice", ErrInvalidPayload, e.ID)
+	}
+	return invoice, nil
+}
+
+// Sign returns the signature header for payload sent at the given time.
+func Sign(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + mac(payload, secret, timestamp)
+}
+
+func mac(payload []byte, secret, timestamp string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParseEvent verifies the signature header of a webhook payload and decodes the event. Any
+// of several v1 signatures may match, as providers send one per active secret while a
+// secret is being rolled.
+func ParseEvent(payload []byte, header, secret string, tolerance time.Duration, now time.Time) (*Event, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return nil, fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+
+	expected := mac(payload, secret, timestamp)
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	event := &Event{}
+	if err := json.Unmarshal(payload, event); err != nil || event.ID == "" || event.Type == "" {
+		return nil, ErrInvalidPayload
+	}
+	return event, nil
+}
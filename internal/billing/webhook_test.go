@@ -0,0 +1,40 @@
+package billing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseEvent(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"invoice.paid","created":1700000000,"data":{"object":{"id":"in_1","subscription":"sub_1","lines":{"data":[{"period":{"start":1700000000,"end":1702592000}}]}}}}`)
+	now := time.Now()
+
+	event, err := ParseEvent(payload, Sign(payload, "whsec_test", now), "whsec_test", DefaultTolerance, now)
+	assert.NoError(t, err)
+	assert.Equal(t, EventInvoicePaid, event.Type)
+	invoice, err := event.Invoice()
+	assert.NoError(t, err)
+	assert.Equal(t, "sub_1", invoice.Subscription)
+	assert.Equal(t, int64(1702592000), invoice.PeriodEnd().Unix())
+	_, err = event.Subscription()
+	assert.NoError(t, err)
+
+	// While a secret is rolled the header carries a signature per secret.
+	header := Sign(payload, "whsec_old", now) + ",v1=" + mac(payload, "whsec_test", "x")
+	_, err = ParseEvent(payload, header, "whsec_old", DefaultTolerance, now)
+	assert.NoError(t, err)
+
+	_, err = ParseEvent(payload, Sign(payload, "whsec_other", now), "whsec_test", DefaultTolerance, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = ParseEvent(payload, Sign(payload, "whsec_test", now.Add(-time.Hour)), "whsec_test", DefaultTolerance, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = ParseEvent(append(payload, ' '), Sign(payload, "whsec_test", now), "whsec_test", DefaultTolerance, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = ParseEvent(payload, "garbage", "whsec_test", DefaultTolerance, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	empty := []byte(`{}`)
+	_, err = ParseEvent(empty, Sign(empty, "whsec_test", now), "whsec_test", DefaultTolerance, now)
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
@@ -22,13 +22,13 @@ func Init() {
 }
 
 func Info(args ...interface{}) {
-	log.Info(args...)
+	log.Info(redact(args))
 }
 
 func Error(args ...interface{}) {
-	log.Error(args...)
+	log.Error(redact(args))
 }
 
 func Debug(args ...interface{}) {
-	log.Debug(args...)
+	log.Debug(redact(args))
 }
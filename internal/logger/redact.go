@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// key=value and "key": "value" pairs whose key names a credential.
+	secretPair = regexp.MustCompile(`(?i)("?\b(?:\w*password|passwd|\w*secret|\w*token|api_key|challenge)"?\s*[:=]\s*"?)([^\s"&,;}]+)`)
+	// The one-time and SSO authorization codes travel as a "code" JSON field or a code query
+	// parameter. Only those exact fields match, so status and error codes stay readable.
+	codeField = regexp.MustCompile(`("code"\s*:\s*"?|[?&]code=)([^\s"&,;}]+)`)
+	bearer    = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/-]+=*`)
+	jwtLike   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	apiKey    = regexp.MustCompile(`lsk_[A-Za-z0-9_-]+`)
+)
+
+// Redact masks passwords, tokens and other credentials in a log message. It is applied to
+// every message, so callers do not have to remember to scrub what they log.
+func Redact(message string) string {
+	message = jwtLike.ReplaceAllString(message, redacted)
+	message = apiKey.ReplaceAllString(message, redacted)
+	message = bearer.ReplaceAllString(message, "${1}"+redacted)
+	message = codeField.ReplaceAllString(message, "${1}"+redacted)
+	return secretPair.ReplaceAllString(message, "${1}"+redacted)
+}
+
+func redact(args []interface{}) string {
+	return Redact(fmt.Sprint(args...))
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	cases := map[string]string{
+		`{"username":"bob","password":"hunter2"}`:            `{"username":"bob","password":"[REDACTED]"}`,
+		"GET /reset?token=abc123&x=1":                        "GET /reset?token=[REDACTED]&x=1",
+		"Authorization: Bearer abc.def-ghi":                  "Authorization: Bearer [REDACTED]",
+		"got eyJhbGciOi.eyJzdWIiOjF9.c2lnbmF0dXJl in header": "got [REDACTED] in header",
+		"refresh_token=xyz new_password: secret1":            "refresh_token=[REDACTED] new_password: [REDACTED]",
+		"key lsk_abc-DEF_123 used":                           "key [REDACTED] used",
+		`{"challenge":"abc","code":"123456"}`:                `{"challenge":"[REDACTED]","code":"[REDACTED]"}`,
+		"GET /auth/sso/callback?code=abc123&state=xyz":       "GET /auth/sso/callback?code=[REDACTED]&state=xyz",
+		"unexpected status code: 502 error_code=42":          "unexpected status code: 502 error_code=42",
+		"Completed request: GET /songs 200 1ms":              "Completed request: GET /songs 200 1ms",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, Redact(input))
+	}
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+// FileMailer writes each message to its own .eml file instead of sending it, for local
+// development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir, from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0o600)
+}
+
+// Messages returns the paths of the written messages, oldest first.
+func (m *FileMailer) Messages() ([]string, error) {
+	return filepath.Glob(filepath.Join(m.dir, "*.eml"))
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+// format renders msg as a plain-text RFC 5322 message. Line breaks are stripped from header
+// values so user-supplied addresses cannot inject headers.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerSanitizer.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerSanitizer.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerSanitizer.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) Mailer {
+	return &SMTPMailer{host, port, username, password, from}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.host, m.port), auth, m.from, []string{msg.To}, format(m.from, msg))
+}
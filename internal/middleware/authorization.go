@@ -14,46 +14,120 @@ type key int
 
 const (
 	UserContextKey key = iota
+	ClaimsContextKey
+	ImpersonatorContextKey
+	APIKeyContextKey
 )
 
-func WithUser(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			logger.Error("No Authorization header")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+// TokenValidator parses an access token and rejects it if it, or the session it was issued
+// for, has been revoked.
+type TokenValidator interface {
+	ValidateAccessToken(tokenString string) (*utils.Claims, error)
+	// TouchSession reports whether the session of the token is still live.
+	TouchSession(claims *utils.Claims, ip string) (bool, error)
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := utils.ParseToken(tokenString)
-		if err != nil {
-			logger.Error("Failed to parse token", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+// APIKeyAuthenticator resolves a personal API key to its owner.
+type APIKeyAuthenticator interface {
+	Authenticate(key string) (*models.User, *models.APIKey, error)
+}
 
-		user := &models.User{
-			ID:   claims.UserID,
-			Role: claims.Role,
-		}
-		logger.Info("User extracted from token", user)
-		ctx := context.WithValue(r.Context(), UserContextKey, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// SuspensionChecker reports whether an admin has suspended a user.
+type SuspensionChecker interface {
+	IsSuspended(userID int) (bool, error)
 }
 
-func RequireRole(role models.Role) func(http.Handler) http.Handler {
+// WithUser authenticates the request from the bearer token, which is either a JWT access
+// token or an API key, and rejects suspended users. For API keys the key is stored under
+// APIKeyContextKey so RequireAPIKeyScope can check its scopes.
+func WithUser(validator TokenValidator, apiKeys APIKeyAuthenticator, suspensions SuspensionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user, ok := r.Context().Value(UserContextKey).(*models.User)
-			logger.Info("Checking user role", user, role)
-			if !ok || user.Role != role {
-				logger.Error("Forbidden", user, role)
-				http.Error(w, "Forbidden", http.StatusForbidden)
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				logger.Error("No Authorization header")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if strings.HasPrefix(tokenString, models.APIKeyPrefix) {
+				user, apiKey, err := apiKeys.Authenticate(tokenString)
+				if err != nil {
+					logger.Error("Failed to authenticate api key", err)
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				if !active(w, suspensions, user.ID) {
+					return
+				}
+				logger.Info("User extracted from api key", user, apiKey.ID)
+				ctx := context.WithValue(r.Context(), UserContextKey, user)
+				ctx = context.WithValue(ctx, APIKeyContextKey, apiKey)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			claims, err := validator.ValidateAccessToken(tokenString)
+			if err != nil {
+				logger.Error("Failed to validate token", err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			live, err := validator.TouchSession(claims, ClientIP(r))
+			if err != nil {
+				logger.Error("Failed to check session", claims.SessionID, err)
+				http.Error(w, "Failed to check session", http.StatusInternalServerError)
 				return
 			}
-			next.ServeHTTP(w, r)
+			if !live {
+				logger.Error("Session is no longer valid", claims.SessionID)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !active(w, suspensions, claims.UserID) {
+				return
+			}
+
+			user := &models.User{
+				ID:   claims.UserID,
+				Role: claims.Role,
+			}
+			logger.Info("User extracted from token", user)
+			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// active answers 403 for suspended users. Suspension revokes their tokens, but their API keys
+// would otherwise keep working.
+func active(w http.ResponseWriter, suspensions SuspensionChecker, userID int) bool {
+	suspended, err := suspensions.IsSuspended(userID)
+	if err != nil {
+		logger.Error("Failed to check suspension of user", userID, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if suspended {
+		logger.Error("Rejected request of suspended user", userID)
+		http.Error(w, "Account suspended", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// RequirePermission wraps a route handler so it only runs for users whose role grants
+// permission. Routes declare their permissions with it in cmd/server/main.go.
+func RequirePermission(permission models.Permission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(UserContextKey).(*models.User)
+		if !ok || !user.Role.Has(permission) {
+			logger.Error("Forbidden", user, permission)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
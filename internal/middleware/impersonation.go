@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"net/http"
+	"strconv"
+)
+
+const ImpersonateHeader = "X-Impersonate-User"
+
+// Impersonator resolves the user an admin acts as and audits the request.
+type Impersonator interface {
+	Impersonate(admin *models.User, targetUserID int, details string) (*models.User, error)
+}
+
+// Impersonate lets an admin act as the user whose ID is sent in the X-Impersonate-User
+// header. The target replaces the user in the context and the admin is kept under
+// ImpersonatorContextKey. It must run after WithUser.
+func Impersonate(impersonator Impersonator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			targetHeader := r.Header.Get(ImpersonateHeader)
+			if targetHeader == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, viaAPIKey := r.Context().Value(APIKeyContextKey).(*models.APIKey); viaAPIKey {
+				logger.Error("Impersonation attempted with an api key")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			admin, ok := r.Context().Value(UserContextKey).(*models.User)
+			if !ok || !admin.Role.Has(models.PermissionUsersImpersonate) {
+				logger.Error("Impersonation attempted by non-admin", admin)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			targetID, err := strconv.Atoi(targetHeader)
+			if err != nil {
+				logger.Error("Invalid impersonation target:", err)
+				http.Error(w, "Invalid impersonation target", http.StatusBadRequest)
+				return
+			}
+
+			target, err := impersonator.Impersonate(admin, targetID, r.Method+" "+r.URL.Path)
+			if err != nil {
+				logger.Error("Failed to impersonate user", targetID, err)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			logger.Info("Admin", admin.ID, "impersonating user", target.ID)
+			ctx := context.WithValue(r.Context(), ImpersonatorContextKey, admin)
+			ctx = context.WithValue(ctx, UserContextKey, target)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
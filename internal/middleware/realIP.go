@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP replaces r.RemoteAddr with the client address from X-Forwarded-For when the server
+// runs behind a trusted proxy. The proxy appends the address it saw, so the last entry is the
+// only one a client cannot forge.
+func RealIP(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if trustProxy {
+				if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+					entries := strings.Split(forwarded, ",")
+					r.RemoteAddr = strings.TrimSpace(entries[len(entries)-1])
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the host part of r.RemoteAddr.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
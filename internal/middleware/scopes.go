@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type scopedHandler struct {
+	scope string
+	http.Handler
+}
+
+// Scoped marks a route handler as reachable with an API key that holds scope. Requests
+// authenticated with a JWT are not affected.
+func Scoped(scope string, handler http.HandlerFunc) http.Handler {
+	return &scopedHandler{scope, handler}
+}
+
+// RequireAPIKeyScope rejects API key requests to routes that are not registered with Scoped
+// or whose scope the key lacks. It must run after WithUser on a router, so the matched route
+// is known.
+func RequireAPIKeyScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey, ok := r.Context().Value(APIKeyContextKey).(*models.APIKey)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		scoped, ok := route.GetHandler().(*scopedHandler)
+		if !ok {
+			logger.Error("API key used on an endpoint without a scope", apiKey.ID, r.Method, r.URL.Path)
+			http.Error(w, "API keys cannot be used for this endpoint", http.StatusForbidden)
+			return
+		}
+		if !apiKey.HasScope(scoped.scope) {
+			logger.Error("API key is missing scope", apiKey.ID, scoped.scope)
+			http.Error(w, "API key is missing scope "+scoped.scope, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
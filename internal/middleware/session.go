@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"louderspace/internal/logger"
+	"net/http"
+)
+
+const (
+	AccessTokenCookie  = "token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFCookie         = "csrf_token"
+	CSRFHeader         = "X-CSRF-Token"
+)
+
+// SessionCookie lets browsers authenticate with the HttpOnly access token cookie instead of
+// an Authorization header. Cookies are sent automatically, so requests that change state
+// must also echo the csrf_token cookie in the X-CSRF-Token header (double-submit); a
+// cross-site page can make the browser send the cookie but cannot read it. Requests with an
+// Authorization header are left alone. It must run before WithUser.
+func SessionCookie(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled || r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cookie, err := r.Cookie(AccessTokenCookie)
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !ValidCSRF(r) {
+				logger.Error("Missing or invalid CSRF token", r.Method, r.URL.Path)
+				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+				return
+			}
+
+			r.Header.Set("Authorization", "Bearer "+cookie.Value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidCSRF reports whether the request may proceed under the double-submit check. Safe
+// methods always pass.
+func ValidCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
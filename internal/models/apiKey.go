@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// APIKeyPrefix starts every API key so keys can be told apart from JWTs and spotted by
+// secret scanners.
+const APIKeyPrefix = "lsk_"
+
+const (
+	ScopeSongsRead     = "songs:read"
+	ScopeSongsWrite    = "songs:write"
+	ScopeStationsRead  = "stations:read"
+	ScopeStationsWrite = "stations:write"
+	ScopeTagsRead      = "tags:read"
+	ScopeTagsWrite     = "tags:write"
+)
+
+// APIKeyScopes lists every scope a key can be granted, mapped to the permission the key's
+// owner needs to grant it. An empty permission means any user may grant the scope.
+var APIKeyScopes = map[string]Permission{
+	ScopeSongsRead:     "",
+	ScopeSongsWrite:    PermissionCatalogWrite,
+	ScopeStationsRead:  "",
+	ScopeStationsWrite: PermissionCatalogWrite,
+	ScopeTagsRead:      PermissionCatalogRead,
+	ScopeTagsWrite:     PermissionCatalogWrite,
+}
+
+// APIKey is a long-lived personal access token for scripts. Only a hash of the key is
+// stored; Prefix is kept so users can tell their keys apart.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	AuditActionImpersonate          = "impersonate"
+	AuditActionChangeRole           = "change_role"
+	AuditActionUnlock               = "unlock"
+	AuditActionExport               = "export"
+	AuditActionTwoFactorRequirement = "two_factor_requirement"
+	AuditActionSuspend              = "suspend"
+	AuditActionReactivate           = "reactivate"
+	AuditActionRedeemInvite         = "redeem_invite"
+)
+
+// SystemActorID is the ActorID of changes no user made, such as roles synced from an identity
+// provider. It is stored as a NULL actor_id.
+const SystemActorID = 0
+
+type AuditEntry struct {
+	ID           int       `json:"id"`
+	ActorID      int       `json:"actor_id"`
+	TargetUserID int       `json:"target_user_id"`
+	Action       string    `json:"action"`
+	Details      string    `json:"details"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+type CatalogFormat string
+
+const (
+	CatalogCSV  CatalogFormat = "csv"
+	CatalogJSON CatalogFormat = "json"
+	// CatalogM3U is an extended M3U playlist of the songs' audio URLs.
+	CatalogM3U CatalogFormat = "m3u"
+)
+
+func (f CatalogFormat) Valid() bool {
+	return f == CatalogCSV || f == CatalogJSON || f == CatalogM3U
+}
+
+// CatalogFilter narrows a catalog export. The zero value exports every song.
+type CatalogFilter struct {
+	Tag string
+	// Station keeps the songs the station plays: those whose genre contains one of its tags.
+	Station *Station
+}
@@ -0,0 +1,53 @@
+package models
+
+// Unlimited marks an entitlement limit that does not apply to the tier.
+const Unlimited = -1
+
+// Features that can be limited by tier. They are reported back to the client when a limit
+// is hit so the UI can show the matching upgrade prompt.
+const (
+	FeatureSkips            = "skips"
+	FeaturePremiumStations  = "premium_stations"
+	FeaturePomodoroHistory  = "pomodoro_history"
+	FeaturePersonalStations = "personal_stations"
+)
+
+type Entitlements struct {
+	SkipsPerHour        int  `json:"skips_per_hour"`
+	PremiumStations     bool `json:"premium_stations"`
+	PomodoroHistoryDays int  `json:"pomodoro_history_days"`
+	PersonalStations    int  `json:"personal_stations"`
+}
+
+var freeEntitlements = Entitlements{
+	SkipsPerHour:        6,
+	PremiumStations:     false,
+	PomodoroHistoryDays: 7,
+	PersonalStations:    0,
+}
+
+var roleEntitlements = map[Role]Entitlements{
+	RoleFree: freeEntitlements,
+	RolePremium: {
+		SkipsPerHour:        Unlimited,
+		PremiumStations:     true,
+		PomodoroHistoryDays: Unlimited,
+		PersonalStations:    20,
+	},
+	// Analysts get data access, not the premium perks.
+	RoleAnalyst: freeEntitlements,
+	RoleAdmin: {
+		SkipsPerHour:        Unlimited,
+		PremiumStations:     true,
+		PomodoroHistoryDays: Unlimited,
+		PersonalStations:    Unlimited,
+	},
+}
+
+// Entitlements returns the tier limits for the role. Unknown roles get the free tier.
+func (r Role) Entitlements() Entitlements {
+	if entitlements, ok := roleEntitlements[r]; ok {
+		return entitlements
+	}
+	return freeEntitlements
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Identity links a user to their account at an external identity provider.
+type Identity struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"-"`
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// SSOLoginState is kept between sending a user to the identity provider and the provider
+// redirecting back. Only the hashes of the state and of the browser binding cookie are
+// stored; the nonce and PKCE verifier never leave the server.
+type SSOLoginState struct {
+	ID           int
+	Provider     string
+	StateHash    string
+	BindingHash  string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+	UsedAt       *time.Time
+}
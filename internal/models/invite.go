@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// RegistrationMode decides who may use POST /register.
+type RegistrationMode string
+
+const (
+	RegistrationOpen       RegistrationMode = "open"
+	RegistrationInviteOnly RegistrationMode = "invite_only"
+	RegistrationClosed     RegistrationMode = "closed"
+)
+
+func (m RegistrationMode) Valid() bool {
+	switch m {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return true
+	}
+	return false
+}
+
+// Invite is an admin-issued registration code. Accounts registered with it get Role. Only a
+// hash of the code is stored; Prefix is kept so admins can tell invites apart. MaxUses 0
+// means the invite can be used any number of times.
+type Invite struct {
+	ID        int        `json:"id"`
+	Prefix    string     `json:"prefix"`
+	CodeHash  string     `json:"-"`
+	Role      Role       `json:"role"`
+	Note      string     `json:"note"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedBy int        `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Usable reports whether the invite can still be redeemed at now.
+func (i *Invite) Usable(now time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt tracks recent failed logins for one key, which is either a username or a
+// client IP address.
+type LoginAttempt struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
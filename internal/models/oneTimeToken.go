@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	// TokenPurposeLoginChallenge is handed out by a password login that still needs a second
+	// factor. It is not mailed; the client sends it back together with the code.
+	TokenPurposeLoginChallenge TokenPurpose = "login_challenge"
+)
+
+// OneTimeToken is a single-use, expiring token, usually mailed to a user. Only its hash is
+// stored.
+type OneTimeToken struct {
+	ID        int
+	UserID    int
+	Purpose   TokenPurpose
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
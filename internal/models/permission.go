@@ -0,0 +1,46 @@
+package models
+
+type Permission string
+
+const (
+	PermissionCatalogRead            Permission = "catalog:read"
+	PermissionCatalogWrite           Permission = "catalog:write"
+	PermissionAnalyticsRead          Permission = "analytics:read"
+	PermissionAnalyticsWrite         Permission = "analytics:write"
+	PermissionUsersRead              Permission = "users:read"
+	PermissionUsersManage            Permission = "users:manage"
+	PermissionUsersImpersonate       Permission = "users:impersonate"
+	PermissionStationsCreatePersonal Permission = "stations:create_personal"
+)
+
+// rolePermissions lists what each role may do on top of what every signed-in user can do.
+// Roles are not strictly ordered: an analyst can read the catalog and analytics but has none
+// of the premium perks, while an admin has everything.
+var rolePermissions = map[Role][]Permission{
+	RoleFree:    {},
+	RolePremium: {PermissionStationsCreatePersonal},
+	RoleAnalyst: {PermissionCatalogRead, PermissionAnalyticsRead},
+	RoleAdmin: {
+		PermissionCatalogRead,
+		PermissionCatalogWrite,
+		PermissionAnalyticsRead,
+		PermissionAnalyticsWrite,
+		PermissionUsersRead,
+		PermissionUsersManage,
+		PermissionUsersImpersonate,
+		PermissionStationsCreatePersonal,
+	},
+}
+
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+func (r Role) Has(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package models
+
+// SearchResults are the catalog matches for a search, grouped by type and best match first.
+type SearchResults struct {
+	Query    string     `json:"query"`
+	Songs    []*Song    `json:"songs"`
+	Stations []*Station `json:"stations"`
+	Tags     []*Tag     `json:"tags"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is one login on one device. Every access and refresh token issued for the login
+// carries the session ID, so revoking the session signs that device out.
+type Session struct {
+	ID         int    `json:"id"`
+	UserID     int    `json:"-"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	// AccessTokenID is the jti of the latest access token issued for the session.
+	AccessTokenID string     `json:"-"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	RevokedAt     *time.Time `json:"-"`
+	// Current marks the session the listing request was made with.
+	Current bool `json:"current"`
+}
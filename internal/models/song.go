@@ -11,4 +11,24 @@ type Song struct {
 	IsGenerated bool      `json:"is_generated"`
 	CreatedAt   time.Time `json:"created_at"`
 	Tags        []Tag     `json:"tags"`
+	SongMetadata
+	// PlayCount is only loaded by song listings.
+	PlayCount int `json:"play_count"`
+}
+
+// SongMetadata describes a song's audio. Generated songs get it from Suno when they are
+// ingested; the fields Suno does not report stay empty until an admin sets them.
+type SongMetadata struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	AudioURL        string  `json:"audio_url"`
+	ImageURL        string  `json:"image_url"`
+	ImageLargeURL   string  `json:"image_large_url"`
+	// ModelVersion is the Suno model that generated the song, e.g. "v3.5".
+	ModelVersion string `json:"model_version"`
+	BPM          *int   `json:"bpm"`
+	// Key is the musical key, e.g. "A minor".
+	Key string `json:"key"`
+	// Energy runs from 0 (calm) to 1 (intense).
+	Energy *float64 `json:"energy"`
+	Lyrics string   `json:"lyrics"`
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Sort keys accepted by SongFilter.Sort.
+const (
+	SongSortID        = "id"
+	SongSortTitle     = "title"
+	SongSortCreatedAt = "created_at"
+	SongSortPlayCount = "play_count"
+)
+
+// SongFilter narrows and orders a song listing. Zero values leave a filter out; Genre
+// matches case-insensitively and Artist matches case-insensitive substrings.
+type SongFilter struct {
+	Tag           string
+	Genre         string
+	Artist        string
+	IsGenerated   *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	Descending    bool
+	Limit         int
+	// After is the last song of the previous page. Only its ID and the sort field are read.
+	After *Song
+}
+
+// SongPage is one page of a song listing. NextCursor fetches the following page and is
+// empty on the last one.
+type SongPage struct {
+	Songs      []*Song `json:"songs"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+type SongImportFormat string
+
+const (
+	SongImportCSV   SongImportFormat = "csv"
+	SongImportJSONL SongImportFormat = "jsonl"
+)
+
+// SongImportRow is one song to import. Line is where it starts in the imported file.
+type SongImportRow struct {
+	Line        int      `json:"-"`
+	Title       string   `json:"title"`
+	Artist      string   `json:"artist"`
+	Genre       string   `json:"genre"`
+	SunoID      string   `json:"suno_id"`
+	IsGenerated bool     `json:"is_generated"`
+	Tags        []string `json:"tags"`
+}
+
+type SongImportStatus string
+
+const (
+	// SongImportValid rows passed validation in a dry run and would be created.
+	SongImportValid   SongImportStatus = "valid"
+	SongImportCreated SongImportStatus = "created"
+	// SongImportInvalid rows failed validation and were not attempted.
+	SongImportInvalid SongImportStatus = "invalid"
+	// SongImportFailed rows were valid but could not be created.
+	SongImportFailed SongImportStatus = "failed"
+)
+
+type SongImportResult struct {
+	Line   int              `json:"line"`
+	SunoID string           `json:"suno_id,omitempty"`
+	Status SongImportStatus `json:"status"`
+	SongID int              `json:"song_id,omitempty"`
+	Errors []string         `json:"errors,omitempty"`
+}
+
+// SongImportReport has one result per imported row, in file order.
+type SongImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Total   int                `json:"total"`
+	Valid   int                `json:"valid"`
+	Created int                `json:"created"`
+	Invalid int                `json:"invalid"`
+	Failed  int                `json:"failed"`
+	Rows    []SongImportResult `json:"rows"`
+}
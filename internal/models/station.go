@@ -1,7 +1,15 @@
 package models
 
 type Station struct {
-	ID   int      `json:"id"`
-	Name string   `json:"name"`
-	Tags []string `json:"tags"`
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags"`
+	Premium bool     `json:"premium"`
+	// OwnerID is set on personal stations, which only their owner can see and play.
+	OwnerID *int `json:"owner_id,omitempty"`
+}
+
+// AccessibleBy reports whether the station is a shared station or the user's own.
+func (s *Station) AccessibleBy(userID int) bool {
+	return s.OwnerID == nil || *s.OwnerID == userID
 }
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive   SubscriptionStatus = "active"
+	SubscriptionPastDue  SubscriptionStatus = "past_due"
+	SubscriptionCanceled SubscriptionStatus = "canceled"
+)
+
+// Subscription is a paid plan at the payment provider that makes its user premium.
+type Subscription struct {
+	ID                     int                `json:"id"`
+	UserID                 int                `json:"user_id"`
+	ProviderSubscriptionID string             `json:"provider_subscription_id"`
+	ProviderCustomerID     string             `json:"provider_customer_id"`
+	Status                 SubscriptionStatus `json:"status"`
+	CurrentPeriodEnd       time.Time          `json:"current_period_end"`
+	// PremiumUntil is when premium access ends unless the provider reports a payment,
+	// including the grace period.
+	PremiumUntil time.Time  `json:"premium_until"`
+	CanceledAt   *time.Time `json:"canceled_at,omitempty"`
+	// EndedAt is when the user was moved back to free.
+	EndedAt *time.Time `json:"ended_at,omitempty"`
+	// LastEventAt is the creation time of the newest provider event applied, so events
+	// delivered out of order do not undo newer ones.
+	LastEventAt time.Time `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
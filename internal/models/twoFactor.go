@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// TwoFactor is a user's TOTP enrollment. It is pending until the user proves they set up
+// their authenticator by entering a code, which sets EnabledAt.
+type TwoFactor struct {
+	UserID    int
+	Secret    string
+	EnabledAt *time.Time
+	// LastUsedStep is the TOTP time step of the last accepted code, so a code cannot be
+	// replayed within its validity window.
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
@@ -1,20 +1,47 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Role string
 
 const (
 	RoleFree    Role = "free"
 	RolePremium Role = "premium"
+	RoleAnalyst Role = "analyst"
 	RoleAdmin   Role = "admin"
 )
 
+func (r Role) Valid() bool {
+	switch r {
+	case RoleFree, RolePremium, RoleAnalyst, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	Role      Role      `json:"role"`
+	ID            int        `json:"id"`
+	Username      string     `json:"username"`
+	Password      string     `json:"-"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Role          Role       `json:"role"`
+	SuspendedAt   *time.Time `json:"suspended_at,omitempty"`
+	// PasswordResetRequired marks an account whose stored hash cannot be trusted to match
+	// the password. It is only loaded together with Password.
+	PasswordResetRequired bool `json:"-"`
+}
+
+// Suspended reports whether an admin has suspended the account.
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// String leaves out the password hash so users can be logged safely.
+func (u User) String() string {
+	return fmt.Sprintf("{ID:%d Username:%s Email:%s Role:%s}", u.ID, u.Username, u.Email, u.Role)
 }
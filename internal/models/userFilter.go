@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Sort keys accepted by UserFilter.Sort.
+const (
+	UserSortID        = "id"
+	UserSortUsername  = "username"
+	UserSortEmail     = "email"
+	UserSortRole      = "role"
+	UserSortCreatedAt = "created_at"
+)
+
+// UserFilter narrows and orders the admin user directory. Zero values leave a filter out;
+// Username and Email match case-insensitive substrings.
+type UserFilter struct {
+	Username      string
+	Email         string
+	Role          Role
+	Suspended     *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	Descending    bool
+	Limit         int
+	Offset        int
+}
+
+// UserPage is one page of the user directory. Total counts every user matching the filter.
+type UserPage struct {
+	Users  []*User `json:"users"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
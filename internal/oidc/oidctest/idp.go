@@ -0,0 +1,166 @@
+// Package oidctest provides a local OpenID Connect provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"louderspace/internal/oidc"
+	"louderspace/internal/utils"
+)
+
+// IdP is a minimal OpenID Connect provider supporting the authorization-code flow with
+// PKCE. Instead of a login page, tests call Authorize with the claims of the signed-in user.
+type IdP struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key *utils.SigningKey
+	ks  *utils.KeySet
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+type authorization struct {
+	claims      map[string]interface{}
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+func NewIdP(clientID, clientSecret string) (*IdP, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	key := &utils.SigningKey{ID: "idp-key", Method: jwt.SigningMethodRS256, SignKey: privateKey, VerifyKey: &privateKey.PublicKey}
+	ks, err := utils.NewKeySet(key.ID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	idp := &IdP{ClientID: clientID, ClientSecret: clientSecret, key: key, ks: ks, codes: make(map[string]*authorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	return idp, nil
+}
+
+func (i *IdP) Issuer() string {
+	return i.Server.URL
+}
+
+func (i *IdP) Close() {
+	i.Server.Close()
+}
+
+// Config returns a provider configuration for this IdP.
+func (i *IdP) Config(name, redirectURL string) oidc.Config {
+	return oidc.Config{Name: name, Issuer: i.Issuer(), ClientID: i.ClientID, ClientSecret: i.ClientSecret, RedirectURL: redirectURL}
+}
+
+// Authorize plays a user signing in at the provider. It takes the authorization URL the app
+// sent the browser to and returns the code and state the provider redirects back with. The
+// claims end up in the ID token; "sub" is required.
+func (i *IdP) Authorize(authURL string, claims map[string]interface{}) (code, state string, err error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != i.ClientID || query.Get("response_type") != "code" {
+		return "", "", errors.New("invalid authorization request")
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		return "", "", errors.New("PKCE is required")
+	}
+
+	code, err = utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	i.mu.Lock()
+	i.codes[code] = &authorization{
+		claims:      claims,
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	i.mu.Unlock()
+	return code, query.Get("state"), nil
+}
+
+func (i *IdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                i.Issuer(),
+		"authorization_endpoint":                i.Issuer() + "/authorize",
+		"token_endpoint":                        i.Issuer() + "/token",
+		"jwks_uri":                              i.Issuer() + "/jwks",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+	})
+}
+
+func (i *IdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": i.ks.JWKS()})
+}
+
+func (i *IdP) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != i.ClientID || clientSecret != i.ClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Codes are single use.
+	i.mu.Lock()
+	auth, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	if !ok || auth.redirectURI != r.PostForm.Get("redirect_uri") || oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != auth.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   i.Issuer(),
+		"aud":   i.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": auth.nonce,
+	}
+	for name, value := range auth.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(i.key.Method, claims)
+	token.Header["kid"] = i.key.ID
+	idToken, err := token.SignedString(i.key.SignKey)
+	if err != nil {
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "unused",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
@@ -0,0 +1,19 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"louderspace/internal/utils"
+)
+
+// NewCodeVerifier returns a random PKCE code verifier (RFC 7636). 32 random bytes encode to
+// 43 characters, the shortest length the RFC allows.
+func NewCodeVerifier() (string, error) {
+	return utils.GenerateOpaqueToken(32)
+}
+
+// CodeChallenge derives the S256 code challenge sent with the authorization request.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
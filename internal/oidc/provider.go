@@ -0,0 +1,332 @@
+package oidc
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"louderspace/internal/utils"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrExchangeFailed = errors.New("authorization code exchange failed")
+)
+
+// Config describes an OpenID Connect provider registered for the authorization-code flow.
+type Config struct {
+	// Name identifies the provider in linked identities, e.g. "okta".
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim is the ID token claim that lists the user's groups.
+	GroupsClaim string
+}
+
+// Claims are the parts of a verified ID token the app uses.
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+// Provider fetches discovery and keys on first use, so the server starts while it is down.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]interface{}
+}
+
+type discoveryDocument struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+func NewProvider(config Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &Provider{config: config, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the provider's login page for a new authorization request.
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the claims of the verified ID token.
+func (p *Provider) Exchange(code, codeVerifier, nonce string) (*Claims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.config.ClientID},
+	}
+	useBasicAuth := p.config.ClientSecret != "" && supportsBasicAuth(discovery.TokenAuthMethods)
+	if p.config.ClientSecret != "" && !useBasicAuth {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+	req, err := http.NewRequest("POST", discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: token endpoint answered %d: %s", ErrExchangeFailed, resp.StatusCode, body)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrExchangeFailed)
+	}
+	return p.verify(tokenResponse.IDToken, nonce)
+}
+
+// supportsBasicAuth follows RFC 8414, which defaults to client_secret_basic.
+func supportsBasicAuth(methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, method := range methods {
+		if method == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
+
+// verify accepts only asymmetric algorithms, so a token cannot be signed with a public key.
+func (p *Provider) verify(idToken, nonce string) (*Claims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *utils.SigningMethodEdDSA:
+		default:
+			return nil, fmt.Errorf("unsupported signing method %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != discovery.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+	if !hasAudience(claims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("%w: token is not meant for this client", ErrInvalidIDToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidIDToken)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	if result.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	result.Email, _ = claims["email"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.Name, _ = claims["name"].(string)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	switch groups := claims[p.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				result.Groups = append(result.Groups, name)
+			}
+		}
+	case string:
+		result.Groups = []string{groups}
+	}
+	return result, nil
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, entry := range aud {
+			if entry == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Provider) discover() (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	discovery := &discoveryDocument{}
+	if err := p.getJSON(strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+	if discovery.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("provider reports issuer %q, expected %q", discovery.Issuer, p.config.Issuer)
+	}
+	p.discovery = discovery
+	return discovery, nil
+}
+
+// key refetches the key set when the ID is unknown, which picks up key rotations.
+func (p *Provider) key(kid string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	jwksURI := p.discovery.JWKSURI
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []utils.JWK `json:"keys"`
+	}
+	if err := p.getJSON(jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if publicKey, err := parseJWK(jwk); err == nil {
+			keys[jwk.KeyID] = publicKey
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func parseJWK(jwk utils.JWK) (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+}
+
+func (p *Provider) getJSON(url string, v interface{}) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s answered %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,72 @@
+package oidc_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/oidc"
+	"louderspace/internal/oidc/oidctest"
+	"testing"
+)
+
+func TestProviderExchange(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+
+	verifier, err := oidc.NewCodeVerifier()
+	assert.NoError(t, err)
+	authURL, err := provider.AuthCodeURL("state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	assert.NoError(t, err)
+
+	code, state, err := idp.Authorize(authURL, map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "jane@corp.example",
+		"email_verified": true,
+		"groups":         []string{"staff", "music"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "state-1", state)
+
+	// A code bound to another nonce, or redeemed without the right verifier, is rejected.
+	_, err = provider.Exchange(code, verifier, "other-nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	code, _, err = idp.Authorize(authURL, map[string]interface{}{"sub": "user-1"})
+	assert.NoError(t, err)
+	_, err = provider.Exchange(code, "wrong-verifier", "nonce-1")
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+
+	code, _, err = idp.Authorize(authURL, map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "jane@corp.example",
+		"email_verified": true,
+		"groups":         []string{"staff", "music"},
+	})
+	assert.NoError(t, err)
+	claims, err := provider.Exchange(code, verifier, "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "jane@corp.example", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, []string{"staff", "music"}, claims.Groups)
+
+	// Codes are single use.
+	_, err = provider.Exchange(code, verifier, "nonce-1")
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+}
+
+func TestProviderRejectsOtherAudience(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := provider.AuthCodeURL("state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	assert.NoError(t, err)
+	code, _, err := idp.Authorize(authURL, map[string]interface{}{"sub": "user-1", "aud": "another-app"})
+	assert.NoError(t, err)
+
+	_, err = provider.Exchange(code, verifier, "nonce-1")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
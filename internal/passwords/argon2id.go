@@ -0,0 +1,99 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+// Argon2idParams tune argon2id. Zero fields take the defaults recommended by OWASP for
+// argon2id with moderate memory: 64 MiB, 3 passes, 2 lanes.
+type Argon2idParams struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+// Argon2id stores hashes in the PHC string format,
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>, so the parameters travel with each hash.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) *Argon2id {
+	if params.Memory == 0 {
+		params.Memory = 64 * 1024
+	}
+	if params.Iterations == 0 {
+		params.Iterations = 3
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = 2
+	}
+	return &Argon2id{params}
+}
+
+func (a *Argon2id) Name() string {
+	return "argon2id"
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, argon2idKeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.params.Memory, a.params.Iterations, a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2id) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (a *Argon2id) Verify(hash, password string) (bool, error) {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func (a *Argon2id) Outdated(hash string) bool {
+	params, _, key, err := parseArgon2id(hash)
+	return err != nil || params != a.params || len(key) != argon2idKeyLength
+}
+
+func parseArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+	return params, salt, key, nil
+}
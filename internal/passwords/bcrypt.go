@@ -0,0 +1,48 @@
+package passwords
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt hashes with cost, or bcrypt.DefaultCost when cost is 0.
+func NewBcrypt(cost int) *Bcrypt {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{cost}
+}
+
+func (b *Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b *Bcrypt) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b *Bcrypt) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Bcrypt) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.cost
+}
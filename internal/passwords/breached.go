@@ -0,0 +1,84 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prefixLength is the length of the SHA-1 hex prefix a range lookup reveals, as in the
+// Pwned Passwords range API.
+const prefixLength = 5
+
+//go:embed breached.txt
+var bundledList string
+
+// RangeSource answers k-anonymity range queries: given the first five hex characters of a
+// password's SHA-1 hash it returns the remaining 35 characters of every known breached hash
+// with that prefix. Only the prefix leaves the caller, so a remote source never sees which
+// password is being checked.
+type RangeSource interface {
+	Range(prefix string) ([]string, error)
+}
+
+// Ranges is an in-memory RangeSource indexed by hash prefix.
+type Ranges map[string][]string
+
+// BundledRanges returns the list of common and breached passwords shipped with the app.
+func BundledRanges() Ranges {
+	ranges, err := LoadRanges(strings.NewReader(bundledList))
+	if err != nil {
+		panic(fmt.Sprintf("passwords: invalid bundled list: %v", err))
+	}
+	return ranges
+}
+
+// LoadRanges reads uppercase or lowercase SHA-1 hashes, one per line, optionally followed by
+// ":count" as in the Pwned Passwords downloads. Blank lines and lines starting with # are
+// skipped.
+func LoadRanges(r io.Reader) (Ranges, error) {
+	ranges := make(Ranges)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		hash := strings.ToUpper(strings.SplitN(entry, ":", 2)[0])
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		prefix := hash[:prefixLength]
+		ranges[prefix] = append(ranges[prefix], hash[prefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func (r Ranges) Range(prefix string) ([]string, error) {
+	return r[strings.ToUpper(prefix)], nil
+}
+
+// Breached reports whether password appears in source.
+func Breached(source RangeSource, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := source.Range(hash[:prefixLength])
+	if err != nil {
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if strings.EqualFold(strings.SplitN(suffix, ":", 2)[0], hash[prefixLength:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
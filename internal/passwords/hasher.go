@@ -0,0 +1,58 @@
+package passwords
+
+import "errors"
+
+var ErrUnknownHash = errors.New("password hash has an unknown format")
+
+// Algorithm is one way of hashing passwords.
+type Algorithm interface {
+	Name() string
+	Hash(password string) (string, error)
+	// Recognizes reports whether hash was made by this algorithm.
+	Recognizes(hash string) bool
+	Verify(hash, password string) (bool, error)
+	// Outdated reports whether hash was made with other parameters than Hash uses now.
+	Outdated(hash string) bool
+}
+
+// Hasher hashes new passwords with the current algorithm and verifies hashes made by any of
+// the algorithms it knows, so the algorithm or its cost can change without locking anyone
+// out: hashes are upgraded the next time their owner signs in.
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+// NewHasher hashes with current and also accepts hashes made by legacy.
+func NewHasher(current Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{current, append([]Algorithm{current}, legacy...)}
+}
+
+// DefaultHasher hashes with bcrypt at its default cost and accepts argon2id hashes.
+func DefaultHasher() *Hasher {
+	return NewHasher(NewBcrypt(0), NewArgon2id(Argon2idParams{}))
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify checks password against hash. rehash is set when the password matched but hash
+// should be replaced with a fresh Hash of it. An empty hash, as on accounts that only sign
+// in through SSO, matches nothing.
+func (h *Hasher) Verify(hash, password string) (match, rehash bool, err error) {
+	if hash == "" {
+		return false, false, nil
+	}
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Recognizes(hash) {
+			continue
+		}
+		match, err := algorithm.Verify(hash, password)
+		if err != nil || !match {
+			return false, false, err
+		}
+		return true, algorithm != h.current || algorithm.Outdated(hash), nil
+	}
+	return false, false, ErrUnknownHash
+}
@@ -0,0 +1,77 @@
+package passwords
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+// Small parameters keep the tests fast.
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestHasherRoundTrip(t *testing.T) {
+	for _, algorithm := range []Algorithm{NewBcrypt(bcrypt.MinCost), NewArgon2id(testArgon2idParams)} {
+		hasher := NewHasher(algorithm)
+		hash, err := hasher.Hash("Tr0ub4dor-and-3")
+		assert.NoError(t, err)
+		assert.True(t, algorithm.Recognizes(hash), algorithm.Name())
+
+		match, rehash, err := hasher.Verify(hash, "Tr0ub4dor-and-3")
+		assert.NoError(t, err)
+		assert.True(t, match, algorithm.Name())
+		assert.False(t, rehash, algorithm.Name())
+
+		match, _, err = hasher.Verify(hash, "tr0ub4dor-and-3")
+		assert.NoError(t, err)
+		assert.False(t, match, algorithm.Name())
+	}
+}
+
+func TestHasherRehash(t *testing.T) {
+	bcryptHash, _ := NewBcrypt(bcrypt.MinCost).Hash("Tr0ub4dor-and-3")
+	argon2Hash, _ := NewArgon2id(testArgon2idParams).Hash("Tr0ub4dor-and-3")
+	assert.True(t, strings.HasPrefix(argon2Hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	// A higher cost makes existing hashes outdated.
+	_, rehash, err := NewHasher(NewBcrypt(bcrypt.MinCost+1)).Verify(bcryptHash, "Tr0ub4dor-and-3")
+	assert.NoError(t, err)
+	assert.True(t, rehash)
+
+	// So do other argon2id parameters.
+	_, rehash, err = NewHasher(NewArgon2id(Argon2idParams{Memory: 2048, Iterations: 1, Parallelism: 1})).Verify(argon2Hash, "Tr0ub4dor-and-3")
+	assert.NoError(t, err)
+	assert.True(t, rehash)
+
+	// Hashes of a legacy algorithm still verify, and are marked for upgrade.
+	hasher := NewHasher(NewArgon2id(testArgon2idParams), NewBcrypt(bcrypt.MinCost))
+	match, rehash, err := hasher.Verify(bcryptHash, "Tr0ub4dor-and-3")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, rehash)
+
+	// A wrong password never asks for a rehash.
+	match, rehash, err = hasher.Verify(bcryptHash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, match)
+	assert.False(t, rehash)
+}
+
+func TestHasherUnknownHashes(t *testing.T) {
+	hasher := NewHasher(NewBcrypt(bcrypt.MinCost))
+
+	match, _, err := hasher.Verify("", "")
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	_, _, err = hasher.Verify("plaintext", "plaintext")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+
+	// argon2id hashes are only accepted when argon2id is configured.
+	argon2Hash, _ := NewArgon2id(testArgon2idParams).Hash("Tr0ub4dor-and-3")
+	_, _, err = hasher.Verify(argon2Hash, "Tr0ub4dor-and-3")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+
+	_, err = NewArgon2id(testArgon2idParams).Verify("$argon2id$v=19$m=x$salt$key", "Tr0ub4dor-and-3")
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Package passwords decides which passwords accounts may use.
+package passwords
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrPolicyViolation matches every *ValidationError with errors.Is.
+var ErrPolicyViolation = errors.New("password does not meet the password policy")
+
+// CharacterClass is a kind of character a policy can require.
+type CharacterClass string
+
+const (
+	ClassLower  CharacterClass = "lower"
+	ClassUpper  CharacterClass = "upper"
+	ClassDigit  CharacterClass = "digit"
+	ClassSymbol CharacterClass = "symbol"
+)
+
+func (c CharacterClass) Valid() bool {
+	switch c {
+	case ClassLower, ClassUpper, ClassDigit, ClassSymbol:
+		return true
+	}
+	return false
+}
+
+var classDescriptions = map[CharacterClass]string{
+	ClassLower:  "a lowercase letter",
+	ClassUpper:  "an uppercase letter",
+	ClassDigit:  "a digit",
+	ClassSymbol: "a symbol",
+}
+
+func (c CharacterClass) matches(r rune) bool {
+	switch c {
+	case ClassLower:
+		return unicode.IsLower(r)
+	case ClassUpper:
+		return unicode.IsUpper(r)
+	case ClassDigit:
+		return unicode.IsDigit(r)
+	case ClassSymbol:
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+	}
+	return false
+}
+
+// Rules a password can break, reported in Violation.Rule.
+const (
+	RuleTooShort         = "too_short"
+	RuleTooLong          = "too_long"
+	RuleMissingClass     = "missing_character_class"
+	RuleContainsUsername = "contains_username"
+	RuleBreached         = "breached"
+)
+
+// Violation is one broken rule.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rule a password breaks, so a client can show them all at once.
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return ErrPolicyViolation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// MaxBytes is the longest password worth accepting: bcrypt ignores everything past it.
+const MaxBytes = 72
+
+// minUsernameMatch keeps very short usernames from rejecting unrelated passwords.
+const minUsernameMatch = 3
+
+// Policy is the set of rules passwords are checked against. Breached may be nil to skip
+// screening against known breached passwords.
+type Policy struct {
+	MinLength       int
+	MaxLength       int
+	RequiredClasses []CharacterClass
+	Breached        RangeSource
+}
+
+// DefaultPolicy asks for ten characters mixing lower and upper case and digits, and screens
+// against the bundled breached list.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MinLength:       10,
+		MaxLength:       MaxBytes,
+		RequiredClasses: []CharacterClass{ClassLower, ClassUpper, ClassDigit},
+		Breached:        BundledRanges(),
+	}
+}
+
+// Validate checks password for the account named username. It returns a *ValidationError
+// when rules are broken, or another error when the breached list cannot be consulted.
+func (p *Policy) Validate(username, password string) error {
+	var violations []Violation
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violations = append(violations, Violation{RuleTooShort, fmt.Sprintf("must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, Violation{RuleTooLong, fmt.Sprintf("must be at most %d bytes long", p.MaxLength)})
+	}
+	for _, class := range p.RequiredClasses {
+		if !strings.ContainsFunc(password, class.matches) {
+			violations = append(violations, Violation{RuleMissingClass, "must contain " + classDescriptions[class]})
+		}
+	}
+	username = strings.TrimSpace(username)
+	if len([]rune(username)) >= minUsernameMatch && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, Violation{RuleContainsUsername, "must not contain the username"})
+	}
+	if p.Breached != nil && password != "" {
+		breached, err := Breached(p.Breached, password)
+		if err != nil {
+			return fmt.Errorf("failed to screen password: %w", err)
+		}
+		if breached {
+			violations = append(violations, Violation{RuleBreached, "is too common or has appeared in a data breach"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{violations}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package passwords
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func rules(err error) []string {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	var broken []string
+	for _, violation := range validationErr.Violations {
+		broken = append(broken, violation.Rule)
+	}
+	return broken
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := DefaultPolicy()
+
+	assert.NoError(t, policy.Validate("testuser", "Tr0ub4dor-and-3"))
+
+	err := policy.Validate("testuser", "")
+	assert.ErrorIs(t, err, ErrPolicyViolation)
+	assert.Equal(t, []string{RuleTooShort, RuleMissingClass, RuleMissingClass, RuleMissingClass}, rules(err))
+
+	assert.Equal(t, []string{RuleMissingClass}, rules(policy.Validate("testuser", "alllowercase1")))
+	assert.Equal(t, []string{RuleTooLong}, rules(policy.Validate("testuser", "Aa1"+strings.Repeat("x", MaxBytes))))
+	assert.Equal(t, []string{RuleContainsUsername}, rules(policy.Validate("Melody", "MyMELODY2024x")))
+	assert.Equal(t, []string{RuleBreached}, rules(policy.Validate("testuser", "Password123")))
+
+	// Usernames too short to matter are not matched.
+	assert.NoError(t, policy.Validate("jo", "Banjo-Tuning-77"))
+
+	symbols := &Policy{MinLength: 4, RequiredClasses: []CharacterClass{ClassSymbol}}
+	assert.Equal(t, []string{RuleMissingClass}, rules(symbols.Validate("testuser", "abcd")))
+	assert.NoError(t, symbols.Validate("testuser", "ab-d"))
+}
+
+func TestLoadRanges(t *testing.T) {
+	// SHA-1 of "hunter2", in the Pwned Passwords download format.
+	ranges, err := LoadRanges(strings.NewReader("# comment\n\nf3bbbd66a63d4bf1747940578ec3d0103530e21d:17\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"D66A63D4BF1747940578EC3D0103530E21D"}, ranges["F3BBB"])
+
+	breached, err := Breached(ranges, "hunter2")
+	assert.NoError(t, err)
+	assert.True(t, breached)
+	breached, err = Breached(ranges, "hunter3")
+	assert.NoError(t, err)
+	assert.False(t, breached)
+
+	_, err = LoadRanges(strings.NewReader("not-a-hash\n"))
+	assert.Error(t, err)
+
+	assert.NotEmpty(t, BundledRanges())
+}
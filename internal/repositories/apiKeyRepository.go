@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"strings"
+	"time"
+)
+
+type APIKeyStorage interface {
+	Create(key *models.APIKey) error
+	APIKeyByHash(keyHash string) (*models.APIKey, error)
+	APIKeysForUser(userID int) ([]*models.APIKey, error)
+	Revoke(id, userID int) (bool, error)
+	TouchLastUsed(id int, at time.Time) error
+}
+
+type APIKeyDatabase struct {
+	db *sql.DB
+}
+
+func NewAPIKeyDatabase(db *sql.DB) APIKeyStorage {
+	return &APIKeyDatabase{db}
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at"
+
+func (r *APIKeyDatabase) Create(key *models.APIKey) error {
+	query := "INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id"
+	return r.db.QueryRow(query, key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, ","), key.ExpiresAt, key.CreatedAt).Scan(&key.ID)
+}
+
+func (r *APIKeyDatabase) APIKeyByHash(keyHash string) (*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = $1"
+	return scanAPIKey(r.db.QueryRow(query, keyHash))
+}
+
+func (r *APIKeyDatabase) APIKeysForUser(userID int) ([]*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke revokes one of the user's keys and reports whether a key was revoked.
+func (r *APIKeyDatabase) Revoke(id, userID int) (bool, error) {
+	query := "UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *APIKeyDatabase) TouchLastUsed(id int, at time.Time) error {
+	query := "UPDATE api_keys SET last_used_at = $1 WHERE id = $2"
+	_, err := r.db.Exec(query, at, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &expiresAt, &lastUsedAt, &key.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	key.Scopes = strings.Split(scopes, ",")
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockAPIKeyStorage struct {
+	keys   map[int]*models.APIKey
+	nextID int
+	mu     sync.RWMutex
+}
+
+func NewMockAPIKeyStorage() *MockAPIKeyStorage {
+	return &MockAPIKeyStorage{
+		keys:   make(map[int]*models.APIKey),
+		nextID: 1,
+	}
+}
+
+func (m *MockAPIKeyStorage) Create(key *models.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key.ID = m.nextID
+	m.nextID++
+	stored := *key
+	m.keys[key.ID] = &stored
+	return nil
+}
+
+func (m *MockAPIKeyStorage) APIKeyByHash(keyHash string) (*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, errors.New("api key not found")
+}
+
+func (m *MockAPIKeyStorage) APIKeysForUser(userID int) ([]*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := []*models.APIKey{}
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			found := *key
+			keys = append(keys, &found)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID > keys[j].ID })
+	return keys, nil
+}
+
+func (m *MockAPIKeyStorage) Revoke(id, userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, exists := m.keys[id]
+	if !exists || key.UserID != userID || key.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return true, nil
+}
+
+func (m *MockAPIKeyStorage) TouchLastUsed(id int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if key, exists := m.keys[id]; exists {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
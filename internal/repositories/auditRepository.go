@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+)
+
+type AuditStorage interface {
+	Record(entry *models.AuditEntry) error
+	// EntriesForUser returns the actions taken on a user, newest first.
+	EntriesForUser(userID int) ([]*models.AuditEntry, error)
+}
+
+type AuditDatabase struct {
+	db *sql.DB
+}
+
+func NewAuditDatabase(db *sql.DB) AuditStorage {
+	return &AuditDatabase{db}
+}
+
+func (r *AuditDatabase) Record(entry *models.AuditEntry) error {
+	return insertAuditEntry(r.db, entry)
+}
+
+// queryRower is a *sql.DB, or the *sql.Tx of a change that is audited together with it.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertAuditEntry(q queryRower, entry *models.AuditEntry) error {
+	query := "INSERT INTO audit_log (actor_id, target_user_id, action, details, created_at) VALUES (NULLIF($1, 0), $2, $3, $4, $5) RETURNING id"
+	return q.QueryRow(query, entry.ActorID, entry.TargetUserID, entry.Action, entry.Details, entry.CreatedAt).Scan(&entry.ID)
+}
+
+func (r *AuditDatabase) EntriesForUser(userID int) ([]*models.AuditEntry, error) {
+	query := "SELECT id, COALESCE(actor_id, 0), target_user_id, action, details, created_at FROM audit_log WHERE target_user_id = $1 ORDER BY created_at DESC, id DESC"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.TargetUserID, &entry.Action, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"louderspace/internal/models"
+	"sync"
+)
+
+type MockAuditStorage struct {
+	Entries []*models.AuditEntry
+	mu      sync.Mutex
+}
+
+func NewMockAuditStorage() *MockAuditStorage {
+	return &MockAuditStorage{}
+}
+
+func (m *MockAuditStorage) Record(entry *models.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = len(m.Entries) + 1
+	m.Entries = append(m.Entries, entry)
+	return nil
+}
+
+func (m *MockAuditStorage) EntriesForUser(userID int) ([]*models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []*models.AuditEntry
+	for i := len(m.Entries) - 1; i >= 0; i-- {
+		if m.Entries[i].TargetUserID == userID {
+			entries = append(entries, m.Entries[i])
+		}
+	}
+	return entries, nil
+}
@@ -11,6 +11,7 @@ type FeedbackStorage interface {
 	DeleteFeedback(userID, songID int) error
 	GetFeedback(userID, songID int) (*models.Feedback, error)
 	GetFeedbackForUserAndSongs(userID int, songIDs []int) (map[int]bool, error)
+	EachFeedbackForUser(userID int, fn func(feedback *models.Feedback) error) error
 }
 
 type FeedbackDatabase struct {
@@ -62,3 +63,25 @@ func (r *FeedbackDatabase) GetFeedbackForUserAndSongs(userID int, songIDs []int)
 	}
 	return feedbackMap, nil
 }
+
+// EachFeedbackForUser calls fn for every feedback row of the user without loading them all
+// into memory. It stops at the first error fn returns.
+func (r *FeedbackDatabase) EachFeedbackForUser(userID int, fn func(feedback *models.Feedback) error) error {
+	query := "SELECT id, user_id, song_id, liked FROM feedback WHERE user_id = $1 ORDER BY id"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		feedback := &models.Feedback{}
+		if err := rows.Scan(&feedback.ID, &feedback.UserID, &feedback.SongID, &feedback.Liked); err != nil {
+			return err
+		}
+		if err := fn(feedback); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -45,3 +45,31 @@ func (m *MockFeedbackStorage) GetFeedback(userID, songID int) (*models.Feedback,
 	}
 	return nil, errors.New("feedback not found")
 }
+
+func (m *MockFeedbackStorage) GetFeedbackForUserAndSongs(userID int, songIDs []int) (map[int]bool, error) {
+	feedbackMap := make(map[int]bool)
+	for _, f := range m.Feedbacks {
+		if f.UserID != userID {
+			continue
+		}
+		for _, songID := range songIDs {
+			if f.SongID == songID {
+				feedbackMap[songID] = f.Liked
+			}
+		}
+	}
+	return feedbackMap, nil
+}
+
+func (m *MockFeedbackStorage) EachFeedbackForUser(userID int, fn func(feedback *models.Feedback) error) error {
+	for _, f := range m.Feedbacks {
+		if f.UserID != userID {
+			continue
+		}
+		feedback := f
+		if err := fn(&feedback); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"time"
+)
+
+type IdentityStorage interface {
+	// Identity returns the identity with the provider's subject, or nil when it is not linked.
+	Identity(provider, subject string) (*models.Identity, error)
+	Link(identity *models.Identity) error
+	TouchIdentity(id int, email string, at time.Time) error
+	IdentitiesForUser(userID int) ([]*models.Identity, error)
+
+	CreateLoginState(state *models.SSOLoginState) error
+	// ConsumeLoginState marks the state used and returns it, or nil when it does not exist,
+	// was already used or belongs to another browser binding.
+	ConsumeLoginState(provider, stateHash, bindingHash string) (*models.SSOLoginState, error)
+}
+
+type IdentityDatabase struct {
+	db *sql.DB
+}
+
+func NewIdentityDatabase(db *sql.DB) IdentityStorage {
+	return &IdentityDatabase{db}
+}
+
+func (r *IdentityDatabase) Identity(provider, subject string) (*models.Identity, error) {
+	query := "SELECT id, user_id, provider, subject, email, created_at, last_login_at FROM identities WHERE provider = $1 AND subject = $2"
+	identity, err := scanIdentity(r.db.QueryRow(query, provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return identity, err
+}
+
+func (r *IdentityDatabase) Link(identity *models.Identity) error {
+	query := "INSERT INTO identities (user_id, provider, subject, email, created_at, last_login_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	return r.db.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, identity.LastLoginAt).Scan(&identity.ID)
+}
+
+func (r *IdentityDatabase) TouchIdentity(id int, email string, at time.Time) error {
+	query := "UPDATE identities SET email = $1, last_login_at = $2 WHERE id = $3"
+	_, err := r.db.Exec(query, email, at, id)
+	return err
+}
+
+func (r *IdentityDatabase) IdentitiesForUser(userID int) ([]*models.Identity, error) {
+	query := "SELECT id, user_id, provider, subject, email, created_at, last_login_at FROM identities WHERE user_id = $1 ORDER BY id"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*models.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func scanIdentity(row rowScanner) (*models.Identity, error) {
+	identity := &models.Identity{}
+	if err := row.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *IdentityDatabase) CreateLoginState(state *models.SSOLoginState) error {
+	query := "INSERT INTO sso_login_states (provider, state_hash, binding_hash, nonce, code_verifier, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id"
+	return r.db.QueryRow(query, state.Provider, state.StateHash, state.BindingHash, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt).Scan(&state.ID)
+}
+
+func (r *IdentityDatabase) ConsumeLoginState(provider, stateHash, bindingHash string) (*models.SSOLoginState, error) {
+	state := &models.SSOLoginState{}
+	query := `UPDATE sso_login_states SET used_at = $1
+		WHERE provider = $2 AND state_hash = $3 AND binding_hash = $4 AND used_at IS NULL
+		RETURNING id, provider, state_hash, binding_hash, nonce, code_verifier, expires_at, created_at, used_at`
+	err := r.db.QueryRow(query, time.Now(), provider, stateHash, bindingHash).Scan(
+		&state.ID, &state.Provider, &state.StateHash, &state.BindingHash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt, &state.UsedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
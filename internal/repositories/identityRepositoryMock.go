@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"sync"
+	"time"
+)
+
+type MockIdentityStorage struct {
+	identities  map[int]*models.Identity
+	loginStates map[string]*models.SSOLoginState
+	mu          sync.RWMutex
+	nextID      int
+}
+
+func NewMockIdentityStorage() *MockIdentityStorage {
+	return &MockIdentityStorage{
+		identities:  make(map[int]*models.Identity),
+		loginStates: make(map[string]*models.SSOLoginState),
+		nextID:      1,
+	}
+}
+
+func (m *MockIdentityStorage) Identity(provider, subject string) (*models.Identity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			found := *identity
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockIdentityStorage) Link(identity *models.Identity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return errors.New("identity already linked")
+		}
+	}
+	identity.ID = m.nextID
+	m.nextID++
+	stored := *identity
+	m.identities[identity.ID] = &stored
+	return nil
+}
+
+func (m *MockIdentityStorage) TouchIdentity(id int, email string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity, ok := m.identities[id]
+	if !ok {
+		return errors.New("identity not found")
+	}
+	identity.Email = email
+	identity.LastLoginAt = at
+	return nil
+}
+
+func (m *MockIdentityStorage) IdentitiesForUser(userID int) ([]*models.Identity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var identities []*models.Identity
+	for id := 1; id < m.nextID; id++ {
+		if identity, ok := m.identities[id]; ok && identity.UserID == userID {
+			found := *identity
+			identities = append(identities, &found)
+		}
+	}
+	return identities, nil
+}
+
+func (m *MockIdentityStorage) CreateLoginState(state *models.SSOLoginState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state.ID = len(m.loginStates) + 1
+	stored := *state
+	m.loginStates[state.Provider+":"+state.StateHash] = &stored
+	return nil
+}
+
+func (m *MockIdentityStorage) ConsumeLoginState(provider, stateHash, bindingHash string) (*models.SSOLoginState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.loginStates[provider+":"+stateHash]
+	if !ok || state.UsedAt != nil || state.BindingHash != bindingHash {
+		return nil, nil
+	}
+	now := time.Now()
+	state.UsedAt = &now
+	found := *state
+	return &found, nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"time"
+)
+
+type InviteStorage interface {
+	Create(invite *models.Invite) error
+	Invites() ([]*models.Invite, error)
+	// InviteByHash returns the invite with the code hash, or nil when there is none.
+	InviteByHash(codeHash string) (*models.Invite, error)
+	// RedeemForUser uses up one use of the invite, creates user and records audit for it in
+	// one transaction. It reports whether the invite was still usable at now; if not, or if
+	// anything fails, nothing is written.
+	RedeemForUser(id int, now time.Time, user *models.User, audit *models.AuditEntry) (bool, error)
+	Revoke(id int, at time.Time) (bool, error)
+}
+
+type InviteDatabase struct {
+	db *sql.DB
+}
+
+func NewInviteDatabase(db *sql.DB) InviteStorage {
+	return &InviteDatabase{db}
+}
+
+const inviteColumns = "id, prefix, code_hash, role, note, max_uses, uses, expires_at, created_by, created_at, revoked_at"
+
+func (r *InviteDatabase) Create(invite *models.Invite) error {
+	query := "INSERT INTO invites (prefix, code_hash, role, note, max_uses, expires_at, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+	return r.db.QueryRow(query, invite.Prefix, invite.CodeHash, invite.Role, invite.Note, invite.MaxUses, invite.ExpiresAt, invite.CreatedBy, invite.CreatedAt).Scan(&invite.ID)
+}
+
+func (r *InviteDatabase) Invites() ([]*models.Invite, error) {
+	rows, err := r.db.Query("SELECT " + inviteColumns + " FROM invites ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []*models.Invite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+func (r *InviteDatabase) InviteByHash(codeHash string) (*models.Invite, error) {
+	invite, err := scanInvite(r.db.QueryRow("SELECT "+inviteColumns+" FROM invites WHERE code_hash = $1", codeHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return invite, err
+}
+
+// RedeemForUser checks and counts the use in one statement, so concurrent registrations
+// cannot overrun MaxUses.
+func (r *InviteDatabase) RedeemForUser(id int, now time.Time, user *models.User, audit *models.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	query := `UPDATE invites SET uses = uses + 1
+		WHERE id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2) AND (max_uses = 0 OR uses < max_uses)`
+	result, err := tx.Exec(query, id, now)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if affected != 1 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	insert := "INSERT INTO users (username, password, email, role, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	if err := tx.QueryRow(insert, user.Username, user.Password, user.Email, user.Role, user.CreatedAt).Scan(&user.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	audit.TargetUserID = user.ID
+	if err := insertAuditEntry(tx, audit); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *InviteDatabase) Revoke(id int, at time.Time) (bool, error) {
+	result, err := r.db.Exec("UPDATE invites SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", at, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func scanInvite(row rowScanner) (*models.Invite, error) {
+	invite := &models.Invite{}
+	var expiresAt, revokedAt sql.NullTime
+	if err := row.Scan(&invite.ID, &invite.Prefix, &invite.CodeHash, &invite.Role, &invite.Note, &invite.MaxUses, &invite.Uses, &expiresAt, &invite.CreatedBy, &invite.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		invite.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		invite.RevokedAt = &revokedAt.Time
+	}
+	return invite, nil
+}
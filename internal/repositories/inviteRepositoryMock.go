@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"sync"
+	"time"
+)
+
+// MockInviteStorage creates the users of redeemed invites in users, and records their audit
+// entries in its AuditLog, as the database does in the users and audit_log tables.
+type MockInviteStorage struct {
+	invites map[int]*models.Invite
+	users   *MockUserStorage
+	mu      sync.RWMutex
+	nextID  int
+}
+
+func NewMockInviteStorage(users *MockUserStorage) *MockInviteStorage {
+	return &MockInviteStorage{
+		invites: make(map[int]*models.Invite),
+		users:   users,
+		nextID:  1,
+	}
+}
+
+func (m *MockInviteStorage) Create(invite *models.Invite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invite.ID = m.nextID
+	m.nextID++
+	stored := *invite
+	m.invites[invite.ID] = &stored
+	return nil
+}
+
+func (m *MockInviteStorage) Invites() ([]*models.Invite, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	invites := []*models.Invite{}
+	for id := m.nextID - 1; id > 0; id-- {
+		if invite, ok := m.invites[id]; ok {
+			found := *invite
+			invites = append(invites, &found)
+		}
+	}
+	return invites, nil
+}
+
+func (m *MockInviteStorage) InviteByHash(codeHash string) (*models.Invite, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, invite := range m.invites {
+		if invite.CodeHash == codeHash {
+			found := *invite
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockInviteStorage) RedeemForUser(id int, now time.Time, user *models.User, audit *models.AuditEntry) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invite, ok := m.invites[id]
+	if !ok || !invite.Usable(now) {
+		return false, nil
+	}
+	if _, err := m.users.UserByUsername(user.Username); err == nil {
+		return false, errors.New("username already exists")
+	}
+	if err := m.users.Save(user); err != nil {
+		return false, err
+	}
+	audit.TargetUserID = user.ID
+	if err := m.users.AuditLog.Record(audit); err != nil {
+		return false, err
+	}
+	invite.Uses++
+	return true, nil
+}
+
+func (m *MockInviteStorage) Revoke(id int, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invite, ok := m.invites[id]
+	if !ok || invite.RevokedAt != nil {
+		return false, nil
+	}
+	invite.RevokedAt = &at
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"time"
+)
+
+type LoginAttemptStorage interface {
+	// ReserveAttempt counts an attempt for key before it is made. It returns false, and the
+	// current record, without counting anything when key is locked.
+	ReserveAttempt(key string, at time.Time, window time.Duration, lockout func(failures int) time.Duration) (*models.LoginAttempt, bool, error)
+	// ReleaseAttempt takes back a reserved attempt that did not fail, and lifts the lock when
+	// the count drops below threshold again.
+	ReleaseAttempt(key string, threshold int) error
+	Reset(key string) error
+}
+
+type LoginAttemptDatabase struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptDatabase(db *sql.DB) LoginAttemptStorage {
+	return &LoginAttemptDatabase{db}
+}
+
+// ReserveAttempt locks the row of key for the length of the transaction, so concurrent
+// attempts are counted one after the other and none of them can slip past a lock set by
+// another. The count starts over when the previous attempt is older than window. lockout
+// gives the lock to set for the new count, zero for none.
+func (r *LoginAttemptDatabase) ReserveAttempt(key string, at time.Time, window time.Duration, lockout func(failures int) time.Duration) (*models.LoginAttempt, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+
+	insert := `INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 0, $2)
+		ON CONFLICT (key) DO NOTHING`
+	if _, err := tx.Exec(insert, key, at); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+
+	query := "SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1 FOR UPDATE"
+	attempt, err := scanLoginAttempt(tx.QueryRow(query, key))
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(at) {
+		return attempt, false, tx.Commit()
+	}
+
+	if attempt.LastFailureAt.Before(at.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	attempt.LockedUntil = nil
+	if d := lockout(attempt.Failures); d > 0 {
+		until := at.Add(d)
+		attempt.LockedUntil = &until
+	}
+
+	update := "UPDATE login_attempts SET failures = $1, last_failure_at = $2, locked_until = $3 WHERE key = $4"
+	if _, err := tx.Exec(update, attempt.Failures, attempt.LastFailureAt, attempt.LockedUntil, key); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	return attempt, true, tx.Commit()
+}
+
+func (r *LoginAttemptDatabase) ReleaseAttempt(key string, threshold int) error {
+	query := `UPDATE login_attempts SET
+			failures = GREATEST(failures - 1, 0),
+			locked_until = CASE WHEN failures - 1 < $1 THEN NULL ELSE locked_until END
+		WHERE key = $2`
+	_, err := r.db.Exec(query, threshold, key)
+	return err
+}
+
+func (r *LoginAttemptDatabase) Reset(key string) error {
+	query := "DELETE FROM login_attempts WHERE key = $1"
+	_, err := r.db.Exec(query, key)
+	return err
+}
+
+func scanLoginAttempt(row rowScanner) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{}
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		attempt.LockedUntil = &lockedUntil.Time
+	}
+	return attempt, nil
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"louderspace/internal/models"
+	"sync"
+	"time"
+)
+
+type MockLoginAttemptStorage struct {
+	attempts map[string]*models.LoginAttempt
+	mu       sync.RWMutex
+}
+
+func NewMockLoginAttemptStorage() *MockLoginAttemptStorage {
+	return &MockLoginAttemptStorage{
+		attempts: make(map[string]*models.LoginAttempt),
+	}
+}
+
+func (m *MockLoginAttemptStorage) ReserveAttempt(key string, at time.Time, window time.Duration, lockout func(failures int) time.Duration) (*models.LoginAttempt, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, exists := m.attempts[key]
+	if !exists {
+		attempt = &models.LoginAttempt{Key: key, LastFailureAt: at}
+		m.attempts[key] = attempt
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(at) {
+		found := *attempt
+		return &found, false, nil
+	}
+	if attempt.LastFailureAt.Before(at.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	attempt.LockedUntil = nil
+	if d := lockout(attempt.Failures); d > 0 {
+		until := at.Add(d)
+		attempt.LockedUntil = &until
+	}
+	found := *attempt
+	return &found, true, nil
+}
+
+func (m *MockLoginAttemptStorage) ReleaseAttempt(key string, threshold int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, exists := m.attempts[key]
+	if !exists {
+		return nil
+	}
+	if attempt.Failures > 0 {
+		attempt.Failures--
+	}
+	if attempt.Failures < threshold {
+		attempt.LockedUntil = nil
+	}
+	return nil
+}
+
+func (m *MockLoginAttemptStorage) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
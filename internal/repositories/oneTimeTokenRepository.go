@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"time"
+)
+
+type OneTimeTokenStorage interface {
+	Create(token *models.OneTimeToken) error
+	ByHash(purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error)
+	MarkUsed(id int) (bool, error)
+	InvalidateForUser(userID int, purpose models.TokenPurpose) error
+}
+
+type OneTimeTokenDatabase struct {
+	db *sql.DB
+}
+
+func NewOneTimeTokenDatabase(db *sql.DB) OneTimeTokenStorage {
+	return &OneTimeTokenDatabase{db}
+}
+
+func (r *OneTimeTokenDatabase) Create(token *models.OneTimeToken) error {
+	query := "INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	return r.db.QueryRow(query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt, token.CreatedAt).Scan(&token.ID)
+}
+
+func (r *OneTimeTokenDatabase) ByHash(purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error) {
+	token := &models.OneTimeToken{}
+	var usedAt sql.NullTime
+	query := "SELECT id, user_id, purpose, token_hash, expires_at, created_at, used_at FROM one_time_tokens WHERE purpose = $1 AND token_hash = $2"
+	if err := r.db.QueryRow(query, purpose, tokenHash).Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &usedAt); err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+// MarkUsed consumes the token and reports whether this call did so.
+func (r *OneTimeTokenDatabase) MarkUsed(id int) (bool, error) {
+	query := "UPDATE one_time_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *OneTimeTokenDatabase) InvalidateForUser(userID int, purpose models.TokenPurpose) error {
+	query := "UPDATE one_time_tokens SET used_at = $1 WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL"
+	_, err := r.db.Exec(query, time.Now(), userID, purpose)
+	return err
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"sync"
+	"time"
+)
+
+type MockOneTimeTokenStorage struct {
+	tokens map[int]*models.OneTimeToken
+	nextID int
+	mu     sync.RWMutex
+}
+
+func NewMockOneTimeTokenStorage() *MockOneTimeTokenStorage {
+	return &MockOneTimeTokenStorage{
+		tokens: make(map[int]*models.OneTimeToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockOneTimeTokenStorage) Create(token *models.OneTimeToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	m.nextID++
+	stored := *token
+	m.tokens[token.ID] = &stored
+	return nil
+}
+
+func (m *MockOneTimeTokenStorage) ByHash(purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.tokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, errors.New("token not found")
+}
+
+func (m *MockOneTimeTokenStorage) MarkUsed(id int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, exists := m.tokens[id]
+	if !exists || token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (m *MockOneTimeTokenStorage) InvalidateForUser(userID int, purpose models.TokenPurpose) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
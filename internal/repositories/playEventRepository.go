@@ -8,6 +8,7 @@ import (
 type PlayEventStorage interface {
 	LogPlayEvent(event *models.PlayEvent) error
 	UpdateAggregates() error
+	EachPlayEventForUser(userID int, fn func(event *models.PlayEvent) error) error
 }
 
 type PlayEventDatabase struct {
@@ -50,3 +51,25 @@ func (r *PlayEventDatabase) UpdateAggregates() error {
 	_, err = tx.Exec(updateQuery)
 	return err
 }
+
+// EachPlayEventForUser calls fn for every play event of the user, oldest first, without
+// loading them all into memory. It stops at the first error fn returns.
+func (r *PlayEventDatabase) EachPlayEventForUser(userID int, fn func(event *models.PlayEvent) error) error {
+	query := "SELECT id, user_id, song_id, start_time, end_time, duration, created_at FROM play_events WHERE user_id = $1 ORDER BY start_time"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event := &models.PlayEvent{}
+		if err := rows.Scan(&event.ID, &event.UserID, &event.SongID, &event.StartTime, &event.EndTime, &event.Duration, &event.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
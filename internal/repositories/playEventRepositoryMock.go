@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"louderspace/internal/models"
+	"sync"
+)
+
+type MockPlayEventStorage struct {
+	Events []*models.PlayEvent
+	nextID int
+	mu     sync.RWMutex
+}
+
+func NewMockPlayEventStorage() *MockPlayEventStorage {
+	return &MockPlayEventStorage{nextID: 1}
+}
+
+func (m *MockPlayEventStorage) LogPlayEvent(event *models.PlayEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = m.nextID
+	m.nextID++
+	event.Duration = int(event.EndTime.Sub(event.StartTime).Seconds())
+	m.Events = append(m.Events, event)
+	return nil
+}
+
+func (m *MockPlayEventStorage) UpdateAggregates() error {
+	return nil
+}
+
+func (m *MockPlayEventStorage) EachPlayEventForUser(userID int, fn func(event *models.PlayEvent) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, event := range m.Events {
+		if event.UserID != userID {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ type PomodoroSessionStorage interface {
 	Update(session *models.PomodoroSession) error
 	ByID(id int) (*models.PomodoroSession, error)
 	ByUserID(userID int) ([]*models.PomodoroSession, error)
+	EachSessionForUser(userID int, fn func(session *models.PomodoroSession) error) error
 }
 
 type PomodoroSessionDatabase struct {
@@ -33,12 +34,8 @@ func (r *PomodoroSessionDatabase) Update(session *models.PomodoroSession) error
 }
 
 func (r *PomodoroSessionDatabase) ByID(id int) (*models.PomodoroSession, error) {
-	session := &models.PomodoroSession{}
 	query := "SELECT id, user_id, start_time, end_time, duration, break_duration, status FROM pomodoro_sessions WHERE id = $1"
-	if err := r.db.QueryRow(query, id).Scan(&session.ID, &session.UserID, &session.StartTime, &session.EndTime, &session.Duration, &session.BreakDuration, &session.Status); err != nil {
-		return nil, err
-	}
-	return session, nil
+	return scanPomodoroSession(r.db.QueryRow(query, id))
 }
 
 func (r *PomodoroSessionDatabase) ByUserID(userID int) ([]*models.PomodoroSession, error) {
@@ -51,11 +48,45 @@ func (r *PomodoroSessionDatabase) ByUserID(userID int) ([]*models.PomodoroSessio
 
 	var sessions []*models.PomodoroSession
 	for rows.Next() {
-		session := &models.PomodoroSession{}
-		if err := rows.Scan(&session.ID, &session.UserID, &session.StartTime, &session.EndTime, &session.Duration, &session.BreakDuration, &session.Status); err != nil {
+		session, err := scanPomodoroSession(rows)
+		if err != nil {
 			return nil, err
 		}
 		sessions = append(sessions, session)
 	}
 	return sessions, nil
 }
+
+// EachSessionForUser calls fn for every pomodoro session of the user, oldest first, without
+// loading them all into memory. It stops at the first error fn returns.
+func (r *PomodoroSessionDatabase) EachSessionForUser(userID int, fn func(session *models.PomodoroSession) error) error {
+	query := "SELECT id, user_id, start_time, end_time, duration, break_duration, status FROM pomodoro_sessions WHERE user_id = $1 ORDER BY id"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanPomodoroSession(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(session); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanPomodoroSession reads a session row. Sessions that are still running have no end time
+// yet and get a zero EndTime.
+func scanPomodoroSession(row rowScanner) (*models.PomodoroSession, error) {
+	session := &models.PomodoroSession{}
+	var endTime sql.NullTime
+	if err := row.Scan(&session.ID, &session.UserID, &session.StartTime, &endTime, &session.Duration, &session.BreakDuration, &session.Status); err != nil {
+		return nil, err
+	}
+	session.EndTime = endTime.Time
+	return session, nil
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockPomodoroSessionStorage struct {
+	sessions map[int]*models.PomodoroSession
+	nextID   int
+	mu       sync.RWMutex
+}
+
+func NewMockPomodoroSessionStorage() *MockPomodoroSessionStorage {
+	return &MockPomodoroSessionStorage{
+		sessions: make(map[int]*models.PomodoroSession),
+		nextID:   1,
+	}
+}
+
+func (m *MockPomodoroSessionStorage) Create(session *models.PomodoroSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session.ID = m.nextID
+	m.nextID++
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *MockPomodoroSessionStorage) Update(session *models.PomodoroSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.sessions[session.ID]; !exists {
+		return errors.New("session not found")
+	}
+	session.EndTime = time.Now()
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *MockPomodoroSessionStorage) ByID(id int) (*models.PomodoroSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, exists := m.sessions[id]
+	if !exists {
+		return nil, errors.New("session not found")
+	}
+	found := *session
+	return &found, nil
+}
+
+func (m *MockPomodoroSessionStorage) ByUserID(userID int) ([]*models.PomodoroSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []*models.PomodoroSession
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *MockPomodoroSessionStorage) EachSessionForUser(userID int, fn func(session *models.PomodoroSession) error) error {
+	sessions, _ := m.ByUserID(userID)
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	for _, session := range sessions {
+		found := *session
+		if err := fn(&found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
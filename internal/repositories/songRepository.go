@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
 	"louderspace/internal/models"
+	"strings"
 )
 
 type SongStorage interface {
@@ -10,12 +13,36 @@ type SongStorage interface {
 	Update(song *models.Song, tags []string) error
 	ByID(id int) (*models.Song, error)
 	BySunoID(sunoID string) (*models.Song, error)
+	// IDsBySunoIDs returns the IDs of the songs with the given suno_ids, keyed by suno_id.
+	// suno_ids without a song are left out.
+	IDsBySunoIDs(sunoIDs []string) (map[string]int, error)
 	All() ([]*models.Song, error)
+	// List returns up to filter.Limit songs matching the filter, with their tags and play
+	// counts, starting after filter.After.
+	List(filter models.SongFilter) ([]*models.Song, error)
+	// Search returns up to limit songs matching the search terms, best match first.
+	Search(terms []string, limit int) ([]*models.Song, error)
 	ByStationID(stationID int) ([]*models.Song, error)
+	// EachSong calls fn for every song matching the filter, in ID order and with its tags and
+	// play count, without loading them all into memory. It stops at the first error fn returns.
+	EachSong(filter models.CatalogFilter, fn func(song *models.Song) error) error
 	Delete(id int) error
 	GetTagsBySongID(songID int) ([]models.Tag, error)
 }
 
+// refreshSongSearchVector recomputes the full-text search vector of the songs selected by
+// the WHERE clause appended to it, from their title, artist, genre and tags.
+const refreshSongSearchVector = "UPDATE songs SET search_vector = song_search_vector(id)"
+
+// songColumns are read into the fields returned by songFields, in this order. Queries alias
+// the songs table as s.
+const songColumns = "s.id, s.title, s.artist, s.genre, s.suno_id, s.is_generated, s.created_at, s.duration_seconds, s.audio_url, s.image_url, s.image_large_url, s.model_version, s.bpm, s.musical_key, s.energy, s.lyrics"
+
+func songFields(song *models.Song) []interface{} {
+	return []interface{}{&song.ID, &song.Title, &song.Artist, &song.Genre, &song.SunoID, &song.IsGenerated, &song.CreatedAt,
+		&song.DurationSeconds, &song.AudioURL, &song.ImageURL, &song.ImageLargeURL, &song.ModelVersion, &song.BPM, &song.Key, &song.Energy, &song.Lyrics}
+}
+
 type SongDatabase struct {
 	db *sql.DB
 }
@@ -30,8 +57,10 @@ func (r *SongDatabase) Create(song *models.Song, tags []string) error {
 		return err
 	}
 
-	query := "INSERT INTO songs (title, artist, genre, suno_id, is_generated, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
-	err = tx.QueryRow(query, song.Title, song.Artist, song.Genre, song.SunoID, song.IsGenerated, song.CreatedAt).Scan(&song.ID)
+	query := `INSERT INTO songs (title, artist, genre, suno_id, is_generated, created_at, duration_seconds, audio_url, image_url, image_large_url, model_version, bpm, musical_key, energy, lyrics)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`
+	err = tx.QueryRow(query, song.Title, song.Artist, song.Genre, song.SunoID, song.IsGenerated, song.CreatedAt, song.DurationSeconds, song.AudioURL,
+		song.ImageURL, song.ImageLargeURL, song.ModelVersion, song.BPM, song.Key, song.Energy, song.Lyrics).Scan(&song.ID)
 	if err != nil {
 		err := tx.Rollback()
 		if err != nil {
@@ -51,6 +80,11 @@ func (r *SongDatabase) Create(song *models.Song, tags []string) error {
 		}
 	}
 
+	if _, err := tx.Exec(refreshSongSearchVector+" WHERE id = $1", song.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -73,8 +107,10 @@ func (r *SongDatabase) Update(song *models.Song, tags []string) error {
 		}
 	}()
 
-	query := "UPDATE songs SET title = $1, artist = $2, genre = $3, suno_id = $4, is_generated = $5 WHERE id = $6"
-	_, err = tx.Exec(query, song.Title, song.Artist, song.Genre, song.SunoID, song.IsGenerated, song.ID)
+	query := `UPDATE songs SET title = $1, artist = $2, genre = $3, suno_id = $4, is_generated = $5, duration_seconds = $6, audio_url = $7, image_url = $8,
+		image_large_url = $9, model_version = $10, bpm = $11, musical_key = $12, energy = $13, lyrics = $14 WHERE id = $15`
+	_, err = tx.Exec(query, song.Title, song.Artist, song.Genre, song.SunoID, song.IsGenerated, song.DurationSeconds, song.AudioURL, song.ImageURL,
+		song.ImageLargeURL, song.ModelVersion, song.BPM, song.Key, song.Energy, song.Lyrics, song.ID)
 	if err != nil {
 		return err
 	}
@@ -85,19 +121,20 @@ func (r *SongDatabase) Update(song *models.Song, tags []string) error {
 	}
 
 	for _, tag := range tags {
-		_, err := tx.Exec("INSERT INTO song_tags (song_id, tag_id) VALUES ($1, (SELECT id FROM tags WHERE name = $2))", song.ID, tag)
+		_, err = tx.Exec("INSERT INTO song_tags (song_id, tag_id) VALUES ($1, (SELECT id FROM tags WHERE name = $2))", song.ID, tag)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	_, err = tx.Exec(refreshSongSearchVector+" WHERE id = $1", song.ID)
+	return err
 }
 
 func (r *SongDatabase) ByID(id int) (*models.Song, error) {
 	song := &models.Song{}
-	query := "SELECT id, title, artist, genre, suno_id, is_generated, created_at FROM songs WHERE id = $1"
-	if err := r.db.QueryRow(query, id).Scan(&song.ID, &song.Title, &song.Artist, &song.Genre, &song.SunoID, &song.IsGenerated, &song.CreatedAt); err != nil {
+	query := "SELECT " + songColumns + " FROM songs s WHERE s.id = $1"
+	if err := r.db.QueryRow(query, id).Scan(songFields(song)...); err != nil {
 		return nil, err
 	}
 	return song, nil
@@ -105,16 +142,36 @@ func (r *SongDatabase) ByID(id int) (*models.Song, error) {
 
 func (r *SongDatabase) BySunoID(sunoID string) (*models.Song, error) {
 	song := &models.Song{}
-	query := "SELECT id, title, artist, genre, suno_id, is_generated, created_at FROM songs WHERE suno_id = $1"
-	if err := r.db.QueryRow(query, sunoID).Scan(&song.ID, &song.Title, &song.Artist, &song.Genre, &song.SunoID, &song.IsGenerated, &song.CreatedAt); err != nil {
+	query := "SELECT " + songColumns + " FROM songs s WHERE s.suno_id = $1"
+	if err := r.db.QueryRow(query, sunoID).Scan(songFields(song)...); err != nil {
 		return nil, err
 	}
 	return song, nil
 }
 
+func (r *SongDatabase) IDsBySunoIDs(sunoIDs []string) (map[string]int, error) {
+	query := "SELECT suno_id, id FROM songs WHERE suno_id = ANY($1)"
+	rows, err := r.db.Query(query, pq.Array(sunoIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var sunoID string
+		var id int
+		if err := rows.Scan(&sunoID, &id); err != nil {
+			return nil, err
+		}
+		ids[sunoID] = id
+	}
+	return ids, rows.Err()
+}
+
 func (r *SongDatabase) All() ([]*models.Song, error) {
 	query := `
-	SELECT s.id, s.title, s.artist, s.genre, s.suno_id, s.is_generated, s.created_at, t.id, t.name
+	SELECT ` + songColumns + `, t.id, t.name
 	FROM songs s
 	LEFT JOIN song_tags st ON s.id = st.song_id
 	LEFT JOIN tags t ON st.tag_id = t.id
@@ -135,7 +192,7 @@ func (r *SongDatabase) All() ([]*models.Song, error) {
 		var song models.Song
 		var tag models.Tag
 
-		err := rows.Scan(&song.ID, &song.Title, &song.Artist, &song.Genre, &song.SunoID, &song.IsGenerated, &song.CreatedAt, &tagID, &tag.Name)
+		err := rows.Scan(append(songFields(&song), &tagID, &tag.Name)...)
 		if err != nil {
 			return nil, err
 		}
@@ -163,6 +220,228 @@ func (r *SongDatabase) All() ([]*models.Song, error) {
 	return songs, nil
 }
 
+// songSortColumns maps the sort keys of models.SongFilter to expressions, so only known
+// columns end up in the ORDER BY clause.
+var songSortColumns = map[string]string{
+	models.SongSortID:        "s.id",
+	models.SongSortTitle:     "s.title",
+	models.SongSortCreatedAt: "s.created_at",
+	models.SongSortPlayCount: "COALESCE(pc.play_count, 0)",
+}
+
+func (r *SongDatabase) List(filter models.SongFilter) ([]*models.Song, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Tag != "" {
+		addCondition("EXISTS (SELECT 1 FROM song_tags st JOIN tags t ON st.tag_id = t.id WHERE st.song_id = s.id AND t.name = $%d)", filter.Tag)
+	}
+	if filter.Genre != "" {
+		addCondition("LOWER(s.genre) = LOWER($%d)", filter.Genre)
+	}
+	if filter.Artist != "" {
+		addCondition("s.artist ILIKE $%d", "%"+escapeLike(filter.Artist)+"%")
+	}
+	if filter.IsGenerated != nil {
+		addCondition("s.is_generated = $%d", *filter.IsGenerated)
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("s.created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("s.created_at < $%d", *filter.CreatedBefore)
+	}
+
+	column, ok := songSortColumns[filter.Sort]
+	if !ok {
+		column = "s.id"
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		var value interface{}
+		switch filter.Sort {
+		case models.SongSortTitle:
+			value = filter.After.Title
+		case models.SongSortCreatedAt:
+			value = filter.After.CreatedAt
+		case models.SongSortPlayCount:
+			value = filter.After.PlayCount
+		default:
+			value = filter.After.ID
+		}
+		args = append(args, value, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, s.id) %s ($%d, $%d)", column, comparison, len(args)-1, len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+		SELECT %s, COALESCE(pc.play_count, 0)
+		FROM songs s
+		LEFT JOIN song_play_counts pc ON pc.song_id = s.id
+		%s
+		ORDER BY %s %s, s.id %s
+		LIMIT $%d`, songColumns, where, column, direction, direction, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*models.Song
+	for rows.Next() {
+		song := &models.Song{}
+		if err := rows.Scan(append(songFields(song), &song.PlayCount)...); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return songs, r.loadTags(songs)
+}
+
+// loadTags sets the tags of the songs with one query.
+func (r *SongDatabase) loadTags(songs []*models.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	songsByID := make(map[int]*models.Song)
+	var ids []int64
+	for _, song := range songs {
+		songsByID[song.ID] = song
+		ids = append(ids, int64(song.ID))
+	}
+
+	rows, err := r.db.Query(`
+		SELECT st.song_id, t.id, t.name
+		FROM song_tags st
+		JOIN tags t ON st.tag_id = t.id
+		WHERE st.song_id = ANY($1)
+		ORDER BY t.name`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var songID int
+		var tag models.Tag
+		if err := rows.Scan(&songID, &tag.ID, &tag.Name); err != nil {
+			return err
+		}
+		songsByID[songID].Tags = append(songsByID[songID].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// Search matches every term against the title, artist, genre and tags, a term also matching
+// words it is a prefix of. Titles and artists that are only similar to the terms match too,
+// which lets misspelled searches find something. Songs are ranked by how well they match.
+func (r *SongDatabase) Search(terms []string, limit int) ([]*models.Song, error) {
+	query := `
+		SELECT ` + songColumns + `
+		FROM songs s, to_tsquery('simple', $1) query
+		WHERE s.search_vector @@ query OR $2 <% s.title OR $2 <% COALESCE(s.artist, '')
+		ORDER BY ts_rank(s.search_vector, query) + GREATEST(word_similarity($2, s.title), word_similarity($2, COALESCE(s.artist, ''))) DESC, s.id
+		LIMIT $3`
+	rows, err := r.db.Query(query, prefixTSQuery(terms), strings.Join(terms, " "), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*models.Song
+	for rows.Next() {
+		song := &models.Song{}
+		if err := rows.Scan(songFields(song)...); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return songs, r.loadTags(songs)
+}
+
+// prefixTSQuery builds a tsquery that matches words starting with every term. The terms
+// must be plain words, without tsquery operators.
+func prefixTSQuery(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " & ")
+}
+
+func (r *SongDatabase) EachSong(filter models.CatalogFilter, fn func(song *models.Song) error) error {
+	var conditions []string
+	var args []interface{}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM song_tags st JOIN tags t ON st.tag_id = t.id WHERE st.song_id = s.id AND t.name = $%d)", len(args)))
+	}
+	if filter.Station != nil {
+		// The same match as StationDatabase.SongsByTags, which picks what the station plays.
+		var genres []string
+		for _, tag := range filter.Station.Tags {
+			args = append(args, "%"+tag+"%")
+			genres = append(genres, fmt.Sprintf("s.genre ILIKE $%d", len(args)))
+		}
+		if len(genres) == 0 {
+			return nil
+		}
+		conditions = append(conditions, "("+strings.Join(genres, " OR ")+")")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := fmt.Sprintf(`
+		SELECT %s, COALESCE(pc.play_count, 0),
+			ARRAY(SELECT t.id FROM song_tags st JOIN tags t ON st.tag_id = t.id WHERE st.song_id = s.id ORDER BY t.name, t.id),
+			ARRAY(SELECT t.name FROM song_tags st JOIN tags t ON st.tag_id = t.id WHERE st.song_id = s.id ORDER BY t.name, t.id)
+		FROM songs s
+		LEFT JOIN song_play_counts pc ON pc.song_id = s.id
+		%s
+		ORDER BY s.id`, songColumns, where)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		song := &models.Song{}
+		var tagIDs []int64
+		var tagNames []string
+		if err := rows.Scan(append(songFields(song), &song.PlayCount, pq.Array(&tagIDs), pq.Array(&tagNames))...); err != nil {
+			return err
+		}
+		for i, id := range tagIDs {
+			song.Tags = append(song.Tags, models.Tag{ID: int(id), Name: tagNames[i]})
+		}
+		if err := fn(song); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (r *SongDatabase) ByStationID(stationID int) ([]*models.Song, error) {
 	var songs []*models.Song
 
@@ -181,13 +460,13 @@ func (r *SongDatabase) ByStationID(stationID int) ([]*models.Song, error) {
 
 	// Query to get songs with at least all the tags of the station
 	query := `
-		SELECT s.id, s.title, s.artist, s.genre, s.suno_id, s.is_generated, s.created_at
+		SELECT ` + songColumns + `
 		FROM songs s
 		JOIN song_tags st ON s.id = st.song_id
 		JOIN tags t ON st.tag_id = t.id
 		JOIN stations stn ON stn.tags LIKE '%' || t.name || '%'
 		WHERE stn.id = $1
-		GROUP BY s.id, s.title, s.artist, s.genre, s.suno_id, s.is_generated, s.created_at
+		GROUP BY s.id
 		HAVING COUNT(DISTINCT t.id) >= $2
 	`
 	rows, err := r.db.Query(query, stationID, stationTagsCount)
@@ -198,7 +477,7 @@ func (r *SongDatabase) ByStationID(stationID int) ([]*models.Song, error) {
 
 	for rows.Next() {
 		song := &models.Song{}
-		if err := rows.Scan(&song.ID, &song.Title, &song.Artist, &song.Genre, &song.SunoID, &song.IsGenerated, &song.CreatedAt); err != nil {
+		if err := rows.Scan(songFields(song)...); err != nil {
 			return nil, err
 		}
 
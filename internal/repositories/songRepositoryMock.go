@@ -3,24 +3,36 @@ package repositories
 import (
 	"errors"
 	"louderspace/internal/models"
+	"sort"
+	"strings"
 	"sync"
+	"unicode"
 )
 
 type SongStorageMock struct {
-	songs  map[int]*models.Song
-	tags   map[int][]models.Tag
-	nextID int
-	mu     sync.RWMutex
+	songs      map[int]*models.Song
+	tags       map[int][]models.Tag
+	playCounts map[int]int
+	nextID     int
+	mu         sync.RWMutex
 }
 
 func NewSongStorageMock() *SongStorageMock {
 	return &SongStorageMock{
-		songs:  make(map[int]*models.Song),
-		tags:   make(map[int][]models.Tag),
-		nextID: 1,
+		songs:      make(map[int]*models.Song),
+		tags:       make(map[int][]models.Tag),
+		playCounts: make(map[int]int),
+		nextID:     1,
 	}
 }
 
+// SetPlayCount stands in for the play event aggregation, which the mock does not have.
+func (s *SongStorageMock) SetPlayCount(songID, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.playCounts[songID] = count
+}
+
 func (s *SongStorageMock) Create(song *models.Song, tags []string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -82,6 +94,23 @@ func (s *SongStorageMock) BySunoID(sunoID string) (*models.Song, error) {
 	return nil, errors.New("song not found")
 }
 
+func (s *SongStorageMock) IDsBySunoIDs(sunoIDs []string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(sunoIDs))
+	for _, sunoID := range sunoIDs {
+		wanted[sunoID] = true
+	}
+	ids := make(map[string]int)
+	for _, song := range s.songs {
+		if wanted[song.SunoID] {
+			ids[song.SunoID] = song.ID
+		}
+	}
+	return ids, nil
+}
+
 func (s *SongStorageMock) All() ([]*models.Song, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -94,6 +123,170 @@ func (s *SongStorageMock) All() ([]*models.Song, error) {
 	return songs, nil
 }
 
+func (s *SongStorageMock) List(filter models.SongFilter) ([]*models.Song, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var songs []*models.Song
+	for _, stored := range s.songs {
+		song := *stored
+		song.Tags = s.tags[song.ID]
+		song.PlayCount = s.playCounts[song.ID]
+		if filter.Tag != "" && !containsTag(song.Tags, filter.Tag) ||
+			filter.Genre != "" && !strings.EqualFold(song.Genre, filter.Genre) ||
+			filter.Artist != "" && !strings.Contains(strings.ToLower(song.Artist), strings.ToLower(filter.Artist)) ||
+			filter.IsGenerated != nil && song.IsGenerated != *filter.IsGenerated ||
+			filter.CreatedAfter != nil && song.CreatedAt.Before(*filter.CreatedAfter) ||
+			filter.CreatedBefore != nil && !song.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		if filter.After != nil && !songLess(filter.After, &song, filter.Sort, filter.Descending) {
+			continue
+		}
+		songs = append(songs, &song)
+	}
+
+	sort.Slice(songs, func(i, j int) bool { return songLess(songs[i], songs[j], filter.Sort, filter.Descending) })
+	if len(songs) > filter.Limit {
+		songs = songs[:filter.Limit]
+	}
+	return songs, nil
+}
+
+func (s *SongStorageMock) EachSong(filter models.CatalogFilter, fn func(song *models.Song) error) error {
+	s.mu.RLock()
+	var songs []*models.Song
+	for _, stored := range s.songs {
+		song := *stored
+		song.Tags = s.tags[song.ID]
+		song.PlayCount = s.playCounts[song.ID]
+		if filter.Tag != "" && !containsTag(song.Tags, filter.Tag) {
+			continue
+		}
+		if filter.Station != nil {
+			plays := false
+			for _, tag := range filter.Station.Tags {
+				plays = plays || strings.Contains(strings.ToLower(song.Genre), strings.ToLower(tag))
+			}
+			if !plays {
+				continue
+			}
+		}
+		songs = append(songs, &song)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+	for _, song := range songs {
+		if err := fn(song); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// songLess orders songs like SongDatabase.List: by the sort field, then by ID.
+func songLess(a, b *models.Song, sortKey string, descending bool) bool {
+	var cmp int
+	switch sortKey {
+	case models.SongSortTitle:
+		cmp = strings.Compare(a.Title, b.Title)
+	case models.SongSortCreatedAt:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	case models.SongSortPlayCount:
+		cmp = a.PlayCount - b.PlayCount
+	}
+	if cmp == 0 {
+		cmp = a.ID - b.ID
+	}
+	if descending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+func (s *SongStorageMock) Search(terms []string, limit int) ([]*models.Song, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var songs []*models.Song
+	scores := make(map[int]int)
+	for _, stored := range s.songs {
+		fields := []string{stored.Title, stored.Artist, stored.Genre}
+		for _, tag := range s.tags[stored.ID] {
+			fields = append(fields, tag.Name)
+		}
+		if score := searchScore(terms, fields...); score > 0 {
+			song := *stored
+			song.Tags = s.tags[song.ID]
+			songs = append(songs, &song)
+			scores[song.ID] = score
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		if scores[songs[i].ID] != scores[songs[j].ID] {
+			return scores[songs[i].ID] > scores[songs[j].ID]
+		}
+		return songs[i].ID < songs[j].ID
+	})
+	if len(songs) > limit {
+		songs = songs[:limit]
+	}
+	return songs, nil
+}
+
+// searchScore stands in for the full-text ranking of the databases. Every term has to match
+// a word of the fields: exactly, as its prefix or, for terms of four or more letters, with one
+// typo. It returns 0 if a term does not match and more the closer the matches are.
+func searchScore(terms []string, fields ...string) int {
+	var words []string
+	for _, field := range fields {
+		words = append(words, strings.FieldsFunc(strings.ToLower(field), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	total := 0
+	for _, term := range terms {
+		best := 0
+		for _, word := range words {
+			switch {
+			case word == term:
+				best = 3
+			case strings.HasPrefix(word, term) && best < 2:
+				best = 2
+			case len(term) >= 4 && editDistance(term, word) <= 1 && best < 1:
+				best = 1
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
 func (s *SongStorageMock) ByStationID(stationID int) ([]*models.Song, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -13,7 +13,15 @@ type StationStorage interface {
 	Delete(stationID int) error
 	ByID(stationID int) (*models.Station, error)
 	All() ([]*models.Station, error)
+	// Accessible returns the shared stations plus the user's personal ones.
+	Accessible(userID int) ([]*models.Station, error)
+	// CreatePersonal creates the station unless its owner already has limit personal stations,
+	// reporting whether it did.
+	CreatePersonal(station *models.Station, limit int) (bool, error)
 	SongsByTags(tags []string) ([]*models.Song, error)
+	// Search returns up to limit of the stations accessible to the user that match the
+	// search terms, best match first.
+	Search(terms []string, userID int, limit int) ([]*models.Station, error)
 }
 
 type StationDatabase struct {
@@ -25,13 +33,13 @@ func NewStationDatabase(db *sql.DB) StationStorage {
 }
 
 func (r *StationDatabase) Create(station *models.Station) error {
-	query := "INSERT INTO stations (name, tags) VALUES ($1, $2) RETURNING id"
-	return r.db.QueryRow(query, station.Name, strings.Join(station.Tags, ",")).Scan(&station.ID)
+	query := "INSERT INTO stations (name, tags, premium, owner_id) VALUES ($1, $2, $3, $4) RETURNING id"
+	return r.db.QueryRow(query, station.Name, strings.Join(station.Tags, ","), station.Premium, station.OwnerID).Scan(&station.ID)
 }
 
 func (r *StationDatabase) Update(station *models.Station) error {
-	query := "UPDATE stations SET name=$1, tags=$2 WHERE id=$3"
-	_, err := r.db.Exec(query, station.Name, strings.Join(station.Tags, ","), station.ID)
+	query := "UPDATE stations SET name=$1, tags=$2, premium=$3 WHERE id=$4"
+	_, err := r.db.Exec(query, station.Name, strings.Join(station.Tags, ","), station.Premium, station.ID)
 	return err
 }
 
@@ -42,44 +50,99 @@ func (r *StationDatabase) Delete(stationID int) error {
 }
 
 func (r *StationDatabase) ByID(stationID int) (*models.Station, error) {
-	station := &models.Station{}
-	var tags string
-	query := "SELECT id, name, tags FROM stations WHERE id=$1"
-	if err := r.db.QueryRow(query, stationID).Scan(&station.ID, &station.Name, &tags); err != nil {
-		return nil, err
-	}
-	station.Tags = strings.Split(tags, ",")
-	return station, nil
+	query := "SELECT id, name, tags, premium, owner_id FROM stations WHERE id=$1"
+	return scanStation(r.db.QueryRow(query, stationID))
 }
 
 func (r *StationDatabase) All() ([]*models.Station, error) {
+	return r.queryStations("SELECT id, name, tags, premium, owner_id FROM stations")
+}
+
+func (r *StationDatabase) Accessible(userID int) ([]*models.Station, error) {
+	return r.queryStations("SELECT id, name, tags, premium, owner_id FROM stations WHERE owner_id IS NULL OR owner_id=$1", userID)
+}
+
+func (r *StationDatabase) CreatePersonal(station *models.Station, limit int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	// Locking the owner makes concurrent creations for the same user count one after another.
+	if _, err := tx.Exec("SELECT id FROM users WHERE id=$1 FOR UPDATE", *station.OwnerID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if limit != models.Unlimited {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM stations WHERE owner_id=$1", *station.OwnerID).Scan(&count); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if count >= limit {
+			tx.Rollback()
+			return false, nil
+		}
+	}
+	query := "INSERT INTO stations (name, tags, premium, owner_id) VALUES ($1, $2, $3, $4) RETURNING id"
+	if err := tx.QueryRow(query, station.Name, strings.Join(station.Tags, ","), station.Premium, station.OwnerID).Scan(&station.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Search matches the terms like SongDatabase.Search, against the station's name and tags.
+func (r *StationDatabase) Search(terms []string, userID int, limit int) ([]*models.Station, error) {
+	query := `
+		SELECT s.id, s.name, s.tags, s.premium, s.owner_id
+		FROM stations s, to_tsquery('simple', $1) query
+		WHERE (s.owner_id IS NULL OR s.owner_id = $3) AND (s.search_vector @@ query OR $2 <% s.name)
+		ORDER BY ts_rank(s.search_vector, query) + word_similarity($2, s.name) DESC, s.id
+		LIMIT $4`
+	return r.queryStations(query, prefixTSQuery(terms), strings.Join(terms, " "), userID, limit)
+}
+
+func (r *StationDatabase) queryStations(query string, args ...interface{}) ([]*models.Station, error) {
 	var stations []*models.Station
-	rows, err := r.db.Query("SELECT id, name, tags FROM stations")
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		station := &models.Station{}
-		var tags string
-		if err := rows.Scan(&station.ID, &station.Name, &tags); err != nil {
+		station, err := scanStation(rows)
+		if err != nil {
 			return nil, err
 		}
-		station.Tags = strings.Split(tags, ",")
 		stations = append(stations, station)
 	}
 
 	return stations, nil
 }
 
+func scanStation(row rowScanner) (*models.Station, error) {
+	station := &models.Station{}
+	var tags string
+	var ownerID sql.NullInt64
+	if err := row.Scan(&station.ID, &station.Name, &tags, &station.Premium, &ownerID); err != nil {
+		return nil, err
+	}
+	station.Tags = strings.Split(tags, ",")
+	if ownerID.Valid {
+		id := int(ownerID.Int64)
+		station.OwnerID = &id
+	}
+	return station, nil
+}
+
 func (r *StationDatabase) SongsByTags(tags []string) ([]*models.Song, error) {
 	var songs []*models.Song
-	query := "SELECT id, title, artist, genre, suno_id, is_generated, created_at FROM songs WHERE"
+	query := "SELECT " + songColumns + " FROM songs s WHERE"
 	var conditions []string
 	var args []interface{}
 	for i, tag := range tags {
-		conditions = append(conditions, fmt.Sprintf("genre ILIKE $%d", i+1))
+		conditions = append(conditions, fmt.Sprintf("s.genre ILIKE $%d", i+1))
 		args = append(args, "%"+tag+"%")
 	}
 	query += " " + strings.Join(conditions, " OR ")
@@ -92,7 +155,7 @@ func (r *StationDatabase) SongsByTags(tags []string) ([]*models.Song, error) {
 
 	for rows.Next() {
 		song := &models.Song{}
-		if err := rows.Scan(&song.ID, &song.Title, &song.Artist, &song.Genre, &song.SunoID, &song.IsGenerated, &song.CreatedAt); err != nil {
+		if err := rows.Scan(songFields(song)...); err != nil {
 			return nil, err
 		}
 		songs = append(songs, song)
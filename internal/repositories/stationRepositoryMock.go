@@ -1,8 +1,9 @@
 package repositories
 
 import (
-	"errors"
+	"database/sql"
 	"louderspace/internal/models"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -36,7 +37,7 @@ func (t *StationStorageMock) Update(station *models.Station) error {
 	defer t.mu.Unlock()
 
 	if _, exists := t.stations[station.ID]; !exists {
-		return errors.New("station not found")
+		return sql.ErrNoRows
 	}
 
 	t.stations[station.ID] = station
@@ -48,7 +49,7 @@ func (t *StationStorageMock) Delete(stationID int) error {
 	defer t.mu.Unlock()
 
 	if _, exists := t.stations[stationID]; !exists {
-		return errors.New("station not found")
+		return sql.ErrNoRows
 	}
 
 	delete(t.stations, stationID)
@@ -61,7 +62,7 @@ func (t *StationStorageMock) ByID(stationID int) (*models.Station, error) {
 
 	station, exists := t.stations[stationID]
 	if !exists {
-		return nil, errors.New("station not found")
+		return nil, sql.ErrNoRows
 	}
 
 	return station, nil
@@ -79,6 +80,40 @@ func (t *StationStorageMock) All() ([]*models.Station, error) {
 	return stations, nil
 }
 
+func (t *StationStorageMock) Accessible(userID int) ([]*models.Station, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var stations []*models.Station
+	for _, station := range t.stations {
+		if station.AccessibleBy(userID) {
+			stations = append(stations, station)
+		}
+	}
+
+	return stations, nil
+}
+
+func (t *StationStorageMock) CreatePersonal(station *models.Station, limit int) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := 0
+	for _, existing := range t.stations {
+		if existing.OwnerID != nil && *existing.OwnerID == *station.OwnerID {
+			count++
+		}
+	}
+	if limit != models.Unlimited && count >= limit {
+		return false, nil
+	}
+
+	station.ID = t.nextID
+	t.nextID++
+	t.stations[station.ID] = station
+	return true, nil
+}
+
 func (t *StationStorageMock) SongsByTags(tags []string) ([]*models.Song, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -97,6 +132,33 @@ func (t *StationStorageMock) SongsByTags(tags []string) ([]*models.Song, error)
 	return matchedSongs, nil
 }
 
+func (t *StationStorageMock) Search(terms []string, userID int, limit int) ([]*models.Station, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var stations []*models.Station
+	scores := make(map[int]int)
+	for _, station := range t.stations {
+		if !station.AccessibleBy(userID) {
+			continue
+		}
+		if score := searchScore(terms, append([]string{station.Name}, station.Tags...)...); score > 0 {
+			stations = append(stations, station)
+			scores[station.ID] = score
+		}
+	}
+	sort.Slice(stations, func(i, j int) bool {
+		if scores[stations[i].ID] != scores[stations[j].ID] {
+			return scores[stations[i].ID] > scores[stations[j].ID]
+		}
+		return stations[i].ID < stations[j].ID
+	})
+	if len(stations) > limit {
+		stations = stations[:limit]
+	}
+	return stations, nil
+}
+
 func contains(slice []string, item string) bool {
 	for _, v := range slice {
 		if v == item {
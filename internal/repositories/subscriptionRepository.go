@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"time"
+)
+
+type SubscriptionStorage interface {
+	Create(subscription *models.Subscription) error
+	Update(subscription *models.Subscription) error
+	// SubscriptionByProviderID returns the subscription with the provider's ID, or nil when
+	// there is none.
+	SubscriptionByProviderID(providerSubscriptionID string) (*models.Subscription, error)
+	// SubscriptionForUser returns the user's newest subscription, or nil when there is none.
+	SubscriptionForUser(userID int) (*models.Subscription, error)
+	// Lapsed returns the subscriptions whose premium access ran out by now and has not been
+	// ended yet.
+	Lapsed(now time.Time) ([]*models.Subscription, error)
+	// RecordEvent remembers a provider event and reports false if it was recorded before.
+	RecordEvent(eventID, eventType string, at time.Time) (bool, error)
+	// ForgetEvent drops a recorded event whose handling failed, so a redelivery is handled.
+	ForgetEvent(eventID string) error
+}
+
+type SubscriptionDatabase struct {
+	db *sql.DB
+}
+
+func NewSubscriptionDatabase(db *sql.DB) SubscriptionStorage {
+	return &SubscriptionDatabase{db}
+}
+
+const subscriptionColumns = "id, user_id, provider_subscription_id, provider_customer_id, status, current_period_end, premium_until, canceled_at, ended_at, last_event_at, created_at, updated_at"
+
+func (r *SubscriptionDatabase) Create(subscription *models.Subscription) error {
+	query := `INSERT INTO subscriptions (user_id, provider_subscription_id, provider_customer_id, status, current_period_end, premium_until, canceled_at, ended_at, last_event_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	return r.db.QueryRow(query, subscription.UserID, subscription.ProviderSubscriptionID, subscription.ProviderCustomerID, subscription.Status,
+		subscription.CurrentPeriodEnd, subscription.PremiumUntil, subscription.CanceledAt, subscription.EndedAt, subscription.LastEventAt,
+		subscription.CreatedAt, subscription.UpdatedAt).Scan(&subscription.ID)
+}
+
+func (r *SubscriptionDatabase) Update(subscription *models.Subscription) error {
+	query := `UPDATE subscriptions SET status = $1, current_period_end = $2, premium_until = $3, canceled_at = $4, ended_at = $5, last_event_at = $6, updated_at = $7
+		WHERE id = $8`
+	_, err := r.db.Exec(query, subscription.Status, subscription.CurrentPeriodEnd, subscription.PremiumUntil, subscription.CanceledAt,
+		subscription.EndedAt, subscription.LastEventAt, subscription.UpdatedAt, subscription.ID)
+	return err
+}
+
+func (r *SubscriptionDatabase) SubscriptionByProviderID(providerSubscriptionID string) (*models.Subscription, error) {
+	subscription, err := scanSubscription(r.db.QueryRow("SELECT "+subscriptionColumns+" FROM subscriptions WHERE provider_subscription_id = $1", providerSubscriptionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return subscription, err
+}
+
+func (r *SubscriptionDatabase) SubscriptionForUser(userID int) (*models.Subscription, error) {
+	subscription, err := scanSubscription(r.db.QueryRow("SELECT "+subscriptionColumns+" FROM subscriptions WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1", userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return subscription, err
+}
+
+func (r *SubscriptionDatabase) Lapsed(now time.Time) ([]*models.Subscription, error) {
+	rows, err := r.db.Query("SELECT "+subscriptionColumns+" FROM subscriptions WHERE ended_at IS NULL AND premium_until <= $1 ORDER BY premium_until", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*models.Subscription
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *SubscriptionDatabase) RecordEvent(eventID, eventType string, at time.Time) (bool, error) {
+	result, err := r.db.Exec("INSERT INTO billing_events (id, type, received_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING", eventID, eventType, at)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *SubscriptionDatabase) ForgetEvent(eventID string) error {
+	_, err := r.db.Exec("DELETE FROM billing_events WHERE id = $1", eventID)
+	return err
+}
+
+func scanSubscription(row rowScanner) (*models.Subscription, error) {
+	subscription := &models.Subscription{}
+	var canceledAt, endedAt sql.NullTime
+	if err := row.Scan(&subscription.ID, &subscription.UserID, &subscription.ProviderSubscriptionID, &subscription.ProviderCustomerID, &subscription.Status,
+		&subscription.CurrentPeriodEnd, &subscription.PremiumUntil, &canceledAt, &endedAt, &subscription.LastEventAt,
+		&subscription.CreatedAt, &subscription.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if canceledAt.Valid {
+		subscription.CanceledAt = &canceledAt.Time
+	}
+	if endedAt.Valid {
+		subscription.EndedAt = &endedAt.Time
+	}
+	return subscription, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockSubscriptionStorage struct {
+	subscriptions map[int]*models.Subscription
+	events        map[string]bool
+	mu            sync.RWMutex
+	nextID        int
+}
+
+func NewMockSubscriptionStorage() *MockSubscriptionStorage {
+	return &MockSubscriptionStorage{
+		subscriptions: make(map[int]*models.Subscription),
+		events:        make(map[string]bool),
+		nextID:        1,
+	}
+}
+
+func (m *MockSubscriptionStorage) Create(subscription *models.Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.subscriptions {
+		if existing.ProviderSubscriptionID == subscription.ProviderSubscriptionID {
+			return errors.New("duplicate provider subscription id")
+		}
+	}
+	subscription.ID = m.nextID
+	m.nextID++
+	stored := *subscription
+	m.subscriptions[subscription.ID] = &stored
+	return nil
+}
+
+func (m *MockSubscriptionStorage) Update(subscription *models.Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.subscriptions[subscription.ID]; !ok {
+		return errors.New("subscription not found")
+	}
+	stored := *subscription
+	m.subscriptions[subscription.ID] = &stored
+	return nil
+}
+
+func (m *MockSubscriptionStorage) SubscriptionByProviderID(providerSubscriptionID string) (*models.Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, subscription := range m.subscriptions {
+		if subscription.ProviderSubscriptionID == providerSubscriptionID {
+			found := *subscription
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockSubscriptionStorage) SubscriptionForUser(userID int) (*models.Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for id := m.nextID - 1; id > 0; id-- {
+		if subscription, ok := m.subscriptions[id]; ok && subscription.UserID == userID {
+			found := *subscription
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockSubscriptionStorage) Lapsed(now time.Time) ([]*models.Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var lapsed []*models.Subscription
+	for _, subscription := range m.subscriptions {
+		if subscription.EndedAt == nil && !subscription.PremiumUntil.After(now) {
+			found := *subscription
+			lapsed = append(lapsed, &found)
+		}
+	}
+	sort.Slice(lapsed, func(i, j int) bool { return lapsed[i].PremiumUntil.Before(lapsed[j].PremiumUntil) })
+	return lapsed, nil
+}
+
+func (m *MockSubscriptionStorage) RecordEvent(eventID, eventType string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.events[eventID] {
+		return false, nil
+	}
+	m.events[eventID] = true
+	return true, nil
+}
+
+func (m *MockSubscriptionStorage) ForgetEvent(eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.events, eventID)
+	return nil
+}
@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"database/sql"
+	"github.com/lib/pq"
 	"louderspace/internal/models"
+	"strings"
 )
 
 type TagStorage interface {
@@ -10,6 +12,9 @@ type TagStorage interface {
 	Create(tag *models.Tag) error
 	Update(tag *models.Tag) error
 	Delete(id int) error
+	// Search returns up to limit tags starting with or similar to the search terms, best
+	// match first.
+	Search(terms []string, limit int) ([]*models.Tag, error)
 }
 
 type TagDatabase struct {
@@ -39,15 +44,53 @@ func (r *TagDatabase) GetAllTags() ([]*models.Tag, error) {
 	return tags, nil
 }
 
+func (r *TagDatabase) Search(terms []string, limit int) ([]*models.Tag, error) {
+	text := strings.Join(terms, " ")
+	query := `
+		SELECT id, name
+		FROM tags
+		WHERE name ILIKE $1 OR $2 % name
+		ORDER BY LOWER(name) = $2 DESC, name ILIKE $1 DESC, similarity($2, name) DESC, name
+		LIMIT $3`
+	rows, err := r.db.Query(query, escapeLike(text)+"%", text, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	return tags, rows.Err()
+}
+
 func (r *TagDatabase) Create(tag *models.Tag) error {
 	query := "INSERT INTO tags (name) VALUES ($1) RETURNING id"
 	return r.db.QueryRow(query, tag.Name).Scan(&tag.ID)
 }
 
+// Update renames the tag and refreshes the search vectors of the songs tagged with it.
 func (r *TagDatabase) Update(tag *models.Tag) error {
-	query := "UPDATE tags SET name = $1 WHERE id = $2"
-	_, err := r.db.Exec(query, tag.Name, tag.ID)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE tags SET name = $1 WHERE id = $2", tag.Name, tag.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(refreshSongSearchVector+" WHERE id IN (SELECT song_id FROM song_tags WHERE tag_id = $1)", tag.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *TagDatabase) Delete(id int) error {
@@ -57,7 +100,29 @@ func (r *TagDatabase) Delete(id int) error {
 	}
 
 	// Clean up related data in the song_tags table
-	_, err = tx.Exec("DELETE FROM song_tags WHERE tag_id = $1", id)
+	rows, err := tx.Query("DELETE FROM song_tags WHERE tag_id = $1 RETURNING song_id", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var songIDs []int64
+	for rows.Next() {
+		var songID int64
+		if err := rows.Scan(&songID); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		songIDs = append(songIDs, songID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The songs no longer match the tag in search
+	_, err = tx.Exec(refreshSongSearchVector+" WHERE id = ANY($1)", pq.Array(songIDs))
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -3,6 +3,7 @@ package repositories
 import (
 	"errors"
 	"louderspace/internal/models"
+	"sort"
 	"sync"
 )
 
@@ -63,3 +64,27 @@ func (m *MockTagStorage) Delete(id int) error {
 	delete(m.tags, id)
 	return nil
 }
+
+func (m *MockTagStorage) Search(terms []string, limit int) ([]*models.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tags []*models.Tag
+	scores := make(map[int]int)
+	for _, tag := range m.tags {
+		if score := searchScore(terms, tag.Name); score > 0 {
+			tags = append(tags, tag)
+			scores[tag.ID] = score
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if scores[tags[i].ID] != scores[tags[j].ID] {
+			return scores[tags[i].ID] > scores[tags[j].ID]
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"time"
+)
+
+type TokenStorage interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	RefreshTokenByHash(tokenHash string) (*models.RefreshToken, error)
+	RevokeRefreshToken(id int) (bool, error)
+	RevokeRefreshTokensForUser(userID int) error
+	RevokeAccessToken(jti string, userID int, expiresAt time.Time) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	// BumpTokenGeneration records that every token issued to the user so far is revoked.
+	BumpTokenGeneration(userID int, revokedAt time.Time) error
+	// TokenGeneration is 0 until the user's tokens are first revoked.
+	TokenGeneration(userID int) (int, error)
+
+	CreateSession(session *models.Session) error
+	Session(id int) (*models.Session, error)
+	// ActiveSessions lists the user's sessions that have not been revoked, most recently
+	// used first.
+	ActiveSessions(userID int) ([]*models.Session, error)
+	SetSessionAccessToken(id int, jti string) error
+	TouchSession(id int, ip string, at time.Time) error
+	// RevokeSession revokes the session and its refresh tokens and reports whether the
+	// session belonged to the user and was still active.
+	RevokeSession(id, userID int) (bool, error)
+	RevokeSessionsForUser(userID int) error
+}
+
+type TokenDatabase struct {
+	db *sql.DB
+}
+
+func NewTokenDatabase(db *sql.DB) TokenStorage {
+	return &TokenDatabase{db}
+}
+
+func (r *TokenDatabase) CreateRefreshToken(token *models.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	return r.db.QueryRow(query, token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt, token.CreatedAt).Scan(&token.ID)
+}
+
+func (r *TokenDatabase) RefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	var revokedAt sql.NullTime
+	var sessionID sql.NullInt64
+	query := "SELECT id, user_id, session_id, token_hash, expires_at, created_at, revoked_at FROM refresh_tokens WHERE token_hash = $1"
+	if err := r.db.QueryRow(query, tokenHash).Scan(&token.ID, &token.UserID, &sessionID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	token.SessionID = int(sessionID.Int64)
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, nil
+}
+
+// RevokeRefreshToken marks a refresh token as revoked and reports whether this call did so,
+// which lets callers detect a token being rotated twice.
+func (r *TokenDatabase) RevokeRefreshToken(id int) (bool, error) {
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *TokenDatabase) RevokeRefreshTokensForUser(userID int) error {
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+	_, err := r.db.Exec(query, time.Now(), userID)
+	return err
+}
+
+func (r *TokenDatabase) RevokeAccessToken(jti string, userID int, expiresAt time.Time) error {
+	query := "INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING"
+	_, err := r.db.Exec(query, jti, userID, expiresAt)
+	return err
+}
+
+func (r *TokenDatabase) IsAccessTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	query := "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)"
+	if err := r.db.QueryRow(query, jti).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *TokenDatabase) BumpTokenGeneration(userID int, revokedAt time.Time) error {
+	query := `INSERT INTO user_token_revocations (user_id, revoked_at, generation) VALUES ($1, $2, 1)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_at = $2, generation = user_token_revocations.generation + 1`
+	_, err := r.db.Exec(query, userID, revokedAt)
+	return err
+}
+
+func (r *TokenDatabase) TokenGeneration(userID int) (int, error) {
+	var generation int
+	query := "SELECT generation FROM user_token_revocations WHERE user_id = $1"
+	err := r.db.QueryRow(query, userID).Scan(&generation)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return generation, nil
+}
+
+func (r *TokenDatabase) CreateSession(session *models.Session) error {
+	query := "INSERT INTO sessions (user_id, device_name, user_agent, ip, created_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	return r.db.QueryRow(query, session.UserID, session.DeviceName, session.UserAgent, session.IP, session.CreatedAt, session.LastSeenAt).Scan(&session.ID)
+}
+
+func (r *TokenDatabase) Session(id int) (*models.Session, error) {
+	query := "SELECT id, user_id, device_name, user_agent, ip, access_jti, created_at, last_seen_at, revoked_at FROM sessions WHERE id = $1"
+	return scanSession(r.db.QueryRow(query, id))
+}
+
+func (r *TokenDatabase) ActiveSessions(userID int) ([]*models.Session, error) {
+	query := "SELECT id, user_id, device_name, user_agent, ip, access_jti, created_at, last_seen_at, revoked_at FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *TokenDatabase) SetSessionAccessToken(id int, jti string) error {
+	query := "UPDATE sessions SET access_jti = $1 WHERE id = $2"
+	_, err := r.db.Exec(query, jti, id)
+	return err
+}
+
+func (r *TokenDatabase) TouchSession(id int, ip string, at time.Time) error {
+	query := "UPDATE sessions SET last_seen_at = $1, ip = $2 WHERE id = $3"
+	_, err := r.db.Exec(query, at, ip, id)
+	return err
+}
+
+func (r *TokenDatabase) RevokeSession(id, userID int) (revoked bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	now := time.Now()
+	result, err := tx.Exec("UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL", now, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if _, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = $1 WHERE session_id = $2 AND revoked_at IS NULL", now, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *TokenDatabase) RevokeSessionsForUser(userID int) error {
+	query := "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+	_, err := r.db.Exec(query, time.Now(), userID)
+	return err
+}
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	session := &models.Session{}
+	var revokedAt sql.NullTime
+	if err := row.Scan(&session.ID, &session.UserID, &session.DeviceName, &session.UserAgent, &session.IP, &session.AccessTokenID, &session.CreatedAt, &session.LastSeenAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"database/sql"
+	"louderspace/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockTokenStorage struct {
+	refreshTokens    map[int]*models.RefreshToken
+	revokedJTIs      map[string]time.Time
+	tokenGenerations map[int]int
+	sessions         map[int]*models.Session
+	nextID           int
+	nextSessionID    int
+	mu               sync.RWMutex
+}
+
+func NewMockTokenStorage() *MockTokenStorage {
+	return &MockTokenStorage{
+		refreshTokens:    make(map[int]*models.RefreshToken),
+		revokedJTIs:      make(map[string]time.Time),
+		tokenGenerations: make(map[int]int),
+		sessions:         make(map[int]*models.Session),
+		nextID:           1,
+		nextSessionID:    1,
+	}
+}
+
+func (m *MockTokenStorage) CreateRefreshToken(token *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	m.nextID++
+	stored := *token
+	m.refreshTokens[token.ID] = &stored
+	return nil
+}
+
+func (m *MockTokenStorage) RefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.refreshTokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockTokenStorage) RevokeRefreshToken(id int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, exists := m.refreshTokens[id]
+	if !exists || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return true, nil
+}
+
+func (m *MockTokenStorage) RevokeRefreshTokensForUser(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MockTokenStorage) RevokeAccessToken(jti string, userID int, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokedJTIs[jti] = expiresAt
+	return nil
+}
+
+func (m *MockTokenStorage) IsAccessTokenRevoked(jti string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, revoked := m.revokedJTIs[jti]
+	return revoked, nil
+}
+
+func (m *MockTokenStorage) BumpTokenGeneration(userID int, revokedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokenGenerations[userID]++
+	return nil
+}
+
+func (m *MockTokenStorage) TokenGeneration(userID int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.tokenGenerations[userID], nil
+}
+
+func (m *MockTokenStorage) CreateSession(session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session.ID = m.nextSessionID
+	m.nextSessionID++
+	stored := *session
+	m.sessions[session.ID] = &stored
+	return nil
+}
+
+func (m *MockTokenStorage) Session(id int) (*models.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, exists := m.sessions[id]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	found := *session
+	return &found, nil
+}
+
+func (m *MockTokenStorage) ActiveSessions(userID int) ([]*models.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []*models.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+func (m *MockTokenStorage) SetSessionAccessToken(id int, jti string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, exists := m.sessions[id]; exists {
+		session.AccessTokenID = jti
+	}
+	return nil
+}
+
+func (m *MockTokenStorage) TouchSession(id int, ip string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, exists := m.sessions[id]; exists {
+		session.LastSeenAt = at
+		session.IP = ip
+	}
+	return nil
+}
+
+func (m *MockTokenStorage) RevokeSession(id, userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, exists := m.sessions[id]
+	if !exists || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	for _, token := range m.refreshTokens {
+		if token.SessionID == id && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return true, nil
+}
+
+func (m *MockTokenStorage) RevokeSessionsForUser(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
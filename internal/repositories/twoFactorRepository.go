@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"louderspace/internal/models"
+	"time"
+)
+
+type TwoFactorStorage interface {
+	// TwoFactor returns the user's enrollment, or nil when there is none.
+	TwoFactor(userID int) (*models.TwoFactor, error)
+	// SavePending stores a new secret that is not enabled yet, replacing any earlier one.
+	SavePending(twoFactor *models.TwoFactor) error
+	Enable(userID int, at time.Time) error
+	// Disable removes the enrollment and its recovery codes.
+	Disable(userID int) error
+	// UseStep records that a code for step was accepted and reports whether step is newer
+	// than the last one used.
+	UseStep(userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	// UseRecoveryCode consumes a recovery code and reports whether it was valid and unused.
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	RequiredRoles() ([]models.Role, error)
+	SetRoleRequired(role models.Role, required bool) error
+}
+
+type TwoFactorDatabase struct {
+	db *sql.DB
+}
+
+func NewTwoFactorDatabase(db *sql.DB) TwoFactorStorage {
+	return &TwoFactorDatabase{db}
+}
+
+func (r *TwoFactorDatabase) TwoFactor(userID int) (*models.TwoFactor, error) {
+	twoFactor := &models.TwoFactor{}
+	var enabledAt sql.NullTime
+	query := "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM two_factor WHERE user_id = $1"
+	err := r.db.QueryRow(query, userID).Scan(&twoFactor.UserID, &twoFactor.Secret, &enabledAt, &twoFactor.LastUsedStep, &twoFactor.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabledAt.Valid {
+		twoFactor.EnabledAt = &enabledAt.Time
+	}
+	return twoFactor, nil
+}
+
+func (r *TwoFactorDatabase) SavePending(twoFactor *models.TwoFactor) error {
+	query := `INSERT INTO two_factor (user_id, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = $2, enabled_at = NULL, last_used_step = 0, created_at = $3`
+	_, err := r.db.Exec(query, twoFactor.UserID, twoFactor.Secret, twoFactor.CreatedAt)
+	return err
+}
+
+func (r *TwoFactorDatabase) Enable(userID int, at time.Time) error {
+	query := "UPDATE two_factor SET enabled_at = $1 WHERE user_id = $2"
+	_, err := r.db.Exec(query, at, userID)
+	return err
+}
+
+func (r *TwoFactorDatabase) Disable(userID int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM two_factor WHERE user_id = $1", userID)
+	return err
+}
+
+func (r *TwoFactorDatabase) UseStep(userID int, step int64) (bool, error) {
+	query := "UPDATE two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1"
+	result, err := r.db.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *TwoFactorDatabase) ReplaceRecoveryCodes(userID int, codeHashes []string) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if _, err = tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TwoFactorDatabase) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	query := "UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *TwoFactorDatabase) RequiredRoles() ([]models.Role, error) {
+	rows, err := r.db.Query("SELECT role FROM two_factor_required_roles ORDER BY role")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *TwoFactorDatabase) SetRoleRequired(role models.Role, required bool) error {
+	query := "DELETE FROM two_factor_required_roles WHERE role = $1"
+	if required {
+		query = "INSERT INTO two_factor_required_roles (role) VALUES ($1) ON CONFLICT DO NOTHING"
+	}
+	_, err := r.db.Exec(query, role)
+	return err
+}
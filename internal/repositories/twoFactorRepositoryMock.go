@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"louderspace/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockTwoFactorStorage struct {
+	enrollments   map[int]*models.TwoFactor
+	recoveryCodes map[int]map[string]bool
+	requiredRoles map[models.Role]bool
+	mu            sync.RWMutex
+}
+
+func NewMockTwoFactorStorage() *MockTwoFactorStorage {
+	return &MockTwoFactorStorage{
+		enrollments:   make(map[int]*models.TwoFactor),
+		recoveryCodes: make(map[int]map[string]bool),
+		requiredRoles: make(map[models.Role]bool),
+	}
+}
+
+func (m *MockTwoFactorStorage) TwoFactor(userID int) (*models.TwoFactor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	twoFactor, exists := m.enrollments[userID]
+	if !exists {
+		return nil, nil
+	}
+	found := *twoFactor
+	return &found, nil
+}
+
+func (m *MockTwoFactorStorage) SavePending(twoFactor *models.TwoFactor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *twoFactor
+	stored.EnabledAt = nil
+	stored.LastUsedStep = 0
+	m.enrollments[twoFactor.UserID] = &stored
+	return nil
+}
+
+func (m *MockTwoFactorStorage) Enable(userID int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if twoFactor, exists := m.enrollments[userID]; exists {
+		twoFactor.EnabledAt = &at
+	}
+	return nil
+}
+
+func (m *MockTwoFactorStorage) Disable(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.enrollments, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *MockTwoFactorStorage) UseStep(userID int, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, exists := m.enrollments[userID]
+	if !exists || twoFactor.LastUsedStep >= step {
+		return false, nil
+	}
+	twoFactor.LastUsedStep = step
+	return true, nil
+}
+
+func (m *MockTwoFactorStorage) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codes := make(map[string]bool, len(codeHashes))
+	for _, codeHash := range codeHashes {
+		codes[codeHash] = false
+	}
+	m.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (m *MockTwoFactorStorage) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used, exists := m.recoveryCodes[userID][codeHash]
+	if !exists || used {
+		return false, nil
+	}
+	m.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (m *MockTwoFactorStorage) RequiredRoles() ([]models.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	roles := []models.Role{}
+	for role := range m.requiredRoles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles, nil
+}
+
+func (m *MockTwoFactorStorage) SetRoleRequired(role models.Role, required bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if required {
+		m.requiredRoles[role] = true
+	} else {
+		delete(m.requiredRoles, role)
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"database/sql"
+	"fmt"
 	"louderspace/internal/models"
+	"strings"
+	"time"
 )
 
 type UserStorage interface {
@@ -10,6 +13,23 @@ type UserStorage interface {
 	UserByID(userID int) (*models.User, error)
 	UserByUsername(username string) (*models.User, error)
 	Users() ([]*models.User, error)
+	// SearchUsers returns the page of users matching filter and the number of matches.
+	SearchUsers(filter models.UserFilter) ([]*models.User, int, error)
+	UserByEmail(email string) (*models.User, error)
+	// UpdateRole sets the role and writes the audit entry in one transaction, so no role
+	// changes without its entry. An unknown user is sql.ErrNoRows.
+	UpdateRole(userID int, role models.Role, audit *models.AuditEntry) error
+	// UpdatePassword stores a new hash and clears PasswordResetRequired.
+	UpdatePassword(userID int, passwordHash string) error
+	// RequirePasswordReset flags the accounts with a password created before createdBefore
+	// and returns how many were flagged.
+	RequirePasswordReset(createdBefore time.Time) (int, error)
+	SetEmailVerified(userID int, verified bool) error
+	UpdateProfile(user *models.User) error
+	// SetSuspended suspends the user at suspendedAt, or reactivates them when it is nil, and
+	// writes the audit entry in the same transaction. An unknown user is sql.ErrNoRows.
+	SetSuspended(userID int, suspendedAt *time.Time, audit *models.AuditEntry) error
+	Anonymize(userID int) error
 }
 
 type UserDatabase struct {
@@ -27,8 +47,8 @@ func (r *UserDatabase) Save(user *models.User) error {
 
 func (r *UserDatabase) UserByID(userID int) (*models.User, error) {
 	user := &models.User{}
-	query := "SELECT id, username, email, role, created_at FROM users WHERE id = $1"
-	if err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt); err != nil {
+	query := "SELECT id, username, email, email_verified, role, created_at, suspended_at FROM users WHERE id = $1"
+	if err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Role, &user.CreatedAt, &user.SuspendedAt); err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -36,16 +56,100 @@ func (r *UserDatabase) UserByID(userID int) (*models.User, error) {
 
 func (r *UserDatabase) UserByUsername(username string) (*models.User, error) {
 	user := &models.User{}
-	query := "SELECT id, username, email, password, role, created_at FROM users WHERE username = $1"
-	if err := r.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role, &user.CreatedAt); err != nil {
+	query := "SELECT id, username, email, email_verified, password, password_reset_required, role, created_at, suspended_at FROM users WHERE username = $1"
+	if err := r.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Password, &user.PasswordResetRequired, &user.Role, &user.CreatedAt, &user.SuspendedAt); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserDatabase) UserByEmail(email string) (*models.User, error) {
+	user := &models.User{}
+	query := "SELECT id, username, email, email_verified, role, created_at, suspended_at FROM users WHERE email = $1"
+	if err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Role, &user.CreatedAt, &user.SuspendedAt); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
 func (r *UserDatabase) Users() ([]*models.User, error) {
-	query := "SELECT id, username, email, role, created_at FROM users"
-	rows, err := r.db.Query(query)
+	query := "SELECT id, username, email, email_verified, role, created_at, suspended_at FROM users WHERE deleted_at IS NULL"
+	return r.queryUsers(query)
+}
+
+// userSortColumns maps the sort keys of models.UserFilter to columns, so only known
+// columns end up in the ORDER BY clause.
+var userSortColumns = map[string]string{
+	models.UserSortID:        "id",
+	models.UserSortUsername:  "username",
+	models.UserSortEmail:     "email",
+	models.UserSortRole:      "role",
+	models.UserSortCreatedAt: "created_at",
+}
+
+func (r *UserDatabase) SearchUsers(filter models.UserFilter) ([]*models.User, int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Username != "" {
+		addCondition("username ILIKE $%d", "%"+escapeLike(filter.Username)+"%")
+	}
+	if filter.Email != "" {
+		addCondition("email ILIKE $%d", "%"+escapeLike(filter.Email)+"%")
+	}
+	if filter.Role != "" {
+		addCondition("role = $%d", filter.Role)
+	}
+	if filter.Suspended != nil {
+		if *filter.Suspended {
+			conditions = append(conditions, "suspended_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "suspended_at IS NULL")
+		}
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at < $%d", *filter.CreatedBefore)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := userSortColumns[filter.Sort]
+	if !ok {
+		column = "id"
+	}
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(
+		"SELECT id, username, email, email_verified, role, created_at, suspended_at FROM users WHERE %s ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d",
+		where, column, direction, direction, len(args)-1, len(args),
+	)
+	users, err := r.queryUsers(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *UserDatabase) queryUsers(query string, args ...interface{}) ([]*models.User, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +158,7 @@ func (r *UserDatabase) Users() ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Role, &user.CreatedAt, &user.SuspendedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -67,3 +171,113 @@ func (r *UserDatabase) Users() ([]*models.User, error) {
 
 	return users, nil
 }
+
+func (r *UserDatabase) UpdateRole(userID int, role models.Role, audit *models.AuditEntry) error {
+	return r.updateAudited(audit, "UPDATE users SET role = $1 WHERE id = $2", role, userID)
+}
+
+func (r *UserDatabase) UpdatePassword(userID int, passwordHash string) error {
+	query := "UPDATE users SET password = $1, password_reset_required = FALSE WHERE id = $2"
+	_, err := r.db.Exec(query, passwordHash, userID)
+	return err
+}
+
+func (r *UserDatabase) RequirePasswordReset(createdBefore time.Time) (int, error) {
+	query := "UPDATE users SET password_reset_required = TRUE WHERE password <> '' AND created_at < $1 AND deleted_at IS NULL AND NOT password_reset_required"
+	result, err := r.db.Exec(query, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	flagged, err := result.RowsAffected()
+	return int(flagged), err
+}
+
+func (r *UserDatabase) SetEmailVerified(userID int, verified bool) error {
+	query := "UPDATE users SET email_verified = $1 WHERE id = $2"
+	_, err := r.db.Exec(query, verified, userID)
+	return err
+}
+
+func (r *UserDatabase) SetSuspended(userID int, suspendedAt *time.Time, audit *models.AuditEntry) error {
+	return r.updateAudited(audit, "UPDATE users SET suspended_at = $1 WHERE id = $2", suspendedAt, userID)
+}
+
+// updateAudited runs an UPDATE of one user and inserts its audit entry in a transaction.
+func (r *UserDatabase) updateAudited(audit *models.AuditEntry, query string, args ...interface{}) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if updated == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+	if err := insertAuditEntry(tx, audit); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *UserDatabase) UpdateProfile(user *models.User) error {
+	query := "UPDATE users SET username = $1, email = $2, email_verified = $3 WHERE id = $4"
+	_, err := r.db.Exec(query, user.Username, user.Email, user.EmailVerified, user.ID)
+	return err
+}
+
+// Anonymize deletes a user's account. The users row is kept as an anonymous tombstone so
+// rows that must survive (audit log, play statistics) stay valid; personal data such as
+// feedback and pomodoro sessions is deleted and play events are detached from the user.
+func (r *UserDatabase) Anonymize(userID int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	now := time.Now()
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM feedback WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM pomodoro_sessions WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM playback_history WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM playlists WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM stations WHERE owner_id = $1", []interface{}{userID}},
+		{"UPDATE play_events SET user_id = NULL WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM one_time_tokens WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM recovery_codes WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM two_factor WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM identities WHERE user_id = $1", []interface{}{userID}},
+		{"UPDATE refresh_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL", []interface{}{userID, now}},
+		{"UPDATE sessions SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL", []interface{}{userID, now}},
+		{"UPDATE api_keys SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL", []interface{}{userID, now}},
+		{
+			"UPDATE users SET username = $2, email = $3, password = '', email_verified = FALSE, deleted_at = $4 WHERE id = $1",
+			[]interface{}{userID, fmt.Sprintf("deleted-user-%d", userID), fmt.Sprintf("deleted-%d@deleted.invalid", userID), now},
+		},
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,21 +1,31 @@
 package repositories
 
 import (
-	"errors"
+	"database/sql"
+	"fmt"
 	"louderspace/internal/models"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 type MockUserStorage struct {
-	users  map[int]*models.User
-	mu     sync.RWMutex
-	nextID int
+	// AuditLog stands in for the audit_log table that UpdateRole and SetSuspended write to.
+	// Tests point it at the MockAuditStorage their services read.
+	AuditLog *MockAuditStorage
+	users    map[int]*models.User
+	deleted  map[int]bool
+	mu       sync.RWMutex
+	nextID   int
 }
 
 func NewMockUserStorage() *MockUserStorage {
 	return &MockUserStorage{
-		users:  make(map[int]*models.User),
-		nextID: 1,
+		AuditLog: NewMockAuditStorage(),
+		users:    make(map[int]*models.User),
+		deleted:  make(map[int]bool),
+		nextID:   1,
 	}
 }
 
@@ -27,7 +37,7 @@ func (s *MockUserStorage) Save(user *models.User) error {
 		user.ID = s.nextID
 		s.nextID++
 	} else if _, exists := s.users[user.ID]; !exists {
-		return errors.New("user not found")
+		return sql.ErrNoRows
 	}
 
 	s.users[user.ID] = user
@@ -40,7 +50,7 @@ func (s *MockUserStorage) UserByID(userID int) (*models.User, error) {
 
 	user, ok := s.users[userID]
 	if !ok {
-		return nil, errors.New("user not found")
+		return nil, sql.ErrNoRows
 	}
 
 	return user, nil
@@ -56,7 +66,20 @@ func (s *MockUserStorage) UserByUsername(username string) (*models.User, error)
 		}
 	}
 
-	return nil, errors.New("user not found")
+	return nil, sql.ErrNoRows
+}
+
+func (s *MockUserStorage) UserByEmail(email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
 }
 
 func (s *MockUserStorage) Users() ([]*models.User, error) {
@@ -65,8 +88,180 @@ func (s *MockUserStorage) Users() ([]*models.User, error) {
 
 	var users []*models.User
 	for _, user := range s.users {
-		users = append(users, user)
+		if !s.deleted[user.ID] {
+			users = append(users, user)
+		}
 	}
 
 	return users, nil
 }
+
+func (s *MockUserStorage) SearchUsers(filter models.UserFilter) ([]*models.User, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []*models.User
+	for _, user := range s.users {
+		if s.deleted[user.ID] || !matchesUserFilter(user, filter) {
+			continue
+		}
+		matches = append(matches, user)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if filter.Descending {
+			a, b = b, a
+		}
+		switch filter.Sort {
+		case models.UserSortUsername:
+			if a.Username != b.Username {
+				return a.Username < b.Username
+			}
+		case models.UserSortEmail:
+			if a.Email != b.Email {
+				return a.Email < b.Email
+			}
+		case models.UserSortRole:
+			if a.Role != b.Role {
+				return a.Role < b.Role
+			}
+		case models.UserSortCreatedAt:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		return a.ID < b.ID
+	})
+
+	total := len(matches)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	matches = matches[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matches) {
+		matches = matches[:filter.Limit]
+	}
+	return matches, total, nil
+}
+
+func matchesUserFilter(user *models.User, filter models.UserFilter) bool {
+	if filter.Username != "" && !strings.Contains(strings.ToLower(user.Username), strings.ToLower(filter.Username)) {
+		return false
+	}
+	if filter.Email != "" && !strings.Contains(strings.ToLower(user.Email), strings.ToLower(filter.Email)) {
+		return false
+	}
+	if filter.Role != "" && user.Role != filter.Role {
+		return false
+	}
+	if filter.Suspended != nil && user.Suspended() != *filter.Suspended {
+		return false
+	}
+	if filter.CreatedAfter != nil && user.CreatedAt.Before(*filter.CreatedAfter) {
+		return false
+	}
+	if filter.CreatedBefore != nil && !user.CreatedAt.Before(*filter.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func (s *MockUserStorage) SetSuspended(userID int, suspendedAt *time.Time, audit *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	user.SuspendedAt = suspendedAt
+	return s.AuditLog.Record(audit)
+}
+
+func (s *MockUserStorage) UpdateRole(userID int, role models.Role, audit *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	user.Role = role
+	return s.AuditLog.Record(audit)
+}
+
+func (s *MockUserStorage) UpdatePassword(userID int, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	user.Password = passwordHash
+	user.PasswordResetRequired = false
+	return nil
+}
+
+func (s *MockUserStorage) RequirePasswordReset(createdBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flagged := 0
+	for _, user := range s.users {
+		if user.Password != "" && user.CreatedAt.Before(createdBefore) && !user.PasswordResetRequired {
+			user.PasswordResetRequired = true
+			flagged++
+		}
+	}
+	return flagged, nil
+}
+
+func (s *MockUserStorage) SetEmailVerified(userID int, verified bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	user.EmailVerified = verified
+	return nil
+}
+
+func (s *MockUserStorage) UpdateProfile(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	stored.Username = user.Username
+	stored.Email = user.Email
+	stored.EmailVerified = user.EmailVerified
+	return nil
+}
+
+func (s *MockUserStorage) Anonymize(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	user.Username = fmt.Sprintf("deleted-user-%d", userID)
+	user.Email = fmt.Sprintf("deleted-%d@deleted.invalid", userID)
+	user.Password = ""
+	user.EmailVerified = false
+	s.deleted[userID] = true
+	return nil
+}
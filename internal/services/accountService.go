@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/logger"
+	"louderspace/internal/mailer"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"strings"
+	"time"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var (
+	ErrInvalidOneTimeToken = errors.New("invalid or expired token")
+	ErrIncorrectPassword   = errors.New("incorrect password")
+	ErrUsernameTaken       = errors.New("username is already taken")
+	ErrEmailTaken          = errors.New("email is already in use")
+	ErrInvalidProfile      = errors.New("username and email are required")
+)
+
+type AccountManagement interface {
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	RequestEmailVerification(userID int) error
+	VerifyEmail(token string) error
+	UpdateProfile(userID int, username, email string) (*models.User, error)
+	ChangePassword(userID int, currentPassword, newPassword string, session *models.Session) (*models.TokenPair, error)
+	DeleteAccount(userID int, password string) error
+}
+
+type AccountService struct {
+	userStorage    repositories.UserStorage
+	tokenStorage   repositories.OneTimeTokenStorage
+	tokenService   TokenManagement
+	mailer         mailer.Mailer
+	baseURL        string
+	passwordPolicy *passwords.Policy
+	hasher         *passwords.Hasher
+}
+
+func NewAccountService(userStorage repositories.UserStorage, tokenStorage repositories.OneTimeTokenStorage, tokenService TokenManagement, mailer mailer.Mailer, baseURL string, passwordPolicy *passwords.Policy, hasher *passwords.Hasher) AccountManagement {
+	return &AccountService{userStorage, tokenStorage, tokenService, mailer, baseURL, passwordPolicy, hasher}
+}
+
+// RequestPasswordReset does not report unknown emails, so it cannot reveal who has an account.
+func (s *AccountService) RequestPasswordReset(email string) error {
+	user, err := s.userStorage.UserByEmail(email)
+	if err != nil {
+		logger.Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := s.issue(user.ID, models.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Louderspace password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Username, passwordResetTTL, s.baseURL, token),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out everywhere. The token is only
+// used up once the password passes the policy, so the user can try again with the same link.
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	stored, err := s.find(models.TokenPurposePasswordReset, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.userStorage.UserByID(stored.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.Validate(user.Username, newPassword); err != nil {
+		return err
+	}
+	if err := s.use(stored); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userStorage.UpdatePassword(stored.UserID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.tokenService.RevokeUserTokens(stored.UserID)
+}
+
+func (s *AccountService) RequestEmailVerification(userID int) error {
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	token, err := s.issue(user.ID, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your Louderspace email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below.\n\n%s/verify-email?token=%s\n",
+			user.Username, s.baseURL, token),
+	})
+}
+
+func (s *AccountService) VerifyEmail(token string) error {
+	stored, err := s.consume(models.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+	return s.userStorage.SetEmailVerified(stored.UserID, true)
+}
+
+// UpdateProfile sends a verification mail to a changed email address.
+func (s *AccountService) UpdateProfile(userID int, username, email string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	email = strings.TrimSpace(email)
+	if username == "" || email == "" {
+		return nil, ErrInvalidProfile
+	}
+
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if username != user.Username {
+		if _, err := s.userStorage.UserByUsername(username); err == nil {
+			return nil, ErrUsernameTaken
+		}
+	}
+	emailChanged := email != user.Email
+	if emailChanged {
+		if _, err := s.userStorage.UserByEmail(email); err == nil {
+			return nil, ErrEmailTaken
+		}
+	}
+
+	updated := *user
+	updated.Username = username
+	updated.Email = email
+	if emailChanged {
+		updated.EmailVerified = false
+	}
+	if err := s.userStorage.UpdateProfile(&updated); err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := s.RequestEmailVerification(userID); err != nil {
+			logger.Error("Failed to send verification email", err)
+		}
+	}
+	return &updated, nil
+}
+
+// ChangePassword signs out every session and returns a token pair for a new one.
+func (s *AccountService) ChangePassword(userID int, currentPassword, newPassword string, session *models.Session) (*models.TokenPair, error) {
+	user, err := s.checkPassword(userID, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.passwordPolicy.Validate(user.Username, newPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userStorage.UpdatePassword(userID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenService.RevokeUserTokens(userID); err != nil {
+		return nil, err
+	}
+	return s.tokenService.IssueTokens(user, session)
+}
+
+// DeleteAccount asks for the password again so a stolen session cannot delete the account.
+func (s *AccountService) DeleteAccount(userID int, password string) error {
+	if _, err := s.checkPassword(userID, password); err != nil {
+		return err
+	}
+	if err := s.userStorage.Anonymize(userID); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserTokens(userID)
+}
+
+// checkPassword loads the user with their password hash, which UserByID does not select.
+func (s *AccountService) checkPassword(userID int, password string) (*models.User, error) {
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	withPassword, err := s.userStorage.UserByUsername(user.Username)
+	if err != nil {
+		return nil, err
+	}
+	match, _, err := s.hasher.Verify(withPassword.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, ErrIncorrectPassword
+	}
+	return withPassword, nil
+}
+
+// issue invalidates earlier tokens for the purpose, so only the latest link works.
+func (s *AccountService) issue(userID int, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := s.tokenStorage.InvalidateForUser(userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.tokenStorage.Create(&models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AccountService) consume(purpose models.TokenPurpose, token string) (*models.OneTimeToken, error) {
+	stored, err := s.find(purpose, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.use(stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// find returns the unused, unexpired token without using it up.
+func (s *AccountService) find(purpose models.TokenPurpose, token string) (*models.OneTimeToken, error) {
+	stored, err := s.tokenStorage.ByHash(purpose, utils.HashToken(token))
+	if err != nil {
+		return nil, ErrInvalidOneTimeToken
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidOneTimeToken
+	}
+	return stored, nil
+}
+
+// use marks the token used. It fails if another request used it first.
+func (s *AccountService) use(stored *models.OneTimeToken) error {
+	used, err := s.tokenStorage.MarkUsed(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidOneTimeToken
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"louderspace/internal/mailer"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var mailedToken = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// lastMailedToken reads the token from the link in the most recent message.
+func lastMailedToken(t *testing.T, mail *mailer.FileMailer) string {
+	messages, err := mail.Messages()
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, messages) {
+		t.FailNow()
+	}
+	data, err := os.ReadFile(messages[len(messages)-1])
+	assert.NoError(t, err)
+	match := mailedToken.FindSubmatch(data)
+	if !assert.NotNil(t, match) {
+		t.FailNow()
+	}
+	return string(match[1])
+}
+
+func TestPasswordReset(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	err = service.RequestPasswordReset(user.Email)
+	assert.NoError(t, err)
+	token := lastMailedToken(t, mail)
+
+	// A rejected password leaves the link usable.
+	err = service.ResetPassword(token, "short")
+	assert.ErrorIs(t, err, passwords.ErrPolicyViolation)
+
+	err = service.ResetPassword(token, "New-Passw0rd-42")
+	assert.NoError(t, err)
+
+	updated, _ := userStorage.UserByID(user.ID)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("New-Passw0rd-42")))
+
+	err = service.ResetPassword(token, "Another-Passw0rd-42")
+	assert.ErrorIs(t, err, ErrInvalidOneTimeToken)
+}
+
+func TestPasswordResetUnknownEmail(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	err = service.RequestPasswordReset("nobody@example.com")
+	assert.NoError(t, err)
+
+	messages, err := mail.Messages()
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestPasswordResetOnlyLatestTokenWorks(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	assert.NoError(t, service.RequestPasswordReset(user.Email))
+	first := lastMailedToken(t, mail)
+	assert.NoError(t, service.RequestPasswordReset(user.Email))
+	second := lastMailedToken(t, mail)
+
+	assert.ErrorIs(t, service.ResetPassword(first, "New-Passw0rd-42"), ErrInvalidOneTimeToken)
+	assert.NoError(t, service.ResetPassword(second, "New-Passw0rd-42"))
+}
+
+func TestEmailVerification(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	err = service.RequestEmailVerification(user.ID)
+	assert.NoError(t, err)
+
+	err = service.VerifyEmail("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidOneTimeToken)
+
+	err = service.VerifyEmail(lastMailedToken(t, mail))
+	assert.NoError(t, err)
+
+	updated, _ := userStorage.UserByID(user.ID)
+	assert.True(t, updated.EmailVerified)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+	assert.NoError(t, userStorage.Save(&models.User{Username: "taken", Email: "taken@example.com"}))
+	assert.NoError(t, userStorage.SetEmailVerified(user.ID, true))
+
+	_, err = service.UpdateProfile(user.ID, "taken", user.Email)
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+	_, err = service.UpdateProfile(user.ID, user.Username, "taken@example.com")
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	_, err = service.UpdateProfile(user.ID, "", user.Email)
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+
+	updated, err := service.UpdateProfile(user.ID, "renamed", "new@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", updated.Username)
+	assert.False(t, updated.EmailVerified)
+
+	// The new address has to be confirmed again.
+	messages, err := mail.Messages()
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+}
+
+func TestChangePassword(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	_, err = service.ChangePassword(user.ID, "wrong", "New-Passw0rd-42", &models.Session{})
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+
+	_, err = service.ChangePassword(user.ID, "old-password", "Testuser-Rocks-1", &models.Session{})
+	assert.ErrorIs(t, err, passwords.ErrPolicyViolation)
+
+	tokens, err := service.ChangePassword(user.ID, "old-password", "New-Passw0rd-42", &models.Session{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	updated, _ := userStorage.UserByID(user.ID)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("New-Passw0rd-42")))
+}
+
+func TestDeleteAccount(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	mail, err := mailer.NewFileMailer(t.TempDir(), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewAccountService(userStorage, repositories.NewMockOneTimeTokenStorage(), tokenService, mail, "http://localhost:3000", passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	assert.ErrorIs(t, service.DeleteAccount(user.ID, "wrong"), ErrIncorrectPassword)
+	assert.NoError(t, service.DeleteAccount(user.ID, "old-password"))
+
+	_, err = userStorage.UserByUsername("testuser")
+	assert.Error(t, err)
+	users, err := userStorage.Users()
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAPIKey    = errors.New("invalid api key")
+	ErrInvalidScope     = errors.New("invalid scope")
+	ErrScopeNotAllowed  = errors.New("scope not allowed for this user")
+	ErrAPIKeyNotFound   = errors.New("api key not found")
+	ErrInvalidKeyName   = errors.New("api key name is required")
+	ErrExpiryInThePast  = errors.New("expiry must be in the future")
+	ErrMissingKeyScopes = errors.New("at least one scope is required")
+)
+
+type APIKeyManagement interface {
+	// CreateKey returns the plaintext key, which is shown once and cannot be recovered.
+	CreateKey(user *models.User, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error)
+	Keys(userID int) ([]*models.APIKey, error)
+	RevokeKey(userID, keyID int) error
+	Authenticate(key string) (*models.User, *models.APIKey, error)
+}
+
+type APIKeyService struct {
+	apiKeyStorage repositories.APIKeyStorage
+	userStorage   repositories.UserStorage
+}
+
+func NewAPIKeyService(apiKeyStorage repositories.APIKeyStorage, userStorage repositories.UserStorage) APIKeyManagement {
+	return &APIKeyService{apiKeyStorage, userStorage}
+}
+
+func (s *APIKeyService) CreateKey(user *models.User, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, ErrInvalidKeyName
+	}
+	if len(scopes) == 0 {
+		return "", nil, ErrMissingKeyScopes
+	}
+	for _, scope := range scopes {
+		permission, known := models.APIKeyScopes[scope]
+		if !known {
+			return "", nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if permission != "" && !user.Role.Has(permission) {
+			return "", nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, ErrExpiryInThePast
+	}
+
+	secret, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	plaintext := models.APIKeyPrefix + secret
+
+	key := &models.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    plaintext[:len(models.APIKeyPrefix)+8],
+		KeyHash:   utils.HashToken(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.apiKeyStorage.Create(key); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+func (s *APIKeyService) Keys(userID int) ([]*models.APIKey, error) {
+	return s.apiKeyStorage.APIKeysForUser(userID)
+}
+
+func (s *APIKeyService) RevokeKey(userID, keyID int) error {
+	revoked, err := s.apiKeyStorage.Revoke(keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate loads the owner from storage so role changes apply to existing keys at once.
+func (s *APIKeyService) Authenticate(plaintext string) (*models.User, *models.APIKey, error) {
+	if !strings.HasPrefix(plaintext, models.APIKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyStorage.APIKeyByHash(utils.HashToken(plaintext))
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userStorage.UserByID(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.apiKeyStorage.TouchLastUsed(key.ID, now); err != nil {
+		logger.Error("Failed to update api key last use", err)
+	}
+	return user, key, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAndAuthenticateAPIKey(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage)
+
+	plaintext, key, err := service.CreateKey(user, "catalog sync", []string{models.ScopeSongsWrite}, nil)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, models.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(plaintext, key.Prefix))
+	assert.NotContains(t, key.KeyHash, plaintext)
+
+	authenticated, authKey, err := service.Authenticate(plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+	assert.True(t, authKey.HasScope(models.ScopeSongsWrite))
+
+	keys, err := service.Keys(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedAt)
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage)
+
+	_, _, err := service.CreateKey(user, "script", []string{"everything"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	_, _, err = service.CreateKey(user, "script", []string{models.ScopeSongsWrite}, nil)
+	assert.ErrorIs(t, err, ErrScopeNotAllowed)
+
+	_, _, err = service.CreateKey(user, " ", []string{models.ScopeSongsRead}, nil)
+	assert.ErrorIs(t, err, ErrInvalidKeyName)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = service.CreateKey(user, "script", []string{models.ScopeSongsRead}, &past)
+	assert.ErrorIs(t, err, ErrExpiryInThePast)
+
+	_, _, err = service.CreateKey(user, "script", []string{models.ScopeSongsRead}, nil)
+	assert.NoError(t, err)
+}
+
+func TestRevokedAndExpiredAPIKeys(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewAPIKeyService(repositories.NewMockAPIKeyStorage(), userStorage)
+
+	plaintext, key, err := service.CreateKey(user, "script", []string{models.ScopeSongsRead}, nil)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, service.RevokeKey(user.ID+1, key.ID), ErrAPIKeyNotFound)
+	assert.NoError(t, service.RevokeKey(user.ID, key.ID))
+	_, _, err = service.Authenticate(plaintext)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	soon := time.Now().Add(50 * time.Millisecond)
+	plaintext, _, err = service.CreateKey(user, "short lived", []string{models.ScopeSongsRead}, &soon)
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, _, err = service.Authenticate(plaintext)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, _, err = service.Authenticate("lsk_unknown")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
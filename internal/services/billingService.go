@@ -0,0 +1,291 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/billing"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"time"
+)
+
+var (
+	ErrUnknownSubscription  = errors.New("unknown subscription")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+type BillingManagement interface {
+	// HandleEvent ignores redelivered, out of order and unhandled events.
+	HandleEvent(event *billing.Event) error
+	Subscription(userID int) (*models.Subscription, error)
+	// ExpireSubscriptions moves lapsed users back to free and returns how many were moved.
+	ExpireSubscriptions(now time.Time) (int, error)
+}
+
+type BillingService struct {
+	subscriptionStorage repositories.SubscriptionStorage
+	userStorage         repositories.UserStorage
+	tokenService        TokenManagement
+	gracePeriod         time.Duration
+}
+
+func NewBillingService(subscriptionStorage repositories.SubscriptionStorage, userStorage repositories.UserStorage, tokenService TokenManagement, gracePeriod time.Duration) BillingManagement {
+	return &BillingService{subscriptionStorage, userStorage, tokenService, gracePeriod}
+}
+
+func (s *BillingService) HandleEvent(event *billing.Event) error {
+	var handle func(*billing.Event) error
+	switch event.Type {
+	case billing.EventSubscriptionCreated:
+		handle = s.subscriptionCreated
+	case billing.EventInvoicePaid:
+		handle = s.invoicePaid
+	case billing.EventInvoicePaymentFailed:
+		handle = s.paymentFailed
+	case billing.EventSubscriptionDeleted:
+		handle = s.subscriptionDeleted
+	default:
+		logger.Info(fmt.Sprintf("Ignoring billing event %s of type %s", event.ID, event.Type))
+		return nil
+	}
+
+	recorded, err := s.subscriptionStorage.RecordEvent(event.ID, event.Type, time.Now())
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		logger.Info(fmt.Sprintf("Ignoring redelivered billing event %s", event.ID))
+		return nil
+	}
+	if err := handle(event); err != nil {
+		// Forget the event so the provider's retry is handled instead of ignored.
+		if forgetErr := s.subscriptionStorage.ForgetEvent(event.ID); forgetErr != nil {
+			logger.Error("Failed to forget billing event", forgetErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *BillingService) Subscription(userID int) (*models.Subscription, error) {
+	subscription, err := s.subscriptionStorage.SubscriptionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// subscriptionCreated grants premium until the end of the paid period plus the grace period.
+func (s *BillingService) subscriptionCreated(event *billing.Event) error {
+	object, err := event.Subscription()
+	if err != nil {
+		return err
+	}
+	userID := object.UserID()
+	if userID == 0 {
+		return fmt.Errorf("%w: subscription %s has no %s metadata", billing.ErrInvalidPayload, object.ID, billing.UserIDMetadataKey)
+	}
+	if _, err := s.userStorage.UserByID(userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	periodEnd := time.Unix(object.CurrentPeriodEnd, 0)
+	subscription, err := s.subscriptionStorage.SubscriptionByProviderID(object.ID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		subscription = &models.Subscription{
+			UserID:                 userID,
+			ProviderSubscriptionID: object.ID,
+			ProviderCustomerID:     object.Customer,
+			Status:                 models.SubscriptionActive,
+			CurrentPeriodEnd:       periodEnd,
+			PremiumUntil:           periodEnd.Add(s.gracePeriod),
+			LastEventAt:            event.CreatedAt(),
+			CreatedAt:              now,
+			UpdatedAt:              now,
+		}
+		if err := s.subscriptionStorage.Create(subscription); err != nil {
+			return err
+		}
+	} else {
+		// An invoice for the subscription may have been handled first.
+		if event.CreatedAt().Before(subscription.LastEventAt) {
+			return nil
+		}
+		subscription.Status = models.SubscriptionActive
+		subscription.CurrentPeriodEnd = periodEnd
+		subscription.PremiumUntil = periodEnd.Add(s.gracePeriod)
+		subscription.EndedAt = nil
+		subscription.LastEventAt = event.CreatedAt()
+		subscription.UpdatedAt = now
+		if err := s.subscriptionStorage.Update(subscription); err != nil {
+			return err
+		}
+	}
+	return s.grantPremium(subscription)
+}
+
+// invoicePaid extends premium access to the end of the newly paid period.
+func (s *BillingService) invoicePaid(event *billing.Event) error {
+	invoice, subscription, err := s.subscriptionFor(event)
+	if err != nil || subscription == nil {
+		return err
+	}
+
+	periodEnd := subscription.CurrentPeriodEnd
+	if invoice.PeriodEnd().After(periodEnd) {
+		periodEnd = invoice.PeriodEnd()
+	}
+	if subscription.Status != models.SubscriptionCanceled {
+		subscription.Status = models.SubscriptionActive
+	}
+	subscription.CurrentPeriodEnd = periodEnd
+	subscription.PremiumUntil = periodEnd.Add(s.gracePeriod)
+	subscription.EndedAt = nil
+	if err := s.save(subscription, event); err != nil {
+		return err
+	}
+	return s.grantPremium(subscription)
+}
+
+// paymentFailed starts the grace period at the first failure; retries do not extend it.
+func (s *BillingService) paymentFailed(event *billing.Event) error {
+	_, subscription, err := s.subscriptionFor(event)
+	if err != nil || subscription == nil {
+		return err
+	}
+
+	if subscription.Status == models.SubscriptionActive {
+		subscription.Status = models.SubscriptionPastDue
+		if graceEnd := time.Now().Add(s.gracePeriod); graceEnd.Before(subscription.PremiumUntil) {
+			subscription.PremiumUntil = graceEnd
+		}
+	}
+	return s.save(subscription, event)
+}
+
+// subscriptionDeleted ends premium at the end of the paid period. ExpireSubscriptions
+// moves the user back to free.
+func (s *BillingService) subscriptionDeleted(event *billing.Event) error {
+	object, err := event.Subscription()
+	if err != nil {
+		return err
+	}
+	subscription, err := s.subscriptionStorage.SubscriptionByProviderID(object.ID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownSubscription, object.ID)
+	}
+	if event.CreatedAt().Before(subscription.LastEventAt) {
+		return nil
+	}
+
+	now := time.Now()
+	end := time.Unix(object.CurrentPeriodEnd, 0)
+	if end.Before(now) {
+		end = now
+	}
+	if end.Before(subscription.PremiumUntil) {
+		subscription.PremiumUntil = end
+	}
+	subscription.Status = models.SubscriptionCanceled
+	subscription.CanceledAt = &now
+	if err := s.save(subscription, event); err != nil {
+		return err
+	}
+
+	if !subscription.PremiumUntil.After(now) {
+		_, err := s.ExpireSubscriptions(now)
+		return err
+	}
+	return nil
+}
+
+func (s *BillingService) ExpireSubscriptions(now time.Time) (int, error) {
+	lapsed, err := s.subscriptionStorage.Lapsed(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, subscription := range lapsed {
+		// A user who subscribed again keeps premium through the newer subscription.
+		latest, err := s.subscriptionStorage.SubscriptionForUser(subscription.UserID)
+		if err != nil {
+			return expired, err
+		}
+		if latest == nil || latest.ID == subscription.ID || !latest.PremiumUntil.After(now) {
+			if err := s.setRole(subscription, models.RolePremium, models.RoleFree); err != nil {
+				return expired, err
+			}
+		}
+		subscription.EndedAt = &now
+		subscription.UpdatedAt = time.Now()
+		if err := s.subscriptionStorage.Update(subscription); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// subscriptionFor returns a nil subscription for an event older than one already applied.
+// An unknown subscription is an error so the provider retries the event later.
+func (s *BillingService) subscriptionFor(event *billing.Event) (*billing.Invoice, *models.Subscription, error) {
+	invoice, err := event.Invoice()
+	if err != nil {
+		return nil, nil, err
+	}
+	subscription, err := s.subscriptionStorage.SubscriptionByProviderID(invoice.Subscription)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subscription == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownSubscription, invoice.Subscription)
+	}
+	if event.CreatedAt().Before(subscription.LastEventAt) {
+		return invoice, nil, nil
+	}
+	return invoice, subscription, nil
+}
+
+func (s *BillingService) save(subscription *models.Subscription, event *billing.Event) error {
+	subscription.LastEventAt = event.CreatedAt()
+	subscription.UpdatedAt = time.Now()
+	return s.subscriptionStorage.Update(subscription)
+}
+
+func (s *BillingService) grantPremium(subscription *models.Subscription) error {
+	return s.setRole(subscription, models.RoleFree, models.RolePremium)
+}
+
+// setRole leaves users with other roles, such as admins, alone.
+func (s *BillingService) setRole(subscription *models.Subscription, from, to models.Role) error {
+	user, err := s.userStorage.UserByID(subscription.UserID)
+	if err != nil {
+		return err
+	}
+	if user.Role != from {
+		return nil
+	}
+
+	if err := s.userStorage.UpdateRole(user.ID, to, &models.AuditEntry{
+		ActorID:      models.SystemActorID,
+		TargetUserID: user.ID,
+		Action:       models.AuditActionChangeRole,
+		Details:      fmt.Sprintf("%s -> %s (subscription %s)", from, to, subscription.ProviderSubscriptionID),
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserTokens(user.ID)
+}
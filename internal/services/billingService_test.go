@@ -0,0 +1,207 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/billing"
+	"louderspace/internal/billing/billingtest"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"testing"
+	"time"
+)
+
+func billingEvent(t *testing.T, payload []byte) *billing.Event {
+	event := &billing.Event{}
+	assert.NoError(t, json.Unmarshal(payload, event))
+	return event
+}
+
+func assertRole(t *testing.T, userStorage *repositories.MockUserStorage, userID int, role models.Role) {
+	stored, err := userStorage.UserByID(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, role, stored.Role)
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := userStorage.AuditLog
+	subscriptionStorage := repositories.NewMockSubscriptionStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewBillingService(subscriptionStorage, userStorage, tokenService, 72*time.Hour)
+
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(user))
+
+	provider := billingtest.NewProvider("whsec_test")
+	periodEnd := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionCreated("sub_1", "cus_1", user.ID, periodEnd))))
+	assertRole(t, userStorage, user.ID, models.RolePremium)
+	subscription, err := service.Subscription(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionActive, subscription.Status)
+	assert.True(t, subscription.PremiumUntil.Equal(periodEnd.Add(72*time.Hour)))
+	assert.Len(t, auditStorage.Entries, 1)
+	assert.Equal(t, "free -> premium (subscription sub_1)", auditStorage.Entries[0].Details)
+	assert.Equal(t, models.SystemActorID, auditStorage.Entries[0].ActorID)
+
+	renewedEnd := periodEnd.AddDate(0, 1, 0)
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.InvoicePaid("sub_1", "cus_1", renewedEnd))))
+	subscription, _ = service.Subscription(user.ID)
+	assert.True(t, subscription.CurrentPeriodEnd.Equal(renewedEnd))
+
+	// Canceling keeps premium until the end of the paid period.
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionDeleted("sub_1", "cus_1", renewedEnd))))
+	subscription, _ = service.Subscription(user.ID)
+	assert.Equal(t, models.SubscriptionCanceled, subscription.Status)
+	assert.True(t, subscription.PremiumUntil.Equal(renewedEnd))
+	assertRole(t, userStorage, user.ID, models.RolePremium)
+
+	expired, err := service.ExpireSubscriptions(renewedEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assertRole(t, userStorage, user.ID, models.RoleFree)
+	subscription, _ = service.Subscription(user.ID)
+	assert.NotNil(t, subscription.EndedAt)
+	assert.Len(t, auditStorage.Entries, 2)
+
+	expired, err = service.ExpireSubscriptions(renewedEnd.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+}
+
+func TestFailedPaymentGracePeriod(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	subscriptionStorage := repositories.NewMockSubscriptionStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewBillingService(subscriptionStorage, userStorage, tokenService, 72*time.Hour)
+
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(user))
+
+	provider := billingtest.NewProvider("whsec_test")
+	periodEnd := time.Now().Add(time.Hour)
+
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionCreated("sub_1", "cus_1", user.ID, periodEnd))))
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.PaymentFailed("sub_1", "cus_1", periodEnd.AddDate(0, 1, 0)))))
+	subscription, _ := service.Subscription(user.ID)
+	assert.Equal(t, models.SubscriptionPastDue, subscription.Status)
+	graceEnd := subscription.PremiumUntil
+	assert.WithinDuration(t, time.Now().Add(72*time.Hour), graceEnd, time.Minute)
+
+	// Retries do not extend the grace period.
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.PaymentFailed("sub_1", "cus_1", periodEnd.AddDate(0, 1, 0)))))
+	subscription, _ = service.Subscription(user.ID)
+	assert.True(t, subscription.PremiumUntil.Equal(graceEnd))
+
+	expired, err := service.ExpireSubscriptions(time.Now().Add(24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+	assertRole(t, userStorage, user.ID, models.RolePremium)
+
+	expired, err = service.ExpireSubscriptions(graceEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assertRole(t, userStorage, user.ID, models.RoleFree)
+
+	// Paying late restores premium.
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.InvoicePaid("sub_1", "cus_1", periodEnd.AddDate(0, 1, 0)))))
+	assertRole(t, userStorage, user.ID, models.RolePremium)
+	subscription, _ = service.Subscription(user.ID)
+	assert.Equal(t, models.SubscriptionActive, subscription.Status)
+	assert.Nil(t, subscription.EndedAt)
+}
+
+func TestImmediateCancellationDowngrades(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	subscriptionStorage := repositories.NewMockSubscriptionStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewBillingService(subscriptionStorage, userStorage, tokenService, 72*time.Hour)
+
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(user))
+
+	provider := billingtest.NewProvider("whsec_test")
+
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionCreated("sub_1", "cus_1", user.ID, time.Now().Add(time.Hour)))))
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionDeleted("sub_1", "cus_1", time.Now().Add(-time.Minute)))))
+	assertRole(t, userStorage, user.ID, models.RoleFree)
+}
+
+func TestBillingEventsAreIdempotent(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := userStorage.AuditLog
+	subscriptionStorage := repositories.NewMockSubscriptionStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewBillingService(subscriptionStorage, userStorage, tokenService, 72*time.Hour)
+
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(user))
+
+	provider := billingtest.NewProvider("whsec_test")
+	periodEnd := time.Now().Add(time.Hour)
+
+	created := provider.SubscriptionCreated("sub_1", "cus_1", user.ID, periodEnd)
+	assert.NoError(t, service.HandleEvent(billingEvent(t, created)))
+	assert.NoError(t, service.HandleEvent(billingEvent(t, created)))
+	assert.Len(t, auditStorage.Entries, 1)
+
+	// A retried failed payment is applied once.
+	failed := provider.PaymentFailed("sub_1", "cus_1", periodEnd)
+	assert.NoError(t, service.HandleEvent(billingEvent(t, failed)))
+	subscription, _ := subscriptionStorage.SubscriptionByProviderID("sub_1")
+	subscription.Status = models.SubscriptionActive
+	assert.NoError(t, subscriptionStorage.Update(subscription))
+	assert.NoError(t, service.HandleEvent(billingEvent(t, failed)))
+	subscription, _ = subscriptionStorage.SubscriptionByProviderID("sub_1")
+	assert.Equal(t, models.SubscriptionActive, subscription.Status)
+}
+
+func TestBillingEventOrdering(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	subscriptionStorage := repositories.NewMockSubscriptionStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewBillingService(subscriptionStorage, userStorage, tokenService, 72*time.Hour)
+
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(user))
+
+	provider := billingtest.NewProvider("whsec_test")
+	periodEnd := time.Now().Add(time.Hour)
+
+	// An invoice that arrives before its subscription is refused, so the provider retries it,
+	// and handled once the subscription exists.
+	paid := billingEvent(t, provider.InvoicePaid("sub_1", "cus_1", periodEnd))
+	assert.ErrorIs(t, service.HandleEvent(paid), ErrUnknownSubscription)
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionCreated("sub_1", "cus_1", user.ID, periodEnd))))
+	assert.NoError(t, service.HandleEvent(paid))
+
+	// An event older than the last one applied is ignored.
+	stale := billingEvent(t, provider.PaymentFailed("sub_1", "cus_1", periodEnd))
+	stale.Created = time.Now().Add(-time.Hour).Unix()
+	assert.NoError(t, service.HandleEvent(stale))
+	subscription, _ := subscriptionStorage.SubscriptionByProviderID("sub_1")
+	assert.Equal(t, models.SubscriptionActive, subscription.Status)
+	assertRole(t, userStorage, user.ID, models.RolePremium)
+}
+
+func TestBillingLeavesOtherRolesAlone(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := userStorage.AuditLog
+	subscriptionStorage := repositories.NewMockSubscriptionStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewBillingService(subscriptionStorage, userStorage, tokenService, 72*time.Hour)
+
+	provider := billingtest.NewProvider("whsec_test")
+	admin := &models.User{Username: "admin", Role: models.RoleAdmin}
+	assert.NoError(t, userStorage.Save(admin))
+
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionCreated("sub_1", "cus_1", admin.ID, time.Now().Add(time.Hour)))))
+	assert.NoError(t, service.HandleEvent(billingEvent(t, provider.SubscriptionDeleted("sub_1", "cus_1", time.Now().Add(-time.Minute)))))
+	assertRole(t, userStorage, admin.ID, models.RoleAdmin)
+	assert.Empty(t, auditStorage.Entries)
+
+	_, err := service.Subscription(12345)
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+}
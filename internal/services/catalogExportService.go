@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCatalogFormat = errors.New("format must be csv, json or m3u")
+
+var catalogCSVHeader = []string{"id", "title", "artist", "genre", "suno_id", "is_generated", "created_at", "duration_seconds", "audio_url",
+	"image_url", "image_large_url", "model_version", "bpm", "key", "energy", "lyrics", "tags", "play_count"}
+
+// csvFormulaPrefixes start a formula when a spreadsheet opens the CSV.
+const csvFormulaPrefixes = "=+-@"
+
+// escapeCSVCell quotes cells a spreadsheet would run as formulas.
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type CatalogExportManagement interface {
+	// ExportCatalog returns errors about the request before anything is written. A zero
+	// stationID or empty tag does not filter.
+	ExportCatalog(w io.Writer, format models.CatalogFormat, stationID int, tag string) error
+}
+
+type CatalogExportService struct {
+	songStorage    repositories.SongStorage
+	stationStorage repositories.StationStorage
+}
+
+func NewCatalogExportService(songStorage repositories.SongStorage, stationStorage repositories.StationStorage) CatalogExportManagement {
+	return &CatalogExportService{songStorage, stationStorage}
+}
+
+func (s *CatalogExportService) ExportCatalog(w io.Writer, format models.CatalogFormat, stationID int, tag string) error {
+	if !format.Valid() {
+		return ErrInvalidCatalogFormat
+	}
+	filter := models.CatalogFilter{Tag: tag}
+	if stationID != 0 {
+		station, err := s.stationStorage.ByID(stationID)
+		if err != nil || station == nil {
+			return ErrStationNotFound
+		}
+		filter.Station = station
+	}
+
+	switch format {
+	case models.CatalogCSV:
+		return s.exportCSV(w, filter)
+	case models.CatalogJSON:
+		return s.exportJSON(w, filter)
+	default:
+		return s.exportM3U(w, filter)
+	}
+}
+
+// exportCSV writes one row per song. The song import reads the file back.
+func (s *CatalogExportService) exportCSV(w io.Writer, filter models.CatalogFilter) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(catalogCSVHeader); err != nil {
+		return err
+	}
+	if err := s.songStorage.EachSong(filter, func(song *models.Song) error {
+		bpm, energy := "", ""
+		if song.BPM != nil {
+			bpm = strconv.Itoa(*song.BPM)
+		}
+		if song.Energy != nil {
+			energy = strconv.FormatFloat(*song.Energy, 'f', -1, 64)
+		}
+		row := []string{strconv.Itoa(song.ID), song.Title, song.Artist, song.Genre, song.SunoID, strconv.FormatBool(song.IsGenerated),
+			formatTime(song.CreatedAt), strconv.FormatFloat(song.DurationSeconds, 'f', -1, 64), song.AudioURL, song.ImageURL, song.ImageLargeURL,
+			song.ModelVersion, bpm, song.Key, energy, song.Lyrics, strings.Join(tagNames(song.Tags), songImportTagSeparator), strconv.Itoa(song.PlayCount)}
+		for i, cell := range row {
+			row[i] = escapeCSVCell(cell)
+		}
+		return writer.Write(row)
+	}); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportJSON writes a JSON array of songs as the API returns them.
+func (s *CatalogExportService) exportJSON(w io.Writer, filter models.CatalogFilter) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	first := true
+	if err := s.songStorage.EachSong(filter, func(song *models.Song) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		return encoder.Encode(song)
+	}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
+
+// exportM3U leaves out songs without an audio URL, since they cannot be played.
+func (s *CatalogExportService) exportM3U(w io.Writer, filter models.CatalogFilter) error {
+	if _, err := io.WriteString(w, "#EXTM3U\n"); err != nil {
+		return err
+	}
+	return s.songStorage.EachSong(filter, func(song *models.Song) error {
+		if song.AudioURL == "" {
+			return nil
+		}
+		duration := -1
+		if song.DurationSeconds > 0 {
+			duration = int(math.Round(song.DurationSeconds))
+		}
+		_, err := fmt.Fprintf(w, "#EXTINF:%d tags=\"%s\" play-count=\"%d\",%s - %s\n%s\n", duration,
+			m3uText(strings.Join(tagNames(song.Tags), ",")), song.PlayCount, m3uText(song.Artist), m3uText(song.Title), m3uText(song.AudioURL))
+		return err
+	})
+}
+
+// m3uText keeps a value on its line and out of the attribute quotes.
+func m3uText(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ", `"`, "'").Replace(value)
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"testing"
+)
+
+func TestExportCatalogCSV(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	stationStorage := repositories.NewStationStorageMock()
+	songService := NewSongService(songStorage)
+
+	bpm := 85
+	night, err := songService.CreateSong("Night Drive", "Nova", "lofi", "suno-1", true, []string{"focus", "chill"}, models.SongMetadata{
+		DurationSeconds: 161.4, AudioURL: "https://cdn.example.com/1.mp3", BPM: &bpm, Lyrics: "City lights\nall night",
+	})
+	assert.NoError(t, err)
+	_, err = songService.CreateSong(`Rain, "Again"`, "Aurora", "ambient", "suno-2", false, []string{"chill"}, models.SongMetadata{})
+	assert.NoError(t, err)
+	songStorage.SetPlayCount(night.ID, 12)
+	exportService := NewCatalogExportService(songStorage, stationStorage)
+
+	var out bytes.Buffer
+	assert.NoError(t, exportService.ExportCatalog(&out, models.CatalogCSV, 0, ""))
+	rows, err := csv.NewReader(&out).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, catalogCSVHeader, rows[0])
+	assert.Equal(t, []string{"1", "Night Drive", "Nova", "lofi", "suno-1", "true"}, rows[1][:6])
+	assert.Equal(t, "161.4", rows[1][7])
+	assert.Equal(t, "85", rows[1][12])
+	assert.Equal(t, "City lights\nall night", rows[1][15])
+	assert.Equal(t, "focus;chill", rows[1][16])
+	assert.Equal(t, "12", rows[1][17])
+	assert.Equal(t, `Rain, "Again"`, rows[2][1])
+	assert.Equal(t, "", rows[2][12])
+	assert.Equal(t, "0", rows[2][17])
+}
+
+func TestExportCatalogJSON(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	stationStorage := repositories.NewStationStorageMock()
+	songService := NewSongService(songStorage)
+
+	bpm := 85
+	night, err := songService.CreateSong("Night Drive", "Nova", "lofi", "suno-1", true, []string{"focus", "chill"}, models.SongMetadata{
+		DurationSeconds: 161.4, AudioURL: "https://cdn.example.com/1.mp3", BPM: &bpm, Lyrics: "City lights\nall night",
+	})
+	assert.NoError(t, err)
+	_, err = songService.CreateSong(`Rain, "Again"`, "Aurora", "ambient", "suno-2", false, []string{"chill"}, models.SongMetadata{})
+	assert.NoError(t, err)
+	songStorage.SetPlayCount(night.ID, 12)
+	station := &models.Station{Name: "Lofi Beats", Tags: []string{"lofi"}}
+	assert.NoError(t, stationStorage.Create(station))
+	exportService := NewCatalogExportService(songStorage, stationStorage)
+
+	var out bytes.Buffer
+	assert.NoError(t, exportService.ExportCatalog(&out, models.CatalogJSON, 0, "chill"))
+	var songs []models.Song
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &songs))
+	assert.Len(t, songs, 2)
+	assert.Equal(t, 12, songs[0].PlayCount)
+	assert.Len(t, songs[0].Tags, 2)
+
+	out.Reset()
+	assert.NoError(t, exportService.ExportCatalog(&out, models.CatalogJSON, station.ID, ""))
+	songs = nil
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &songs))
+	assert.Len(t, songs, 1)
+	assert.Equal(t, "suno-1", songs[0].SunoID)
+
+	out.Reset()
+	assert.NoError(t, exportService.ExportCatalog(&out, models.CatalogJSON, 0, "jazz"))
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestExportCatalogM3U(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	stationStorage := repositories.NewStationStorageMock()
+	songService := NewSongService(songStorage)
+
+	bpm := 85
+	night, err := songService.CreateSong("Night Drive", "Nova", "lofi", "suno-1", true, []string{"focus", "chill"}, models.SongMetadata{
+		DurationSeconds: 161.4, AudioURL: "https://cdn.example.com/1.mp3", BPM: &bpm, Lyrics: "City lights\nall night",
+	})
+	assert.NoError(t, err)
+	_, err = songService.CreateSong(`Rain, "Again"`, "Aurora", "ambient", "suno-2", false, []string{"chill"}, models.SongMetadata{})
+	assert.NoError(t, err)
+	songStorage.SetPlayCount(night.ID, 12)
+	exportService := NewCatalogExportService(songStorage, stationStorage)
+
+	var out bytes.Buffer
+	assert.NoError(t, exportService.ExportCatalog(&out, models.CatalogM3U, 0, ""))
+	assert.Equal(t, "#EXTM3U\n"+
+		"#EXTINF:161 tags=\"focus,chill\" play-count=\"12\",Nova - Night Drive\n"+
+		"https://cdn.example.com/1.mp3\n", out.String(), "songs without audio are left out")
+}
+
+func TestExportCatalogRejectsBadRequests(t *testing.T) {
+	exportService := NewCatalogExportService(repositories.NewSongStorageMock(), repositories.NewStationStorageMock())
+
+	var out bytes.Buffer
+	assert.ErrorIs(t, exportService.ExportCatalog(&out, "xlsx", 0, ""), ErrInvalidCatalogFormat)
+	assert.ErrorIs(t, exportService.ExportCatalog(&out, models.CatalogCSV, 99, ""), ErrStationNotFound)
+	assert.Empty(t, out.String())
+}
+
+func TestExportCatalogCSVEscapesFormulas(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	songService := NewSongService(songStorage)
+	_, err := songService.CreateSong(`=HYPERLINK("http://evil.example")`, "-Nova", "lofi", "suno-1", true, []string{"focus"}, models.SongMetadata{DurationSeconds: 90})
+	assert.NoError(t, err)
+	exportService := NewCatalogExportService(songStorage, repositories.NewStationStorageMock())
+
+	var out bytes.Buffer
+	assert.NoError(t, exportService.ExportCatalog(&out, models.CatalogCSV, 0, ""))
+	rows, err := csv.NewReader(bytes.NewReader(out.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, `'=HYPERLINK("http://evil.example")`, rows[1][1], "formulas are not run by spreadsheets")
+	assert.Equal(t, "'-Nova", rows[1][2])
+	assert.Equal(t, "suno-1", rows[1][4])
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/models"
+	"time"
+)
+
+var ErrUpgradeRequired = errors.New("upgrade required")
+
+// UpgradeRequiredError matches ErrUpgradeRequired.
+type UpgradeRequiredError struct {
+	Feature   string      `json:"feature"`
+	Limit     int         `json:"limit"`
+	Tier      models.Role `json:"tier"`
+	UpgradeTo models.Role `json:"upgrade_to"`
+	// RetryAfter is set for rate limits that lift on their own.
+	RetryAfter *time.Time `json:"retry_after,omitempty"`
+}
+
+func (e *UpgradeRequiredError) Error() string {
+	return fmt.Sprintf("%s: %s is limited to %d on the %s tier", ErrUpgradeRequired, e.Feature, e.Limit, e.Tier)
+}
+
+func (e *UpgradeRequiredError) Is(target error) bool {
+	return target == ErrUpgradeRequired
+}
+
+func upgradeRequired(user *models.User, feature string, limit int) *UpgradeRequiredError {
+	return &UpgradeRequiredError{Feature: feature, Limit: limit, Tier: user.Role, UpgradeTo: models.RolePremium}
+}
+
+// checkStationAccess hides other users' personal stations and gates premium ones.
+func checkStationAccess(user *models.User, station *models.Station) error {
+	if !station.AccessibleBy(user.ID) {
+		return ErrStationNotFound
+	}
+	if station.Premium && !user.Role.Entitlements().PremiumStations {
+		return upgradeRequired(user, models.FeaturePremiumStations, 0)
+	}
+	return nil
+}
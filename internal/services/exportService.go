@@ -0,0 +1,172 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"os"
+	"strconv"
+	"time"
+)
+
+type ExportManagement interface {
+	// Export audits the export first when the actor is not the user.
+	Export(actor, user *models.User, w io.Writer) error
+}
+
+type ExportService struct {
+	feedbackStorage        repositories.FeedbackStorage
+	playEventStorage       repositories.PlayEventStorage
+	pomodoroSessionStorage repositories.PomodoroSessionStorage
+	playbackService        PlaybackManagement
+	auditStorage           repositories.AuditStorage
+}
+
+func NewExportService(feedbackStorage repositories.FeedbackStorage, playEventStorage repositories.PlayEventStorage, pomodoroSessionStorage repositories.PomodoroSessionStorage, playbackService PlaybackManagement, auditStorage repositories.AuditStorage) ExportManagement {
+	return &ExportService{feedbackStorage, playEventStorage, pomodoroSessionStorage, playbackService, auditStorage}
+}
+
+// emitFunc receives a record as both its JSON value and its CSV row.
+type emitFunc func(record interface{}, row []string) error
+
+func (s *ExportService) Export(actor, user *models.User, w io.Writer) error {
+	if actor.ID != user.ID {
+		if err := s.auditStorage.Record(&models.AuditEntry{
+			ActorID:      actor.ID,
+			TargetUserID: user.ID,
+			Action:       models.AuditActionExport,
+			CreatedAt:    time.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	archive := zip.NewWriter(w)
+
+	if err := writeDataset(archive, "profile", []string{"id", "username", "email", "email_verified", "role", "created_at"},
+		func(emit emitFunc) error {
+			return emit(user, []string{strconv.Itoa(user.ID), user.Username, user.Email, strconv.FormatBool(user.EmailVerified), string(user.Role), formatTime(user.CreatedAt)})
+		}); err != nil {
+		return err
+	}
+
+	if err := writeDataset(archive, "feedback", []string{"id", "song_id", "liked"},
+		func(emit emitFunc) error {
+			return s.feedbackStorage.EachFeedbackForUser(user.ID, func(f *models.Feedback) error {
+				return emit(f, []string{strconv.Itoa(f.ID), strconv.Itoa(f.SongID), strconv.FormatBool(f.Liked)})
+			})
+		}); err != nil {
+		return err
+	}
+
+	if err := writeDataset(archive, "play_events", []string{"id", "song_id", "start_time", "end_time", "duration", "created_at"},
+		func(emit emitFunc) error {
+			return s.playEventStorage.EachPlayEventForUser(user.ID, func(e *models.PlayEvent) error {
+				return emit(e, []string{strconv.Itoa(e.ID), strconv.Itoa(e.SongID), formatTime(e.StartTime), formatTime(e.EndTime), strconv.Itoa(e.Duration), formatTime(e.CreatedAt)})
+			})
+		}); err != nil {
+		return err
+	}
+
+	if err := writeDataset(archive, "pomodoro_sessions", []string{"id", "start_time", "end_time", "duration", "break_duration", "status"},
+		func(emit emitFunc) error {
+			return s.pomodoroSessionStorage.EachSessionForUser(user.ID, func(p *models.PomodoroSession) error {
+				return emit(p, []string{strconv.Itoa(p.ID), formatTime(p.StartTime), formatTime(p.EndTime), strconv.Itoa(p.Duration), strconv.Itoa(p.BreakDuration), p.Status})
+			})
+		}); err != nil {
+		return err
+	}
+
+	// Playback state only exists while the user has something queued.
+	playback, _ := s.playbackService.GetPlaybackState(user)
+	if err := writeDataset(archive, "playback", []string{"station_id", "current_song_id", "queued_song_ids", "is_playing", "playback_time"},
+		func(emit emitFunc) error {
+			if playback == nil {
+				return nil
+			}
+			currentSongID := ""
+			if playback.CurrentSong != nil {
+				currentSongID = strconv.Itoa(playback.CurrentSong.ID)
+			}
+			queued := ""
+			for i, song := range playback.SongQueue {
+				if i > 0 {
+					queued += ";"
+				}
+				queued += strconv.Itoa(song.ID)
+			}
+			return emit(playback, []string{strconv.Itoa(playback.StationID), currentSongID, queued, strconv.FormatBool(playback.IsPlaying), formatTime(playback.PlaybackTime)})
+		}); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// writeDataset spools the CSV rows to a temporary file, since a zip is written one entry
+// at a time.
+func writeDataset(archive *zip.Writer, name string, header []string, each func(emit emitFunc) error) error {
+	csvTemp, err := os.CreateTemp("", "export-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(csvTemp.Name())
+	defer csvTemp.Close()
+
+	writer := csv.NewWriter(csvTemp)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	jsonFile, err := archive.Create(name + ".json")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(jsonFile, "["); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(jsonFile)
+	first := true
+	if err := each(func(record interface{}, row []string) error {
+		if !first {
+			if _, err := io.WriteString(jsonFile, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		return writer.Write(row)
+	}); err != nil {
+		return fmt.Errorf("exporting %s: %w", name, err)
+	}
+	if _, err := io.WriteString(jsonFile, "]\n"); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	if _, err := csvTemp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	csvFile, err := archive.Create(name + ".csv")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(csvFile, csvTemp)
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
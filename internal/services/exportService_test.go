@@ -0,0 +1,109 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		rc.Close()
+		files[file.Name] = content
+	}
+	return files
+}
+
+func TestExport(t *testing.T) {
+	feedbackStorage := repositories.NewMockFeedbackStorage()
+	playEventStorage := repositories.NewMockPlayEventStorage()
+	pomodoroStorage := repositories.NewMockPomodoroSessionStorage()
+	auditStorage := repositories.NewMockAuditStorage()
+	service := NewExportService(feedbackStorage, playEventStorage, pomodoroStorage, NewPlaybackService(repositories.NewStationStorageMock()), auditStorage)
+
+	user := &models.User{ID: 1, Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, feedbackStorage.SaveFeedback(&models.Feedback{UserID: 1, SongID: 10, Liked: true}))
+	assert.NoError(t, feedbackStorage.SaveFeedback(&models.Feedback{UserID: 2, SongID: 11, Liked: false}))
+	start := time.Now().Add(-time.Minute)
+	assert.NoError(t, playEventStorage.LogPlayEvent(&models.PlayEvent{UserID: 1, SongID: 10, StartTime: start, EndTime: start.Add(30 * time.Second)}))
+	assert.NoError(t, pomodoroStorage.Create(&models.PomodoroSession{UserID: 1, Duration: 25, BreakDuration: 5, Status: "active"}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, service.Export(user, user, &buf))
+	files := readArchive(t, buf.Bytes())
+
+	for _, name := range []string{"profile", "feedback", "play_events", "pomodoro_sessions", "playback"} {
+		assert.Contains(t, files, name+".json")
+		assert.Contains(t, files, name+".csv")
+	}
+
+	var feedback []models.Feedback
+	assert.NoError(t, json.Unmarshal(files["feedback.json"], &feedback))
+	assert.Len(t, feedback, 1)
+	assert.Equal(t, 10, feedback[0].SongID)
+
+	rows, err := csv.NewReader(bytes.NewReader(files["play_events.csv"])).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "30", rows[1][4])
+
+	var profile []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "testuser", profile[0]["username"])
+	assert.NotContains(t, profile[0], "password")
+
+	// Users exporting their own data are not audited.
+	assert.Empty(t, auditStorage.Entries)
+}
+
+func TestExportByAdminIsAudited(t *testing.T) {
+	auditStorage := repositories.NewMockAuditStorage()
+	service := NewExportService(repositories.NewMockFeedbackStorage(), repositories.NewMockPlayEventStorage(), repositories.NewMockPomodoroSessionStorage(), NewPlaybackService(repositories.NewStationStorageMock()), auditStorage)
+
+	admin := &models.User{ID: 1, Role: models.RoleAdmin}
+	user := &models.User{ID: 2, Username: "testuser"}
+
+	var buf bytes.Buffer
+	assert.NoError(t, service.Export(admin, user, &buf))
+	assert.Len(t, auditStorage.Entries, 1)
+	assert.Equal(t, models.AuditActionExport, auditStorage.Entries[0].Action)
+	assert.Equal(t, user.ID, auditStorage.Entries[0].TargetUserID)
+}
+
+func TestWriteDatasetRunsQueryOnce(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	runs := 0
+	assert.NoError(t, writeDataset(archive, "numbers", []string{"n"}, func(emit emitFunc) error {
+		runs++
+		for i := 1; i <= 2; i++ {
+			if err := emit(i, []string{strconv.Itoa(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	assert.NoError(t, archive.Close())
+	assert.Equal(t, 1, runs)
+
+	files := readArchive(t, buf.Bytes())
+	assert.JSONEq(t, `[1,2]`, string(files["numbers.json"]))
+	assert.Equal(t, "n\n1\n2\n", string(files["numbers.csv"]))
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"time"
+)
+
+var ErrImpersonationForbidden = errors.New("only admins can impersonate other users")
+
+type ImpersonationManagement interface {
+	Impersonate(admin *models.User, targetUserID int, details string) (*models.User, error)
+}
+
+type ImpersonationService struct {
+	userStorage  repositories.UserStorage
+	auditStorage repositories.AuditStorage
+}
+
+func NewImpersonationService(userStorage repositories.UserStorage, auditStorage repositories.AuditStorage) ImpersonationManagement {
+	return &ImpersonationService{userStorage, auditStorage}
+}
+
+// Impersonate refuses the impersonation if it cannot be audited.
+func (s *ImpersonationService) Impersonate(admin *models.User, targetUserID int, details string) (*models.User, error) {
+	if !admin.Role.Has(models.PermissionUsersImpersonate) {
+		return nil, ErrImpersonationForbidden
+	}
+
+	target, err := s.userStorage.UserByID(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditStorage.Record(&models.AuditEntry{
+		ActorID:      admin.ID,
+		TargetUserID: target.ID,
+		Action:       models.AuditActionImpersonate,
+		Details:      details,
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"strings"
+	"time"
+)
+
+const invitePrefixLength = 6
+
+var (
+	ErrRegistrationClosed = errors.New("registration is closed")
+	ErrInviteRequired     = errors.New("an invite code is required to register")
+	ErrInvalidInvite      = errors.New("invalid, expired or used up invite code")
+	ErrInviteNotFound     = errors.New("invite not found")
+	ErrInvalidMaxUses     = errors.New("max uses cannot be negative")
+)
+
+type InviteManagement interface {
+	Mode() models.RegistrationMode
+	// Register gives the account the invite's role. Invite-only mode requires a code.
+	Register(username, password, email, code string) (*models.User, error)
+
+	// CreateInvite returns the plaintext code, which is shown once and cannot be recovered.
+	CreateInvite(admin *models.User, role models.Role, maxUses int, expiresAt *time.Time, note string) (string, *models.Invite, error)
+	Invites() ([]*models.Invite, error)
+	RevokeInvite(inviteID int) error
+}
+
+type InviteService struct {
+	inviteStorage repositories.InviteStorage
+	userService   UserManagement
+	mode          models.RegistrationMode
+}
+
+func NewInviteService(inviteStorage repositories.InviteStorage, userService UserManagement, mode models.RegistrationMode) InviteManagement {
+	return &InviteService{inviteStorage, userService, mode}
+}
+
+func (s *InviteService) Mode() models.RegistrationMode {
+	return s.mode
+}
+
+func (s *InviteService) Register(username, password, email, code string) (*models.User, error) {
+	code = strings.TrimSpace(code)
+	switch s.mode {
+	case models.RegistrationClosed:
+		return nil, ErrRegistrationClosed
+	case models.RegistrationInviteOnly:
+		if code == "" {
+			return nil, ErrInviteRequired
+		}
+	}
+
+	if code == "" {
+		return s.userService.Register(username, password, email)
+	}
+	return s.registerWithInvite(username, password, email, code)
+}
+
+// registerWithInvite creates the user with the invite's role in the same transaction that
+// takes a use of the invite and records the redemption, so a failed registration neither
+// uses up the invite nor leaves an account behind.
+func (s *InviteService) registerWithInvite(username, password, email, code string) (*models.User, error) {
+	invite, err := s.inviteStorage.InviteByHash(utils.HashToken(code))
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil {
+		return nil, ErrInvalidInvite
+	}
+
+	user, err := s.userService.NewUser(username, password, email)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = invite.Role
+
+	now := time.Now()
+	redeemed, err := s.inviteStorage.RedeemForUser(invite.ID, now, user, &models.AuditEntry{
+		ActorID:   invite.CreatedBy,
+		Action:    models.AuditActionRedeemInvite,
+		Details:   fmt.Sprintf("invite %d (%s)", invite.ID, invite.Role),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !redeemed {
+		return nil, ErrInvalidInvite
+	}
+	return user, nil
+}
+
+func (s *InviteService) CreateInvite(admin *models.User, role models.Role, maxUses int, expiresAt *time.Time, note string) (string, *models.Invite, error) {
+	if role == "" {
+		role = models.RoleFree
+	}
+	if !role.Valid() {
+		return "", nil, ErrInvalidRole
+	}
+	if maxUses < 0 {
+		return "", nil, ErrInvalidMaxUses
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, ErrExpiryInThePast
+	}
+
+	code, err := utils.GenerateOpaqueToken(12)
+	if err != nil {
+		return "", nil, err
+	}
+	invite := &models.Invite{
+		Prefix:    code[:invitePrefixLength],
+		CodeHash:  utils.HashToken(code),
+		Role:      role,
+		Note:      strings.TrimSpace(note),
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+		CreatedBy: admin.ID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.inviteStorage.Create(invite); err != nil {
+		return "", nil, err
+	}
+	return code, invite, nil
+}
+
+func (s *InviteService) Invites() ([]*models.Invite, error) {
+	return s.inviteStorage.Invites()
+}
+
+func (s *InviteService) RevokeInvite(inviteID int) error {
+	revoked, err := s.inviteStorage.Revoke(inviteID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInviteNotFound
+	}
+	return nil
+}
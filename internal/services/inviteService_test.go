@@ -0,0 +1,124 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/passwords"
+	"louderspace/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestRegistrationModes(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
+
+	closed := NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationClosed)
+	_, err := closed.Register("testuser", "Tr0ub4dor-and-3", "test@example.com", "")
+	assert.ErrorIs(t, err, ErrRegistrationClosed)
+
+	inviteOnly := NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationInviteOnly)
+	_, err = inviteOnly.Register("testuser", "Tr0ub4dor-and-3", "test@example.com", "")
+	assert.ErrorIs(t, err, ErrInviteRequired)
+	_, err = inviteOnly.Register("testuser", "Tr0ub4dor-and-3", "test@example.com", "not-a-code")
+	assert.ErrorIs(t, err, ErrInvalidInvite)
+
+	open := NewInviteService(repositories.NewMockInviteStorage(userStorage), userService, models.RegistrationOpen)
+	user, err := open.Register("testuser", "Tr0ub4dor-and-3", "test@example.com", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleFree, user.Role)
+}
+
+func TestInviteRedemption(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	service := NewInviteService(repositories.NewMockInviteStorage(userStorage), NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher()), models.RegistrationInviteOnly)
+	auditStorage := userStorage.AuditLog
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(admin))
+
+	_, _, err := service.CreateInvite(admin, "owner", 1, nil, "")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+	_, _, err = service.CreateInvite(admin, models.RoleFree, -1, nil, "")
+	assert.ErrorIs(t, err, ErrInvalidMaxUses)
+	past := time.Now().Add(-time.Hour)
+	_, _, err = service.CreateInvite(admin, models.RoleFree, 1, &past, "")
+	assert.ErrorIs(t, err, ErrExpiryInThePast)
+
+	code, invite, err := service.CreateInvite(admin, models.RolePremium, 1, nil, "beta tester")
+	assert.NoError(t, err)
+	assert.Equal(t, code[:invitePrefixLength], invite.Prefix)
+
+	user, err := service.Register("tester", "Tr0ub4dor-and-3", "tester@example.com", code)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RolePremium, user.Role)
+	stored, _ := userStorage.UserByID(user.ID)
+	assert.Equal(t, models.RolePremium, stored.Role)
+
+	entries, err := auditStorage.EntriesForUser(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.AuditActionRedeemInvite, entries[0].Action)
+	assert.Equal(t, admin.ID, entries[0].ActorID)
+
+	// Single use: the second redemption fails and creates no account.
+	_, err = service.Register("second", "Tr0ub4dor-and-3", "second@example.com", code)
+	assert.ErrorIs(t, err, ErrInvalidInvite)
+	_, err = userStorage.UserByUsername("second")
+	assert.Error(t, err)
+}
+
+func TestFailedRegistrationKeepsInvite(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	service := NewInviteService(repositories.NewMockInviteStorage(userStorage), NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher()), models.RegistrationInviteOnly)
+	auditStorage := userStorage.AuditLog
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	taken := &models.User{Username: "tester", Email: "taken@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(admin))
+	assert.NoError(t, userStorage.Save(taken))
+
+	code, _, err := service.CreateInvite(admin, models.RolePremium, 1, nil, "")
+	assert.NoError(t, err)
+	_, err = service.Register("tester", "Tr0ub4dor-and-3", "tester@example.com", code)
+	assert.Error(t, err)
+	_, err = service.Register("newcomer", "short", "newcomer@example.com", code)
+	assert.Error(t, err)
+	assert.Empty(t, auditStorage.Entries)
+
+	user, err := service.Register("newcomer", "Tr0ub4dor-and-3", "newcomer@example.com", code)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RolePremium, user.Role)
+}
+
+func TestInviteLimits(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	service := NewInviteService(repositories.NewMockInviteStorage(userStorage), NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher()), models.RegistrationInviteOnly)
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(admin))
+
+	code, _, err := service.CreateInvite(admin, models.RoleFree, 2, nil, "")
+	assert.NoError(t, err)
+	for _, name := range []string{"one", "two"} {
+		_, err = service.Register(name, "Tr0ub4dor-and-3", name+"@example.com", code)
+		assert.NoError(t, err)
+	}
+	_, err = service.Register("three", "Tr0ub4dor-and-3", "three@example.com", code)
+	assert.ErrorIs(t, err, ErrInvalidInvite)
+
+	soon := time.Now().Add(50 * time.Millisecond)
+	code, _, err = service.CreateInvite(admin, models.RoleFree, 0, &soon, "")
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = service.Register("late", "Tr0ub4dor-and-3", "late@example.com", code)
+	assert.ErrorIs(t, err, ErrInvalidInvite)
+
+	code, invite, err := service.CreateInvite(admin, models.RoleFree, 0, nil, "")
+	assert.NoError(t, err)
+	assert.NoError(t, service.RevokeInvite(invite.ID))
+	assert.ErrorIs(t, service.RevokeInvite(invite.ID), ErrInviteNotFound)
+	_, err = service.Register("revoked", "Tr0ub4dor-and-3", "revoked@example.com", code)
+	assert.ErrorIs(t, err, ErrInvalidInvite)
+
+	invites, err := service.Invites()
+	assert.NoError(t, err)
+	assert.Len(t, invites, 3)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"time"
+)
+
+const (
+	usernameFailureThreshold = 5
+	ipFailureThreshold       = 20
+	loginFailureWindow       = 24 * time.Hour
+	baseLockout              = time.Minute
+	maxLockout               = time.Hour
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+type LoginThrottling interface {
+	// Reserve counts an attempt against the username and the IP before the credentials are
+	// checked, and returns ErrTooManyLoginAttempts while either is locked out.
+	Reserve(username, ip string) (time.Time, error)
+	// Release takes back the attempt of a login answered with a two-factor challenge.
+	Release(username, ip string) error
+	RecordSuccess(username, ip string) error
+	Unlock(admin *models.User, userID int) error
+}
+
+type LoginThrottleService struct {
+	attemptStorage repositories.LoginAttemptStorage
+	userStorage    repositories.UserStorage
+	auditStorage   repositories.AuditStorage
+}
+
+func NewLoginThrottleService(attemptStorage repositories.LoginAttemptStorage, userStorage repositories.UserStorage, auditStorage repositories.AuditStorage) LoginThrottling {
+	return &LoginThrottleService{attemptStorage, userStorage, auditStorage}
+}
+
+// usernameKey hashes the username so that any length fits in login_attempts.key.
+func usernameKey(username string) string {
+	return "user:" + utils.HashToken(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+type throttleKey struct {
+	key       string
+	threshold int
+}
+
+func throttleKeys(username, ip string) []throttleKey {
+	return []throttleKey{
+		{usernameKey(username), usernameFailureThreshold},
+		{ipKey(ip), ipFailureThreshold},
+	}
+}
+
+// Reserve counts the attempt up front, so parallel requests cannot all pass the check.
+// Every attempt after a lock ends doubles the lockout, up to maxLockout.
+func (s *LoginThrottleService) Reserve(username, ip string) (time.Time, error) {
+	now := time.Now()
+	var reserved []throttleKey
+	for _, k := range throttleKeys(username, ip) {
+		threshold := k.threshold
+		attempt, ok, err := s.attemptStorage.ReserveAttempt(k.key, now, loginFailureWindow, func(failures int) time.Duration {
+			if failures < threshold {
+				return 0
+			}
+			return lockoutDuration(failures - threshold)
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !ok {
+			s.release(reserved)
+			return *attempt.LockedUntil, ErrTooManyLoginAttempts
+		}
+		if attempt.LockedUntil != nil {
+			logger.Info("Locked out login for", k.key, "until", *attempt.LockedUntil)
+		}
+		reserved = append(reserved, k)
+	}
+	return time.Time{}, nil
+}
+
+func (s *LoginThrottleService) Release(username, ip string) error {
+	return s.release(throttleKeys(username, ip))
+}
+
+func (s *LoginThrottleService) release(keys []throttleKey) error {
+	for _, k := range keys {
+		if err := s.attemptStorage.ReleaseAttempt(k.key, k.threshold); err != nil {
+			logger.Error("Failed to release login attempt for", k.key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess only gives the IP its attempt back, so an attacker cannot reset it by
+// logging into their own account between guesses.
+func (s *LoginThrottleService) RecordSuccess(username, ip string) error {
+	if err := s.attemptStorage.Reset(usernameKey(username)); err != nil {
+		return err
+	}
+	return s.attemptStorage.ReleaseAttempt(ipKey(ip), ipFailureThreshold)
+}
+
+func (s *LoginThrottleService) Unlock(admin *models.User, userID int) error {
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.attemptStorage.Reset(usernameKey(user.Username)); err != nil {
+		return err
+	}
+	return s.auditStorage.Record(&models.AuditEntry{
+		ActorID:      admin.ID,
+		TargetUserID: userID,
+		Action:       models.AuditActionUnlock,
+		CreatedAt:    time.Now(),
+	})
+}
+
+func lockoutDuration(excessFailures int) time.Duration {
+	lockout := baseLockout
+	for i := 0; i < excessFailures && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout {
+		return maxLockout
+	}
+	return lockout
+}
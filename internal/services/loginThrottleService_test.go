@@ -0,0 +1,116 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoginLockoutAfterThreshold(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), repositories.NewMockUserStorage(), repositories.NewMockAuditStorage())
+
+	for i := 0; i < usernameFailureThreshold; i++ {
+		_, err := service.Reserve("testuser", "192.0.2.1")
+		assert.NoError(t, err)
+	}
+
+	lockedUntil, err := service.Reserve("testuser", "192.0.2.2")
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+	assert.WithinDuration(t, time.Now().Add(baseLockout), lockedUntil, time.Second)
+
+	// Other usernames from the same IP are not affected until the IP threshold is reached.
+	_, err = service.Reserve("otheruser", "192.0.2.1")
+	assert.NoError(t, err)
+}
+
+func TestUsernameKeyFitsAnyUsername(t *testing.T) {
+	// login_attempts.key is VARCHAR(150).
+	assert.LessOrEqual(t, len(usernameKey(strings.Repeat("a", 1000))), 150)
+	assert.NotEqual(t, usernameKey("testuser"), usernameKey("TestUser"))
+}
+
+func TestLoginReserveIsAtomic(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), repositories.NewMockUserStorage(), repositories.NewMockAuditStorage())
+
+	var wg sync.WaitGroup
+	var allowed int32
+	for i := 0; i < 4*usernameFailureThreshold; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.Reserve("testuser", "192.0.2.1"); err == nil {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(usernameFailureThreshold), allowed)
+}
+
+func TestLoginSuccessReleasesAttempt(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), repositories.NewMockUserStorage(), repositories.NewMockAuditStorage())
+
+	// Successful logins do not use up the allowance of a shared IP.
+	for i := 0; i < 2*ipFailureThreshold; i++ {
+		_, err := service.Reserve("testuser", "192.0.2.1")
+		assert.NoError(t, err)
+		assert.NoError(t, service.RecordSuccess("testuser", "192.0.2.1"))
+	}
+
+	// Neither does a right password that was answered with a two-factor challenge.
+	for i := 0; i < 2*usernameFailureThreshold; i++ {
+		_, err := service.Reserve("testuser", "192.0.2.1")
+		assert.NoError(t, err)
+		assert.NoError(t, service.Release("testuser", "192.0.2.1"))
+	}
+}
+
+func TestLoginLockoutBackoff(t *testing.T) {
+	assert.Equal(t, baseLockout, lockoutDuration(0))
+	assert.Equal(t, 2*baseLockout, lockoutDuration(1))
+	assert.Equal(t, 8*baseLockout, lockoutDuration(3))
+	assert.Equal(t, maxLockout, lockoutDuration(50))
+}
+
+func TestLoginLockoutPerIP(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), repositories.NewMockUserStorage(), repositories.NewMockAuditStorage())
+
+	for i := 0; i < ipFailureThreshold; i++ {
+		_, err := service.Reserve("user"+string(rune('a'+i)), "192.0.2.1")
+		assert.NoError(t, err)
+	}
+	_, err := service.Reserve("fresh", "192.0.2.1")
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+	_, err = service.Reserve("fresh", "192.0.2.2")
+	assert.NoError(t, err)
+}
+
+func TestUnlock(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := repositories.NewMockAuditStorage()
+	service := NewLoginThrottleService(repositories.NewMockLoginAttemptStorage(), userStorage, auditStorage)
+
+	admin := &models.User{Username: "admin", Role: models.RoleAdmin}
+	user := &models.User{Username: "testuser", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(admin))
+	assert.NoError(t, userStorage.Save(user))
+
+	for i := 0; i < usernameFailureThreshold; i++ {
+		_, err := service.Reserve(user.Username, "192.0.2.1")
+		assert.NoError(t, err)
+	}
+	_, err := service.Reserve(user.Username, "192.0.2.2")
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+
+	assert.NoError(t, service.Unlock(admin, user.ID))
+	_, err = service.Reserve(user.Username, "192.0.2.2")
+	assert.NoError(t, err)
+
+	assert.Len(t, auditStorage.Entries, 1)
+	assert.Equal(t, models.AuditActionUnlock, auditStorage.Entries[0].Action)
+}
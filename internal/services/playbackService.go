@@ -9,37 +9,46 @@ import (
 )
 
 type PlaybackManagement interface {
-	Play(userID, stationID int) (*models.PlaybackState, error)
-	Pause(userID int) (*models.PlaybackState, error)
-	Skip(userID int) (*models.PlaybackState, error)
-	Rewind(userID int) (*models.PlaybackState, error)
-	GetPlaybackState(userID int) (*models.PlaybackState, error)
+	Play(user *models.User, stationID int) (*models.PlaybackState, error)
+	Pause(user *models.User) (*models.PlaybackState, error)
+	// Skip returns an UpgradeRequiredError once the tier's hourly skips are used up.
+	Skip(user *models.User) (*models.PlaybackState, error)
+	Rewind(user *models.User) (*models.PlaybackState, error)
+	GetPlaybackState(user *models.User) (*models.PlaybackState, error)
 }
 
 type PlaybackService struct {
 	stationStorage repositories.StationStorage
 	userPlayback   map[int]*models.PlaybackState
-	mu             sync.Mutex
+	// skips holds the time of each user's skips within the last hour.
+	skips     map[int][]time.Time
+	lastPrune time.Time
+	mu        sync.Mutex
 }
 
 func NewPlaybackService(stationStorage repositories.StationStorage) PlaybackManagement {
 	return &PlaybackService{
 		stationStorage: stationStorage,
 		userPlayback:   make(map[int]*models.PlaybackState),
+		skips:          make(map[int][]time.Time),
 	}
 }
 
-func (p *PlaybackService) Play(userID, stationID int) (*models.PlaybackState, error) {
+func (p *PlaybackService) Play(user *models.User, stationID int) (*models.PlaybackState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	playbackState, exists := p.userPlayback[userID]
-	if !exists || playbackState.StationID != stationID {
-		station, err := p.stationStorage.ByID(stationID)
-		if err != nil {
-			return nil, err
-		}
+	// Access is checked on every play so a user who lost a station's tier cannot resume it.
+	station, err := p.stationStorage.ByID(stationID)
+	if err != nil {
+		return nil, stationNotFound(err)
+	}
+	if err := checkStationAccess(user, station); err != nil {
+		return nil, err
+	}
 
+	playbackState, exists := p.userPlayback[user.ID]
+	if !exists || playbackState.StationID != stationID {
 		songs, err := p.stationStorage.SongsByTags(station.Tags)
 		if err != nil {
 			return nil, err
@@ -50,14 +59,14 @@ func (p *PlaybackService) Play(userID, stationID int) (*models.PlaybackState, er
 		}
 
 		playbackState = &models.PlaybackState{
-			UserID:       userID,
+			UserID:       user.ID,
 			StationID:    stationID,
 			CurrentSong:  songs[0],
 			SongQueue:    songs[1:],
 			IsPlaying:    true,
 			PlaybackTime: time.Now(),
 		}
-		p.userPlayback[userID] = playbackState
+		p.userPlayback[user.ID] = playbackState
 	} else {
 		playbackState.IsPlaying = true
 	}
@@ -65,47 +74,67 @@ func (p *PlaybackService) Play(userID, stationID int) (*models.PlaybackState, er
 	return playbackState, nil
 }
 
-func (p *PlaybackService) Pause(userID int) (*models.PlaybackState, error) {
+func (p *PlaybackService) Pause(user *models.User) (*models.PlaybackState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	playbackState, exists := p.userPlayback[userID]
-	if !exists {
-		return nil, errors.New("no playback state found for this user")
+	playbackState, err := p.accessiblePlayback(user)
+	if err != nil {
+		return nil, err
 	}
 
 	playbackState.IsPlaying = false
 	return playbackState, nil
 }
 
-func (p *PlaybackService) Skip(userID int) (*models.PlaybackState, error) {
+func (p *PlaybackService) Skip(user *models.User) (*models.PlaybackState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	playbackState, exists := p.userPlayback[userID]
-	if !exists {
-		return nil, errors.New("no playback state found for this user")
+	playbackState, err := p.accessiblePlayback(user)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(playbackState.SongQueue) == 0 {
 		return nil, errors.New("no more songs in the queue")
 	}
 
+	now := time.Now()
+	p.pruneSkips(now)
+	limit := user.Role.Entitlements().SkipsPerHour
+	if limit != models.Unlimited {
+		recent := p.skips[user.ID][:0]
+		for _, skippedAt := range p.skips[user.ID] {
+			if now.Sub(skippedAt) < time.Hour {
+				recent = append(recent, skippedAt)
+			}
+		}
+		p.skips[user.ID] = recent
+		if len(recent) >= limit {
+			err := upgradeRequired(user, models.FeatureSkips, limit)
+			retryAfter := recent[0].Add(time.Hour)
+			err.RetryAfter = &retryAfter
+			return nil, err
+		}
+		p.skips[user.ID] = append(recent, now)
+	}
+
 	playbackState.CurrentSong = playbackState.SongQueue[0]
 	playbackState.SongQueue = playbackState.SongQueue[1:]
-	playbackState.PlaybackTime = time.Now()
+	playbackState.PlaybackTime = now
 	playbackState.IsPlaying = true
 
 	return playbackState, nil
 }
 
-func (p *PlaybackService) Rewind(userID int) (*models.PlaybackState, error) {
+func (p *PlaybackService) Rewind(user *models.User) (*models.PlaybackState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	playbackState, exists := p.userPlayback[userID]
-	if !exists {
-		return nil, errors.New("no playback state found for this user")
+	playbackState, err := p.accessiblePlayback(user)
+	if err != nil {
+		return nil, err
 	}
 
 	if playbackState.CurrentSong == nil {
@@ -116,14 +145,39 @@ func (p *PlaybackService) Rewind(userID int) (*models.PlaybackState, error) {
 	return playbackState, nil
 }
 
-func (p *PlaybackService) GetPlaybackState(userID int) (*models.PlaybackState, error) {
+func (p *PlaybackService) GetPlaybackState(user *models.User) (*models.PlaybackState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	playbackState, exists := p.userPlayback[userID]
+	return p.accessiblePlayback(user)
+}
+
+// accessiblePlayback checks station access on every call. The caller must hold p.mu.
+func (p *PlaybackService) accessiblePlayback(user *models.User) (*models.PlaybackState, error) {
+	playbackState, exists := p.userPlayback[user.ID]
 	if !exists {
 		return nil, errors.New("no playback state found for this user")
 	}
 
+	station, err := p.stationStorage.ByID(playbackState.StationID)
+	if err != nil {
+		return nil, stationNotFound(err)
+	}
+	if err := checkStationAccess(user, station); err != nil {
+		return nil, err
+	}
 	return playbackState, nil
 }
+
+// pruneSkips drops idle users at most once an hour. The caller must hold p.mu.
+func (p *PlaybackService) pruneSkips(now time.Time) {
+	if now.Sub(p.lastPrune) < time.Hour {
+		return
+	}
+	for userID, skips := range p.skips {
+		if len(skips) == 0 || now.Sub(skips[len(skips)-1]) >= time.Hour {
+			delete(p.skips, userID)
+		}
+	}
+	p.lastPrune = now
+}
@@ -5,6 +5,7 @@ import (
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
 	"testing"
+	"time"
 )
 
 func TestPlaybackService_Play(t *testing.T) {
@@ -22,7 +23,7 @@ func TestPlaybackService_Play(t *testing.T) {
 		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
 	}
 
-	playbackState, err := service.Play(1, station.ID)
+	playbackState, err := service.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
 	assert.NoError(t, err)
 	assert.NotNil(t, playbackState)
 	assert.True(t, playbackState.IsPlaying)
@@ -44,8 +45,8 @@ func TestPlaybackService_Pause(t *testing.T) {
 		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
 	}
 
-	service.Play(1, station.ID)
-	playbackState, err := service.Pause(1)
+	service.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	playbackState, err := service.Pause(&models.User{ID: 1, Role: models.RoleFree})
 	assert.NoError(t, err)
 	assert.NotNil(t, playbackState)
 	assert.False(t, playbackState.IsPlaying)
@@ -66,8 +67,8 @@ func TestPlaybackService_Skip(t *testing.T) {
 		{ID: 2, Title: "Chill Song 2", Artist: "Artist 2", Genre: "chill, vibes"},
 	}
 
-	service.Play(1, station.ID)
-	playbackState, err := service.Skip(1)
+	service.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	playbackState, err := service.Skip(&models.User{ID: 1, Role: models.RoleFree})
 	assert.NoError(t, err)
 	assert.NotNil(t, playbackState)
 	assert.Equal(t, "Chill Song 2", playbackState.CurrentSong.Title)
@@ -87,8 +88,8 @@ func TestPlaybackService_Rewind(t *testing.T) {
 		{ID: 1, Title: "Chill Song 1", Artist: "Artist 1", Genre: "chill, beats"},
 	}
 
-	service.Play(1, station.ID)
-	playbackState, err := service.Rewind(1)
+	service.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	playbackState, err := service.Rewind(&models.User{ID: 1, Role: models.RoleFree})
 	assert.NoError(t, err)
 	assert.NotNil(t, playbackState)
 	assert.Equal(t, "Chill Song 1", playbackState.CurrentSong.Title)
@@ -108,9 +109,105 @@ func TestPlaybackService_GetPlaybackState(t *testing.T) {
 		{ID: 1, Title: "Chill Song 1", Artist: "Artist 1", Genre: "chill, beats"},
 	}
 
-	service.Play(1, station.ID)
-	playbackState, err := service.GetPlaybackState(1)
+	service.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	playbackState, err := service.GetPlaybackState(&models.User{ID: 1, Role: models.RoleFree})
 	assert.NoError(t, err)
 	assert.NotNil(t, playbackState)
 	assert.Equal(t, "Chill Song 1", playbackState.CurrentSong.Title)
 }
+
+func TestPlaybackService_SkipLimit(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	service := NewPlaybackService(storage)
+
+	station := &models.Station{Name: "Chill Beats", Tags: []string{"chill"}}
+	storage.Create(station)
+	for i := 1; i <= 20; i++ {
+		storage.Songs = append(storage.Songs, &models.Song{ID: i, Title: "Chill Song", Genre: "chill"})
+	}
+
+	free := &models.User{ID: 1, Role: models.RoleFree}
+	limit := models.RoleFree.Entitlements().SkipsPerHour
+	service.Play(free, station.ID)
+	for i := 0; i < limit; i++ {
+		_, err := service.Skip(free)
+		assert.NoError(t, err)
+	}
+
+	_, err := service.Skip(free)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+	var upgrade *UpgradeRequiredError
+	if assert.ErrorAs(t, err, &upgrade) {
+		assert.Equal(t, models.FeatureSkips, upgrade.Feature)
+		assert.Equal(t, limit, upgrade.Limit)
+		assert.NotNil(t, upgrade.RetryAfter)
+	}
+
+	premium := &models.User{ID: 2, Role: models.RolePremium}
+	service.Play(premium, station.ID)
+	for i := 0; i <= limit; i++ {
+		_, err := service.Skip(premium)
+		assert.NoError(t, err)
+	}
+}
+
+func TestPlaybackService_PremiumStation(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	service := NewPlaybackService(storage)
+
+	station := &models.Station{Name: "Deep Focus", Tags: []string{"focus"}, Premium: true}
+	storage.Create(station)
+	storage.Songs = []*models.Song{{ID: 1, Title: "Focus Song", Genre: "focus"}}
+
+	_, err := service.Play(&models.User{ID: 1, Role: models.RoleFree}, station.ID)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+	_, err = service.Play(&models.User{ID: 3, Role: models.RoleAnalyst}, station.ID)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+
+	state, err := service.Play(&models.User{ID: 2, Role: models.RolePremium}, station.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Focus Song", state.CurrentSong.Title)
+}
+
+func TestPlaybackService_PremiumStationAfterDowngrade(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	service := NewPlaybackService(storage)
+
+	station := &models.Station{Name: "Deep Focus", Tags: []string{"focus"}, Premium: true}
+	storage.Create(station)
+	storage.Songs = []*models.Song{{ID: 1, Title: "Focus Song", Genre: "focus"}, {ID: 2, Title: "Focus Song 2", Genre: "focus"}}
+
+	user := &models.User{ID: 1, Role: models.RolePremium}
+	_, err := service.Play(user, station.ID)
+	assert.NoError(t, err)
+
+	user.Role = models.RoleFree
+	_, err = service.Play(user, station.ID)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+	_, err = service.Skip(user)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+	_, err = service.Pause(user)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+	_, err = service.Rewind(user)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+	_, err = service.GetPlaybackState(user)
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+}
+
+func TestPlaybackService_PrunesIdleSkips(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	service := NewPlaybackService(storage).(*PlaybackService)
+
+	station := &models.Station{Name: "Chill Beats", Tags: []string{"chill"}}
+	storage.Create(station)
+	storage.Songs = []*models.Song{{ID: 1, Title: "Chill Song", Genre: "chill"}, {ID: 2, Title: "Chill Song 2", Genre: "chill"}}
+
+	service.skips[2] = []time.Time{time.Now().Add(-2 * time.Hour)}
+	user := &models.User{ID: 1, Role: models.RoleFree}
+	service.Play(user, station.ID)
+	_, err := service.Skip(user)
+	assert.NoError(t, err)
+
+	assert.NotContains(t, service.skips, 2)
+	assert.Len(t, service.skips[1], 1)
+}
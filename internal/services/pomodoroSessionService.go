@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
 	"time"
@@ -8,11 +9,16 @@ import (
 
 type PomodoroSessionManagement interface {
 	StartSession(session *models.PomodoroSession) error
-	EndSession(sessionID int) error
+	EndSession(userID, sessionID int) error
 	GetSessionsByUser(userID int) ([]*models.PomodoroSession, error)
+	// History returns an UpgradeRequiredError for more history than the tier allows. A zero
+	// since means all the tier allows.
+	History(user *models.User, since time.Time) ([]*models.PomodoroSession, error)
 	GetFocusMetrics(userID int) (int, int, error)
 }
 
+var ErrSessionNotOwned = errors.New("pomodoro session belongs to another user")
+
 type PomodoroSessionService struct {
 	pomodoroRepo repositories.PomodoroSessionStorage
 }
@@ -25,11 +31,14 @@ func (s *PomodoroSessionService) StartSession(session *models.PomodoroSession) e
 	return s.pomodoroRepo.Create(session)
 }
 
-func (s *PomodoroSessionService) EndSession(sessionID int) error {
+func (s *PomodoroSessionService) EndSession(userID, sessionID int) error {
 	session, err := s.pomodoroRepo.ByID(sessionID)
 	if err != nil {
 		return err
 	}
+	if session.UserID != userID {
+		return ErrSessionNotOwned
+	}
 	session.EndTime = time.Now()
 	session.Status = "completed"
 	return s.pomodoroRepo.Update(session)
@@ -39,6 +48,30 @@ func (s *PomodoroSessionService) GetSessionsByUser(userID int) ([]*models.Pomodo
 	return s.pomodoroRepo.ByUserID(userID)
 }
 
+func (s *PomodoroSessionService) History(user *models.User, since time.Time) ([]*models.PomodoroSession, error) {
+	days := user.Role.Entitlements().PomodoroHistoryDays
+	if days != models.Unlimited {
+		earliest := time.Now().AddDate(0, 0, -days)
+		if since.IsZero() {
+			since = earliest
+		} else if since.Before(earliest) {
+			return nil, upgradeRequired(user, models.FeaturePomodoroHistory, days)
+		}
+	}
+
+	sessions, err := s.pomodoroRepo.ByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]*models.PomodoroSession, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.StartTime.Before(since) {
+			history = append(history, session)
+		}
+	}
+	return history, nil
+}
+
 func (s *PomodoroSessionService) GetFocusMetrics(userID int) (int, int, error) {
 	sessions, err := s.pomodoroRepo.ByUserID(userID)
 	if err != nil {
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestPomodoroHistoryDepth(t *testing.T) {
+	storage := repositories.NewMockPomodoroSessionStorage()
+	service := NewPomodoroSessionService(storage)
+
+	storage.Create(&models.PomodoroSession{UserID: 1, StartTime: time.Now().Add(-time.Hour), Status: "completed"})
+	storage.Create(&models.PomodoroSession{UserID: 1, StartTime: time.Now().AddDate(0, 0, -30), Status: "completed"})
+
+	free := &models.User{ID: 1, Role: models.RoleFree}
+	sessions, err := service.History(free, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	_, err = service.History(free, time.Now().AddDate(0, 0, -30))
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+
+	sessions, err = service.History(&models.User{ID: 1, Role: models.RolePremium}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"time"
+)
+
+var (
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrCannotChangeOwnRole = errors.New("admins cannot change their own role")
+)
+
+type RoleManagement interface {
+	ChangeRole(admin *models.User, userID int, role models.Role) (*models.User, error)
+}
+
+type RoleService struct {
+	userStorage  repositories.UserStorage
+	tokenService TokenManagement
+}
+
+func NewRoleService(userStorage repositories.UserStorage, tokenService TokenManagement) RoleManagement {
+	return &RoleService{userStorage, tokenService}
+}
+
+// ChangeRole revokes the user's tokens so the new role applies from their next refresh.
+func (s *RoleService) ChangeRole(admin *models.User, userID int, role models.Role) (*models.User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if admin.ID == userID {
+		return nil, ErrCannotChangeOwnRole
+	}
+
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return nil, userNotFound(err)
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if err := s.userStorage.UpdateRole(userID, role, &models.AuditEntry{
+		ActorID:      admin.ID,
+		TargetUserID: userID,
+		Action:       models.AuditActionChangeRole,
+		Details:      fmt.Sprintf("%s -> %s", user.Role, role),
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return nil, userNotFound(err)
+	}
+	user.Role = role
+
+	if err := s.tokenService.RevokeUserTokens(userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestChangeRole(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := userStorage.AuditLog
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewRoleService(userStorage, tokenService)
+
+	admin := &models.User{Username: "admin", Role: models.RoleAdmin}
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(admin))
+	assert.NoError(t, userStorage.Save(user))
+
+	updated, err := service.ChangeRole(admin, user.ID, models.RolePremium)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RolePremium, updated.Role)
+
+	stored, err := userStorage.UserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RolePremium, stored.Role)
+
+	assert.Len(t, auditStorage.Entries, 1)
+	entry := auditStorage.Entries[0]
+	assert.Equal(t, admin.ID, entry.ActorID)
+	assert.Equal(t, user.ID, entry.TargetUserID)
+	assert.Equal(t, models.AuditActionChangeRole, entry.Action)
+	assert.Equal(t, "free -> premium", entry.Details)
+}
+
+func TestChangeRoleRejectsInvalidChanges(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := userStorage.AuditLog
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewRoleService(userStorage, tokenService)
+
+	admin := &models.User{Username: "admin", Role: models.RoleAdmin}
+	user := &models.User{Username: "listener", Role: models.RoleFree}
+	assert.NoError(t, userStorage.Save(admin))
+	assert.NoError(t, userStorage.Save(user))
+
+	_, err := service.ChangeRole(admin, user.ID, "superuser")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	_, err = service.ChangeRole(admin, admin.ID, models.RoleFree)
+	assert.ErrorIs(t, err, ErrCannotChangeOwnRole)
+
+	_, err = service.ChangeRole(admin, user.ID+100, models.RolePremium)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	assert.Empty(t, auditStorage.Entries)
+}
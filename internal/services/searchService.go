@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	maxSearchTerms     = 8
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+type SearchManagement interface {
+	// Search only returns stations the user can see. A zero limit uses the default.
+	Search(userID int, q string, limit int) (*models.SearchResults, error)
+}
+
+type SearchService struct {
+	songStorage    repositories.SongStorage
+	stationStorage repositories.StationStorage
+	tagStorage     repositories.TagStorage
+}
+
+func NewSearchService(songStorage repositories.SongStorage, stationStorage repositories.StationStorage, tagStorage repositories.TagStorage) SearchManagement {
+	return &SearchService{songStorage, stationStorage, tagStorage}
+}
+
+func (s *SearchService) Search(userID int, q string, limit int) (*models.SearchResults, error) {
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, maxSearchLimit)
+	}
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: the query has no words", ErrInvalidSearch)
+	}
+
+	results := &models.SearchResults{Query: strings.Join(terms, " ")}
+	var err error
+	if results.Songs, err = s.songStorage.Search(terms, limit); err != nil {
+		return nil, err
+	}
+	if results.Stations, err = s.stationStorage.Search(terms, userID, limit); err != nil {
+		return nil, err
+	}
+	if results.Tags, err = s.tagStorage.Search(terms, limit); err != nil {
+		return nil, err
+	}
+
+	if results.Songs == nil {
+		results.Songs = []*models.Song{}
+	}
+	if results.Stations == nil {
+		results.Stations = []*models.Station{}
+	}
+	if results.Tags == nil {
+		results.Tags = []*models.Tag{}
+	}
+	return results, nil
+}
+
+// searchTerms splits on anything but letters and digits, so terms carry no query operators.
+func searchTerms(q string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	stationStorage := repositories.NewStationStorageMock()
+	tagStorage := repositories.NewMockTagStorage()
+
+	songService := NewSongService(songStorage)
+	for _, song := range []struct{ title, artist, genre string }{
+		{"Midnight Chill", "Nova", "lofi"},
+		{"Chillwave Sunrise", "Aurora", "synthwave"},
+		{"Deep Focus", "Chilly Gonzales", "piano"},
+		{"Rainy Study", "Nova", "lofi"},
+	} {
+		_, err := songService.CreateSong(song.title, song.artist, song.genre, song.title, true, []string{"focus"}, models.SongMetadata{})
+		assert.NoError(t, err)
+	}
+
+	ownerID := 2
+	assert.NoError(t, stationStorage.Create(&models.Station{Name: "Chill Beats", Tags: []string{"chill", "beats"}}))
+	assert.NoError(t, stationStorage.Create(&models.Station{Name: "My Chill Mix", Tags: []string{"chill"}, OwnerID: &ownerID}))
+	assert.NoError(t, stationStorage.Create(&models.Station{Name: "Deep Work", Tags: []string{"focus"}}))
+
+	for _, name := range []string{"chill", "chillhop", "classical"} {
+		assert.NoError(t, tagStorage.Create(&models.Tag{Name: name}))
+	}
+
+	service := NewSearchService(songStorage, stationStorage, tagStorage)
+
+	// Exact words rank above prefixes; personal stations are only found by their owner.
+	results, err := service.Search(1, "Chill", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "chill", results.Query)
+	assert.Equal(t, []string{"Midnight Chill", "Chillwave Sunrise", "Deep Focus"}, songTitles(results.Songs))
+	assert.Len(t, results.Stations, 1)
+	assert.Equal(t, "Chill Beats", results.Stations[0].Name)
+	assert.Equal(t, "chill", results.Tags[0].Name)
+	assert.Len(t, results.Tags, 2)
+
+	results, err = service.Search(2, "chill", 0)
+	assert.NoError(t, err)
+	assert.Len(t, results.Stations, 2)
+
+	// Every term has to match, in any field.
+	results, err = service.Search(1, "nova lofi rain", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Rainy Study"}, songTitles(results.Songs))
+
+	// A typo still finds the song.
+	results, err = service.Search(1, "sunrize", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Chillwave Sunrise"}, songTitles(results.Songs))
+
+	results, err = service.Search(1, "chill", 1)
+	assert.NoError(t, err)
+	assert.Len(t, results.Songs, 1)
+
+	results, err = service.Search(1, "jazz", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results.Songs)
+	assert.NotNil(t, results.Stations)
+}
+
+func TestSearchRejectsInvalidQueries(t *testing.T) {
+	service := NewSearchService(repositories.NewSongStorageMock(), repositories.NewStationStorageMock(), repositories.NewMockTagStorage())
+
+	_, err := service.Search(1, "  &|!:* ", 0)
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+	_, err = service.Search(1, "chill", 500)
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxSongImportRows = 10000
+	// maxSongImportLine bounds a single JSONL line, lyrics and all.
+	maxSongImportLine = 1 << 20
+	// songImportTagSeparator separates the tags in the tags column of a CSV import.
+	songImportTagSeparator = ";"
+)
+
+var ErrInvalidSongImport = errors.New("invalid song import")
+
+type SongImportManagement interface {
+	// Import creates the valid rows of a CSV or JSONL file; an invalid row does not stop the
+	// others. The error is only for files that cannot be read at all.
+	Import(r io.Reader, format models.SongImportFormat, dryRun bool) (*models.SongImportReport, error)
+}
+
+type SongImportService struct {
+	songService SongManagement
+	tagStorage  repositories.TagStorage
+}
+
+func NewSongImportService(songService SongManagement, tagStorage repositories.TagStorage) SongImportManagement {
+	return &SongImportService{songService, tagStorage}
+}
+
+// importRow is a parsed row and the problems found with it so far.
+type importRow struct {
+	models.SongImportRow
+	problems   []string
+	unreadable bool
+}
+
+func (r *importRow) invalid(format string, args ...interface{}) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+func (s *SongImportService) Import(r io.Reader, format models.SongImportFormat, dryRun bool) (*models.SongImportReport, error) {
+	var rows []*importRow
+	var err error
+	switch format {
+	case models.SongImportCSV:
+		rows, err = parseSongImportCSV(r)
+	case models.SongImportJSONL:
+		rows, err = parseSongImportJSONL(r)
+	default:
+		return nil, fmt.Errorf("%w: format must be csv or jsonl", ErrInvalidSongImport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(rows); err != nil {
+		return nil, err
+	}
+
+	report := &models.SongImportReport{DryRun: dryRun, Total: len(rows), Rows: make([]models.SongImportResult, 0, len(rows))}
+	for _, row := range rows {
+		result := models.SongImportResult{Line: row.Line, SunoID: row.SunoID}
+		switch {
+		case len(row.problems) > 0:
+			result.Status = models.SongImportInvalid
+			result.Errors = row.problems
+			report.Invalid++
+		case dryRun:
+			result.Status = models.SongImportValid
+			report.Valid++
+		default:
+			report.Valid++
+			song, err := s.songService.CreateSong(row.Title, row.Artist, row.Genre, row.SunoID, row.IsGenerated, row.Tags, models.SongMetadata{})
+			if err != nil {
+				result.Status = models.SongImportFailed
+				result.Errors = []string{err.Error()}
+				report.Failed++
+			} else {
+				result.Status = models.SongImportCreated
+				result.SongID = song.ID
+				report.Created++
+			}
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	logger.Info(fmt.Sprintf("Imported songs: %d rows, %d created, %d invalid, %d failed (dry run: %t)",
+		report.Total, report.Created, report.Invalid, report.Failed, dryRun))
+	return report, nil
+}
+
+// validate looks up the suno_ids of the whole file in one query.
+func (s *SongImportService) validate(rows []*importRow) error {
+	tags, err := s.tagStorage.GetAllTags()
+	if err != nil {
+		return err
+	}
+	tagNames := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagNames[strings.ToLower(tag.Name)] = tag.Name
+	}
+
+	var sunoIDs []string
+	for _, row := range rows {
+		if !row.unreadable && row.SunoID != "" {
+			sunoIDs = append(sunoIDs, row.SunoID)
+		}
+	}
+	existing, err := s.songService.SongIDsBySunoIDs(sunoIDs)
+	if err != nil {
+		return err
+	}
+
+	sunoIDLines := make(map[string]int)
+	for _, row := range rows {
+		if row.unreadable {
+			continue
+		}
+		checkLength(row, "title", row.Title, 100, true)
+		checkLength(row, "artist", row.Artist, 100, true)
+		checkLength(row, "genre", row.Genre, 50, false)
+		checkLength(row, "suno_id", row.SunoID, 50, true)
+
+		if row.SunoID != "" {
+			if line, seen := sunoIDLines[row.SunoID]; seen {
+				row.invalid("suno_id %q is already on line %d", row.SunoID, line)
+			} else {
+				sunoIDLines[row.SunoID] = row.Line
+				if songID, ok := existing[row.SunoID]; ok {
+					row.invalid("suno_id %q is already song %d", row.SunoID, songID)
+				}
+			}
+		}
+
+		var known []string
+		seen := make(map[string]bool)
+		for _, tag := range row.Tags {
+			name, ok := tagNames[strings.ToLower(strings.TrimSpace(tag))]
+			if !ok {
+				row.invalid("unknown tag %q", tag)
+				continue
+			}
+			if !seen[name] {
+				seen[name] = true
+				known = append(known, name)
+			}
+		}
+		row.Tags = known
+	}
+	return nil
+}
+
+func checkLength(row *importRow, field, value string, max int, required bool) {
+	if required && value == "" {
+		row.invalid("%s is required", field)
+	} else if utf8.RuneCountInString(value) > max {
+		row.invalid("%s is longer than %d characters", field, max)
+	}
+}
+
+var importColumns = map[string]bool{"title": true, "artist": true, "genre": true, "suno_id": true, "is_generated": true, "tags": true}
+
+// catalogExportColumn reports whether the import skips name as an export-only column.
+func catalogExportColumn(name string) bool {
+	for _, column := range catalogCSVHeader {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeCSVCell removes the quote escapeCSVCell puts before a formula-like cell.
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// parseSongImportCSV requires title, artist and suno_id columns, in any order.
+func parseSongImportCSV(r io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidSongImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSongImport, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case importColumns[name]:
+		case catalogExportColumn(name):
+			continue
+		default:
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidSongImport, name)
+		}
+		if _, duplicate := columns[name]; duplicate {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrInvalidSongImport, name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "artist", "suno_id"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidSongImport, required)
+		}
+	}
+
+	var rows []*importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		row := &importRow{SongImportRow: models.SongImportRow{Line: line}}
+		if errors.Is(err, csv.ErrFieldCount) {
+			row.invalid("expected %d fields, got %d", len(header), len(record))
+			row.unreadable = true
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSongImport, err)
+		} else {
+			field := func(name string) string {
+				if i, ok := columns[name]; ok {
+					return unescapeCSVCell(strings.TrimSpace(record[i]))
+				}
+				return ""
+			}
+			row.Title = field("title")
+			row.Artist = field("artist")
+			row.Genre = field("genre")
+			row.SunoID = field("suno_id")
+			if value := field("is_generated"); value != "" {
+				if row.IsGenerated, err = strconv.ParseBool(value); err != nil {
+					row.invalid("is_generated must be true or false")
+				}
+			}
+			for _, tag := range strings.Split(field("tags"), songImportTagSeparator) {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					row.Tags = append(row.Tags, tag)
+				}
+			}
+		}
+		if rows = append(rows, row); len(rows) > maxSongImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidSongImport, maxSongImportRows)
+		}
+	}
+	return rows, nil
+}
+
+// parseSongImportJSONL reads one JSON object per line. Blank lines are skipped.
+func parseSongImportJSONL(r io.Reader) ([]*importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSongImportLine)
+
+	var rows []*importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		row := &importRow{SongImportRow: models.SongImportRow{Line: line}}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.SongImportRow); err != nil {
+			row.invalid("invalid JSON: %v", err)
+			row.unreadable = true
+		}
+		row.Line = line
+		row.Title = strings.TrimSpace(row.Title)
+		row.Artist = strings.TrimSpace(row.Artist)
+		row.Genre = strings.TrimSpace(row.Genre)
+		row.SunoID = strings.TrimSpace(row.SunoID)
+		if rows = append(rows, row); len(rows) > maxSongImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidSongImport, maxSongImportRows)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSongImport, err)
+	}
+	return rows, nil
+}
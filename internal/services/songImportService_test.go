@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"strings"
+	"testing"
+)
+
+func TestImportSongsCSV(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	tagStorage := repositories.NewMockTagStorage()
+	for _, name := range []string{"focus", "Chill"} {
+		assert.NoError(t, tagStorage.Create(&models.Tag{Name: name}))
+	}
+	songService := NewSongService(songStorage)
+	_, err := songService.CreateSong("Existing", "Nova", "lofi", "suno-existing", true, []string{"focus"}, models.SongMetadata{})
+	assert.NoError(t, err)
+	importService := NewSongImportService(songService, tagStorage)
+
+	file := `title,artist,suno_id,tags,is_generated
+Night Drive,Nova,suno-1,focus;chill,true
+"Rain, Again",Aurora,suno-2,FOCUS,false
+Duplicate,Nova,suno-1,focus,true
+Taken,Nova,suno-existing,focus,true
+Unknown Tag,Nova,suno-3,jazz,true
+,Nova,suno-4,focus,maybe
+Short,Row
+`
+
+	report, err := importService.Import(strings.NewReader(file), models.SongImportCSV, true)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 7, report.Total)
+	assert.Equal(t, 2, report.Valid)
+	assert.Equal(t, 5, report.Invalid)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, models.SongImportValid, report.Rows[0].Status)
+	assert.Equal(t, 2, report.Rows[0].Line)
+	assert.Equal(t, []string{`suno_id "suno-1" is already on line 2`}, report.Rows[2].Errors)
+	assert.Equal(t, []string{`suno_id "suno-existing" is already song 1`}, report.Rows[3].Errors)
+	assert.Equal(t, []string{`unknown tag "jazz"`}, report.Rows[4].Errors)
+	assert.ElementsMatch(t, []string{"title is required", "is_generated must be true or false"}, report.Rows[5].Errors)
+	assert.Equal(t, []string{"expected 5 fields, got 2"}, report.Rows[6].Errors)
+	_, err = songStorage.BySunoID("suno-1")
+	assert.Error(t, err, "a dry run creates nothing")
+
+	report, err = importService.Import(strings.NewReader(file), models.SongImportCSV, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 5, report.Invalid)
+	assert.Equal(t, models.SongImportCreated, report.Rows[0].Status)
+
+	song, err := songStorage.BySunoID("suno-1")
+	assert.NoError(t, err)
+	assert.Equal(t, report.Rows[0].SongID, song.ID)
+	assert.True(t, song.IsGenerated)
+	assert.Len(t, song.Tags, 2)
+	assert.Equal(t, "Chill", song.Tags[1].Name, "tags take their stored names")
+	song, err = songStorage.BySunoID("suno-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "Rain, Again", song.Title)
+	assert.False(t, song.IsGenerated)
+}
+
+func TestImportSongsJSONL(t *testing.T) {
+	tagStorage := repositories.NewMockTagStorage()
+	assert.NoError(t, tagStorage.Create(&models.Tag{Name: "focus"}))
+	importService := NewSongImportService(NewSongService(repositories.NewSongStorageMock()), tagStorage)
+
+	file := `{"title": "Night Drive", "artist": "Nova", "suno_id": "suno-1", "tags": ["focus"], "is_generated": true}
+
+{"title": "Typo", "artist": "Nova", "suno-id": "suno-2"}
+{"title": "Broken"
+`
+
+	report, err := importService.Import(strings.NewReader(file), models.SongImportJSONL, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 2, report.Invalid)
+	assert.Equal(t, []int{1, 3, 4}, []int{report.Rows[0].Line, report.Rows[1].Line, report.Rows[2].Line})
+	assert.Contains(t, report.Rows[1].Errors[0], "invalid JSON")
+	assert.Contains(t, report.Rows[2].Errors[0], "invalid JSON")
+}
+
+func TestImportSongsRejectsUnreadableFiles(t *testing.T) {
+	importService := NewSongImportService(NewSongService(repositories.NewSongStorageMock()), repositories.NewMockTagStorage())
+
+	for name, file := range map[string]string{
+		"empty":          "",
+		"missing column": "title,artist\nNight Drive,Nova\n",
+		"unknown column": "title,artist,suno_id,mood\nNight Drive,Nova,suno-1,calm\n",
+	} {
+		_, err := importService.Import(strings.NewReader(file), models.SongImportCSV, true)
+		assert.ErrorIs(t, err, ErrInvalidSongImport, name)
+	}
+	_, err := importService.Import(strings.NewReader(""), "xml", true)
+	assert.ErrorIs(t, err, ErrInvalidSongImport)
+}
+
+type failingSunoIDLookup struct {
+	repositories.SongStorage
+}
+
+func (failingSunoIDLookup) IDsBySunoIDs(sunoIDs []string) (map[string]int, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestImportSongsSurfacesLookupErrors(t *testing.T) {
+	importService := NewSongImportService(NewSongService(failingSunoIDLookup{repositories.NewSongStorageMock()}), repositories.NewMockTagStorage())
+
+	_, err := importService.Import(strings.NewReader("title,artist,suno_id\nNight Drive,Nova,suno-1\n"), models.SongImportCSV, true)
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestImportSongsFromCatalogExport(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	songService := NewSongService(songStorage)
+	_, err := songService.CreateSong(`=HYPERLINK("http://evil.example")`, "-Nova", "lofi", "suno-1", true, []string{"focus"}, models.SongMetadata{DurationSeconds: 90})
+	assert.NoError(t, err)
+	var export bytes.Buffer
+	assert.NoError(t, NewCatalogExportService(songStorage, repositories.NewStationStorageMock()).ExportCatalog(&export, models.CatalogCSV, 0, ""))
+
+	tagStorage := repositories.NewMockTagStorage()
+	assert.NoError(t, tagStorage.Create(&models.Tag{Name: "focus"}))
+	importedStorage := repositories.NewSongStorageMock()
+	importService := NewSongImportService(NewSongService(importedStorage), tagStorage)
+	report, err := importService.Import(&export, models.SongImportCSV, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	imported, err := importedStorage.BySunoID("suno-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `=HYPERLINK("http://evil.example")`, imported.Title)
+	assert.Equal(t, "-Nova", imported.Artist)
+}
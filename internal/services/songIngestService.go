@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/suno"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clipStatusComplete is the status of a Suno clip whose audio is final.
+const clipStatusComplete = "complete"
+
+var (
+	ErrMissingTaskID   = errors.New("task_id is required")
+	ErrClipsNotReady   = errors.New("the Suno task has no finished clips yet")
+	ErrNoClipsIngested = errors.New("the Suno task has no clips to ingest")
+)
+
+// ClipFetcher loads the clips of a Suno generation task. suno.GetClip is the real one.
+type ClipFetcher func(taskID string) (*suno.GetClipResponse, error)
+
+type SongIngestion interface {
+	// IngestTask adds a task's finished clips as generated songs, skipping existing ones.
+	IngestTask(taskID, genre string, tags []string) ([]*models.Song, error)
+}
+
+type SongIngestService struct {
+	songService SongManagement
+	fetchClips  ClipFetcher
+}
+
+func NewSongIngestService(songService SongManagement, fetchClips ClipFetcher) SongIngestion {
+	return &SongIngestService{songService, fetchClips}
+}
+
+func (s *SongIngestService) IngestTask(taskID, genre string, tags []string) ([]*models.Song, error) {
+	if strings.TrimSpace(taskID) == "" {
+		return nil, ErrMissingTaskID
+	}
+	response, err := s.fetchClips(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Suno task %s: %w", taskID, err)
+	}
+
+	// Clips come keyed by ID; ingest them in a stable order.
+	ids := make([]string, 0, len(response.Data.Clips))
+	for id := range response.Data.Clips {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	songs := []*models.Song{}
+	pending := 0
+	for _, id := range ids {
+		clip := response.Data.Clips[id]
+		if clip.ID == "" {
+			clip.ID = id
+		}
+		if clip.Status != clipStatusComplete {
+			if clip.Status != "error" {
+				pending++
+			}
+			continue
+		}
+		if existing, err := s.songService.GetSongBySunoID(clip.ID); err == nil && existing != nil {
+			logger.Info("Skipping Suno clip that is already a song:", clip.ID)
+			continue
+		}
+
+		artist := clip.DisplayName
+		if artist == "" {
+			artist = "Suno"
+		}
+		song, err := s.songService.CreateSong(clip.Title, artist, genre, clip.ID, true, tags, clipMetadata(clip))
+		if err != nil {
+			return songs, err
+		}
+		songs = append(songs, song)
+	}
+
+	if len(songs) == 0 && pending > 0 {
+		return nil, ErrClipsNotReady
+	}
+	if len(songs) == 0 && len(response.Data.Clips) == 0 {
+		return nil, ErrNoClipsIngested
+	}
+	return songs, nil
+}
+
+var (
+	bpmPattern = regexp.MustCompile(`(?i)\b(\d{2,3})\s*bpm\b`)
+	keyPattern = regexp.MustCompile(`(?i)\b([a-g])(#|♯|b|♭)?\s*(major|minor|maj|min)\b`)
+)
+
+// clipMetadata reads the tempo and key from Suno's style tags, e.g. "lofi, 85 bpm, A minor".
+func clipMetadata(clip suno.Clip) models.SongMetadata {
+	metadata := models.SongMetadata{
+		DurationSeconds: clip.Metadata.Duration,
+		AudioURL:        clip.AudioURL,
+		ImageURL:        clip.ImageURL,
+		ImageLargeURL:   clip.ImageLargeURL,
+		ModelVersion:    clip.MajorModelVersion,
+		Lyrics:          strings.TrimSpace(clip.Metadata.Prompt),
+	}
+	if metadata.ModelVersion == "" {
+		metadata.ModelVersion = clip.ModelName
+	}
+
+	if match := bpmPattern.FindStringSubmatch(clip.Metadata.Tags); match != nil {
+		if bpm, err := strconv.Atoi(match[1]); err == nil && bpm > 0 && bpm <= 400 {
+			metadata.BPM = &bpm
+		}
+	}
+	if match := keyPattern.FindStringSubmatch(clip.Metadata.Tags); match != nil {
+		key := strings.ToUpper(match[1])
+		switch match[2] {
+		case "#", "♯":
+			key += "#"
+		case "b", "B", "♭":
+			key += "b"
+		}
+		if strings.HasPrefix(strings.ToLower(match[3]), "maj") {
+			metadata.Key = key + " major"
+		} else {
+			metadata.Key = key + " minor"
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/repositories"
+	"louderspace/suno"
+	"testing"
+)
+
+func sunoTask(clips ...suno.Clip) *suno.GetClipResponse {
+	response := &suno.GetClipResponse{}
+	response.Data.Clips = make(map[string]suno.Clip)
+	for _, clip := range clips {
+		response.Data.Clips[clip.ID] = clip
+	}
+	return response
+}
+
+func TestIngestTask(t *testing.T) {
+	songStorage := repositories.NewSongStorageMock()
+	songService := NewSongService(songStorage)
+
+	first := suno.Clip{
+		ID:                "clip-a",
+		Status:            "complete",
+		Title:             "Night Drive",
+		DisplayName:       "nova",
+		AudioURL:          "https://cdn.example.com/clip-a.mp3",
+		ImageURL:          "https://cdn.example.com/clip-a.jpg",
+		ImageLargeURL:     "https://cdn.example.com/clip-a-large.jpg",
+		MajorModelVersion: "v3.5",
+		ModelName:         "chirp-v3-5",
+		Metadata: suno.Metadata{
+			Tags:     "lofi, chill, 85 BPM, F# minor",
+			Prompt:   "[Verse]\nCity lights\n",
+			Duration: 161.4,
+		},
+	}
+	second := suno.Clip{
+		ID:        "clip-b",
+		Status:    "complete",
+		Title:     "Night Drive",
+		ModelName: "chirp-v3",
+		Metadata:  suno.Metadata{Tags: "ambient piano in Bb major", Duration: 98},
+	}
+	failed := suno.Clip{ID: "clip-c", Status: "error"}
+
+	ingest := NewSongIngestService(songService, func(taskID string) (*suno.GetClipResponse, error) {
+		assert.Equal(t, "task-1", taskID)
+		return sunoTask(first, second, failed), nil
+	})
+
+	songs, err := ingest.IngestTask("task-1", "lofi", []string{"focus"})
+	assert.NoError(t, err)
+	assert.Len(t, songs, 2)
+
+	song := songs[0]
+	assert.Equal(t, "clip-a", song.SunoID)
+	assert.Equal(t, "nova", song.Artist)
+	assert.Equal(t, "lofi", song.Genre)
+	assert.True(t, song.IsGenerated)
+	assert.Equal(t, 161.4, song.DurationSeconds)
+	assert.Equal(t, first.AudioURL, song.AudioURL)
+	assert.Equal(t, first.ImageURL, song.ImageURL)
+	assert.Equal(t, first.ImageLargeURL, song.ImageLargeURL)
+	assert.Equal(t, "v3.5", song.ModelVersion)
+	if assert.NotNil(t, song.BPM) {
+		assert.Equal(t, 85, *song.BPM)
+	}
+	assert.Equal(t, "F# minor", song.Key)
+	assert.Nil(t, song.Energy)
+	assert.Equal(t, "[Verse]\nCity lights", song.Lyrics)
+
+	song = songs[1]
+	assert.Equal(t, "Suno", song.Artist)
+	assert.Equal(t, "chirp-v3", song.ModelVersion)
+	assert.Nil(t, song.BPM)
+	assert.Equal(t, "Bb major", song.Key)
+
+	stored, err := songStorage.BySunoID("clip-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "F# minor", stored.Key)
+
+	// Ingesting the same task again adds nothing.
+	songs, err = ingest.IngestTask("task-1", "lofi", []string{"focus"})
+	assert.NoError(t, err)
+	assert.Empty(t, songs)
+}
+
+func TestIngestTaskErrors(t *testing.T) {
+	songService := NewSongService(repositories.NewSongStorageMock())
+
+	pending := NewSongIngestService(songService, func(string) (*suno.GetClipResponse, error) {
+		return sunoTask(suno.Clip{ID: "clip-a", Status: "streaming"}), nil
+	})
+	_, err := pending.IngestTask("task-1", "lofi", nil)
+	assert.ErrorIs(t, err, ErrClipsNotReady)
+	_, err = pending.IngestTask(" ", "lofi", nil)
+	assert.ErrorIs(t, err, ErrMissingTaskID)
+
+	empty := NewSongIngestService(songService, func(string) (*suno.GetClipResponse, error) {
+		return sunoTask(), nil
+	})
+	_, err = empty.IngestTask("task-1", "lofi", nil)
+	assert.ErrorIs(t, err, ErrNoClipsIngested)
+
+	unreachable := NewSongIngestService(songService, func(string) (*suno.GetClipResponse, error) {
+		return nil, errors.New("connection refused")
+	})
+	_, err = unreachable.IngestTask("task-1", "lofi", nil)
+	assert.ErrorContains(t, err, "connection refused")
+}
@@ -1,17 +1,36 @@
 package services
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"louderspace/internal/logger"
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
 	"time"
 )
 
+const (
+	defaultSongPageSize = 50
+	maxSongPageSize     = 200
+)
+
+var (
+	ErrInvalidSongFilter   = errors.New("invalid song filter")
+	ErrInvalidSongMetadata = errors.New("invalid song metadata")
+)
+
 type SongManagement interface {
-	CreateSong(title, artist, genre, sunoID string, isGenerated bool, tags []string) (*models.Song, error)
+	CreateSong(title, artist, genre, sunoID string, isGenerated bool, tags []string, metadata models.SongMetadata) (*models.Song, error)
 	GetSongByID(songID int) (*models.Song, error)
 	GetSongBySunoID(sunoID string) (*models.Song, error)
+	// SongIDsBySunoIDs looks up which of the suno_ids are already in the catalog, in one query.
+	SongIDsBySunoIDs(sunoIDs []string) (map[string]int, error)
 	GetAllSongs() ([]*models.Song, error)
+	// ListSongs takes the NextCursor of the previous page, or "" for the first one. A zero
+	// limit uses the default page size.
+	ListSongs(filter models.SongFilter, cursor string) (*models.SongPage, error)
 	GetSongsForStation(stationID int) ([]*models.Song, error)
 	UpdateSong(song *models.Song, tags []string) (*models.Song, error)
 	DeleteSong(id int) error
@@ -25,14 +44,18 @@ func NewSongService(songStorage repositories.SongStorage) SongManagement {
 	return &SongService{songStorage}
 }
 
-func (s *SongService) CreateSong(title, artist, genre, sunoID string, isGenerated bool, tags []string) (*models.Song, error) {
+func (s *SongService) CreateSong(title, artist, genre, sunoID string, isGenerated bool, tags []string, metadata models.SongMetadata) (*models.Song, error) {
+	if err := validateSongMetadata(metadata); err != nil {
+		return nil, err
+	}
 	song := &models.Song{
-		Title:       title,
-		Artist:      artist,
-		Genre:       genre,
-		SunoID:      sunoID,
-		IsGenerated: isGenerated,
-		CreatedAt:   time.Now(),
+		Title:        title,
+		Artist:       artist,
+		Genre:        genre,
+		SunoID:       sunoID,
+		IsGenerated:  isGenerated,
+		CreatedAt:    time.Now(),
+		SongMetadata: metadata,
 	}
 	if err := s.songStorage.Create(song, tags); err != nil {
 		logger.Error("Failed to create song:", err)
@@ -50,6 +73,9 @@ func (s *SongService) CreateSong(title, artist, genre, sunoID string, isGenerate
 }
 
 func (s *SongService) UpdateSong(song *models.Song, tags []string) (*models.Song, error) {
+	if err := validateSongMetadata(song.SongMetadata); err != nil {
+		return nil, err
+	}
 
 	if err := s.songStorage.Update(song, tags); err != nil {
 		logger.Error("Failed to update song:", err)
@@ -67,6 +93,19 @@ func (s *SongService) UpdateSong(song *models.Song, tags []string) (*models.Song
 	return song, nil
 }
 
+func validateSongMetadata(metadata models.SongMetadata) error {
+	if metadata.DurationSeconds < 0 {
+		return fmt.Errorf("%w: duration_seconds cannot be negative", ErrInvalidSongMetadata)
+	}
+	if metadata.BPM != nil && (*metadata.BPM <= 0 || *metadata.BPM > 400) {
+		return fmt.Errorf("%w: bpm must be between 1 and 400", ErrInvalidSongMetadata)
+	}
+	if metadata.Energy != nil && (*metadata.Energy < 0 || *metadata.Energy > 1) {
+		return fmt.Errorf("%w: energy must be between 0 and 1", ErrInvalidSongMetadata)
+	}
+	return nil
+}
+
 func (s *SongService) GetSongByID(songID int) (*models.Song, error) {
 	return s.songStorage.ByID(songID)
 }
@@ -75,10 +114,96 @@ func (s *SongService) GetSongBySunoID(sunoID string) (*models.Song, error) {
 	return s.songStorage.BySunoID(sunoID)
 }
 
+func (s *SongService) SongIDsBySunoIDs(sunoIDs []string) (map[string]int, error) {
+	return s.songStorage.IDsBySunoIDs(sunoIDs)
+}
+
 func (s *SongService) GetAllSongs() ([]*models.Song, error) {
 	return s.songStorage.All()
 }
 
+func (s *SongService) ListSongs(filter models.SongFilter, cursor string) (*models.SongPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultSongPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > maxSongPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSongFilter, maxSongPageSize)
+	}
+	switch filter.Sort {
+	case "", models.SongSortID, models.SongSortTitle, models.SongSortCreatedAt, models.SongSortPlayCount:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidSongFilter, filter.Sort)
+	}
+	if cursor != "" {
+		after, err := decodeSongCursor(cursor, filter)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	// One extra song tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	songs, err := s.songStorage.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.SongPage{Songs: songs}
+	if len(songs) > limit {
+		page.Songs = songs[:limit]
+		page.NextCursor = encodeSongCursor(page.Songs[limit-1], filter)
+	}
+	if page.Songs == nil {
+		page.Songs = []*models.Song{}
+	}
+	return page, nil
+}
+
+// songCursor records the sort order, so a cursor cannot be reused with another order.
+type songCursor struct {
+	Sort       string     `json:"s,omitempty"`
+	Descending bool       `json:"d,omitempty"`
+	ID         int        `json:"id"`
+	Title      string     `json:"t,omitempty"`
+	CreatedAt  *time.Time `json:"c,omitempty"`
+	PlayCount  int        `json:"p,omitempty"`
+}
+
+func encodeSongCursor(last *models.Song, filter models.SongFilter) string {
+	cursor := songCursor{Sort: filter.Sort, Descending: filter.Descending, ID: last.ID}
+	switch filter.Sort {
+	case models.SongSortTitle:
+		cursor.Title = last.Title
+	case models.SongSortCreatedAt:
+		cursor.CreatedAt = &last.CreatedAt
+	case models.SongSortPlayCount:
+		cursor.PlayCount = last.PlayCount
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSongCursor(encoded string, filter models.SongFilter) (*models.Song, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSongFilter)
+	}
+	var cursor songCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSongFilter)
+	}
+	if cursor.Sort != filter.Sort || cursor.Descending != filter.Descending {
+		return nil, fmt.Errorf("%w: cursor belongs to another sort order", ErrInvalidSongFilter)
+	}
+	after := &models.Song{ID: cursor.ID, Title: cursor.Title, PlayCount: cursor.PlayCount}
+	if cursor.CreatedAt != nil {
+		after.CreatedAt = *cursor.CreatedAt
+	}
+	return after, nil
+}
+
 func (s *SongService) GetSongsForStation(stationID int) ([]*models.Song, error) {
 	return s.songStorage.ByStationID(stationID)
 }
@@ -5,13 +5,14 @@ import (
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
 	"testing"
+	"time"
 )
 
 func TestCreateSong(t *testing.T) {
 	storage := repositories.NewSongStorageMock()
 	service := NewSongService(storage)
 
-	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"})
+	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"}, models.SongMetadata{})
 	assert.NoError(t, err)
 	assert.NotNil(t, song)
 	assert.Equal(t, "Synth Beats", song.Title)
@@ -23,7 +24,7 @@ func TestUpdateSong(t *testing.T) {
 	storage := repositories.NewSongStorageMock()
 	service := NewSongService(storage)
 
-	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"})
+	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"}, models.SongMetadata{})
 	assert.NoError(t, err)
 
 	updatedSong, err := service.UpdateSong(&models.Song{
@@ -45,7 +46,7 @@ func TestDeleteSong(t *testing.T) {
 	storage := repositories.NewSongStorageMock()
 	service := NewSongService(storage)
 
-	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"})
+	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"}, models.SongMetadata{})
 	assert.NoError(t, err)
 
 	err = service.DeleteSong(song.ID)
@@ -59,7 +60,7 @@ func TestGetSongByID(t *testing.T) {
 	storage := repositories.NewSongStorageMock()
 	service := NewSongService(storage)
 
-	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"})
+	song, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"}, models.SongMetadata{})
 	assert.NoError(t, err)
 
 	fetchedSong, err := service.GetSongByID(song.ID)
@@ -74,7 +75,7 @@ func TestGetSongBySunoID(t *testing.T) {
 	storage := repositories.NewSongStorageMock()
 	service := NewSongService(storage)
 
-	_, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"})
+	_, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"}, models.SongMetadata{})
 	assert.NoError(t, err)
 
 	fetchedSong, err := service.GetSongBySunoID("123")
@@ -89,12 +90,90 @@ func TestGetAllSongs(t *testing.T) {
 	storage := repositories.NewSongStorageMock()
 	service := NewSongService(storage)
 
-	_, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"})
+	_, err := service.CreateSong("Synth Beats", "Artist 1", "synth", "123", true, []string{"electronic", "beats"}, models.SongMetadata{})
 	assert.NoError(t, err)
-	_, err = service.CreateSong("Lo-fi Chill", "Artist 2", "lofi", "456", true, []string{"chill", "lofi"})
+	_, err = service.CreateSong("Lo-fi Chill", "Artist 2", "lofi", "456", true, []string{"chill", "lofi"}, models.SongMetadata{})
 	assert.NoError(t, err)
 
 	songs, err := service.GetAllSongs()
 	assert.NoError(t, err)
 	assert.Len(t, songs, 2)
 }
+
+func songTitles(songs []*models.Song) []string {
+	titles := []string{}
+	for _, song := range songs {
+		titles = append(titles, song.Title)
+	}
+	return titles
+}
+
+func TestListSongs(t *testing.T) {
+	storage := repositories.NewSongStorageMock()
+	service := NewSongService(storage)
+
+	for i, title := range []string{"Echo", "Alpha", "Delta", "Bravo", "Charlie"} {
+		song, err := service.CreateSong(title, "Artist "+title, "synth", title, i%2 == 0, []string{"focus"}, models.SongMetadata{})
+		assert.NoError(t, err)
+		song.CreatedAt = time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)
+		storage.SetPlayCount(song.ID, 10-i%3)
+	}
+
+	// Walk every page of each order.
+	pages := func(filter models.SongFilter) []string {
+		var titles []string
+		cursor := ""
+		for {
+			page, err := service.ListSongs(filter, cursor)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Songs), filter.Limit)
+			titles = append(titles, songTitles(page.Songs)...)
+			if page.NextCursor == "" {
+				return titles
+			}
+			cursor = page.NextCursor
+		}
+	}
+	assert.Equal(t, []string{"Echo", "Alpha", "Delta", "Bravo", "Charlie"}, pages(models.SongFilter{Limit: 2}))
+	assert.Equal(t, []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo"}, pages(models.SongFilter{Sort: models.SongSortTitle, Limit: 2}))
+	assert.Equal(t, []string{"Charlie", "Bravo", "Delta", "Alpha", "Echo"}, pages(models.SongFilter{Sort: models.SongSortCreatedAt, Descending: true, Limit: 2}))
+	// Play counts are 10, 9, 8, 10, 9; ties are ordered by ID in the same direction.
+	assert.Equal(t, []string{"Bravo", "Echo", "Charlie", "Alpha", "Delta"}, pages(models.SongFilter{Sort: models.SongSortPlayCount, Descending: true, Limit: 3}))
+
+	generated := true
+	page, err := service.ListSongs(models.SongFilter{IsGenerated: &generated, Sort: models.SongSortTitle}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Charlie", "Delta", "Echo"}, songTitles(page.Songs))
+	assert.Empty(t, page.NextCursor)
+
+	after := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	page, err = service.ListSongs(models.SongFilter{Artist: "artist d", CreatedAfter: &after, CreatedBefore: &before}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Delta"}, songTitles(page.Songs))
+
+	page, err = service.ListSongs(models.SongFilter{Tag: "sleep"}, "")
+	assert.NoError(t, err)
+	assert.Empty(t, page.Songs)
+}
+
+func TestListSongsRejectsInvalidFilters(t *testing.T) {
+	storage := repositories.NewSongStorageMock()
+	service := NewSongService(storage)
+	for _, title := range []string{"Alpha", "Bravo"} {
+		_, err := service.CreateSong(title, "Artist", "synth", title, false, []string{"focus"}, models.SongMetadata{})
+		assert.NoError(t, err)
+	}
+
+	_, err := service.ListSongs(models.SongFilter{Sort: "duration"}, "")
+	assert.ErrorIs(t, err, ErrInvalidSongFilter)
+	_, err = service.ListSongs(models.SongFilter{Limit: 1000}, "")
+	assert.ErrorIs(t, err, ErrInvalidSongFilter)
+	_, err = service.ListSongs(models.SongFilter{}, "not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidSongFilter)
+
+	page, err := service.ListSongs(models.SongFilter{Sort: models.SongSortTitle, Limit: 1}, "")
+	assert.NoError(t, err)
+	_, err = service.ListSongs(models.SongFilter{Sort: models.SongSortPlayCount, Limit: 1}, page.NextCursor)
+	assert.ErrorIs(t, err, ErrInvalidSongFilter)
+}
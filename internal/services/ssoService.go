@@ -0,0 +1,274 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"louderspace/internal/models"
+	"louderspace/internal/oidc"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SSOLoginStateTTL is how long a user has to finish signing in at the identity provider.
+const SSOLoginStateTTL = 10 * time.Minute
+
+var (
+	ErrInvalidSSOState  = errors.New("invalid or expired sign-in state")
+	ErrSSOEmailRequired = errors.New("identity provider did not return an email address")
+	ErrSSOAccountExists = errors.New("an account with this email already exists")
+)
+
+// IdentityProvider is implemented by *oidc.Provider.
+type IdentityProvider interface {
+	Name() string
+	AuthCodeURL(state, nonce, codeChallenge string) (string, error)
+	Exchange(code, codeVerifier, nonce string) (*oidc.Claims, error)
+}
+
+type SSOManagement interface {
+	Provider() string
+	// StartLogin also returns the binding the starting browser keeps in a cookie.
+	StartLogin() (authURL, binding string, err error)
+	// CompleteLogin links unknown identities to the account with the same verified email,
+	// or creates one.
+	CompleteLogin(state, binding, code string) (*models.User, error)
+	Identities(userID int) ([]*models.Identity, error)
+}
+
+type SSOService struct {
+	provider        IdentityProvider
+	identityStorage repositories.IdentityStorage
+	userStorage     repositories.UserStorage
+	tokenService    TokenManagement
+	groupRoles      map[string]models.Role
+}
+
+// NewSSOService maps the provider's groups to roles through groupRoles.
+func NewSSOService(provider IdentityProvider, identityStorage repositories.IdentityStorage, userStorage repositories.UserStorage, tokenService TokenManagement, groupRoles map[string]models.Role) SSOManagement {
+	return &SSOService{provider, identityStorage, userStorage, tokenService, groupRoles}
+}
+
+func (s *SSOService) Provider() string {
+	return s.provider.Name()
+}
+
+func (s *SSOService) StartLogin() (string, string, error) {
+	state, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	binding, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if err := s.identityStorage.CreateLoginState(&models.SSOLoginState{
+		Provider:     s.provider.Name(),
+		StateHash:    utils.HashToken(state),
+		BindingHash:  utils.HashToken(binding),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(SSOLoginStateTTL),
+		CreatedAt:    now,
+	}); err != nil {
+		return "", "", err
+	}
+	authURL, err := s.provider.AuthCodeURL(state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, binding, nil
+}
+
+// CompleteLogin only consumes a state presented with the starting browser's binding, so an
+// attacker can neither finish their login in a victim's browser nor burn the victim's state.
+func (s *SSOService) CompleteLogin(state, binding, code string) (*models.User, error) {
+	if binding == "" {
+		return nil, ErrInvalidSSOState
+	}
+	loginState, err := s.identityStorage.ConsumeLoginState(s.provider.Name(), utils.HashToken(state), utils.HashToken(binding))
+	if err != nil {
+		return nil, err
+	}
+	if loginState == nil || time.Now().After(loginState.ExpiresAt) {
+		return nil, ErrInvalidSSOState
+	}
+
+	claims, err := s.provider.Exchange(code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.identityUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrUserSuspended
+	}
+	if err := s.syncRole(user, claims.Groups); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *SSOService) Identities(userID int) ([]*models.Identity, error) {
+	return s.identityStorage.IdentitiesForUser(userID)
+}
+
+// identityUser only links an existing account when the provider has verified its email.
+func (s *SSOService) identityUser(claims *oidc.Claims) (*models.User, error) {
+	now := time.Now()
+	identity, err := s.identityStorage.Identity(s.provider.Name(), claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if err := s.identityStorage.TouchIdentity(identity.ID, claims.Email, now); err != nil {
+			return nil, err
+		}
+		return s.userStorage.UserByID(identity.UserID)
+	}
+
+	if claims.Email == "" {
+		return nil, ErrSSOEmailRequired
+	}
+	user, err := s.userStorage.UserByEmail(claims.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if user, err = s.provision(claims); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !claims.EmailVerified:
+		return nil, ErrSSOAccountExists
+	}
+
+	if err := s.identityStorage.Link(&models.Identity{
+		UserID:      user.ID,
+		Provider:    s.provider.Name(),
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+var unsafeUsernameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// provision creates an account without a password; a password reset can set one.
+func (s *SSOService) provision(claims *oidc.Claims) (*models.User, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = unsafeUsernameChars.ReplaceAllString(base, "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	username, err := s.freeUsername(base)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username:      username,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Role:          models.RoleFree,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.userStorage.Save(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *SSOService) freeUsername(base string) (string, error) {
+	for i := 1; i <= 20; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		_, err := s.userStorage.UserByUsername(candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	suffix, err := utils.GenerateOpaqueToken(4)
+	if err != nil {
+		return "", err
+	}
+	return base + "-" + strings.ToLower(suffix), nil
+}
+
+// ssoRolePriority decides between roles when a user is in several mapped groups.
+var ssoRolePriority = []models.Role{models.RoleAdmin, models.RoleAnalyst, models.RolePremium, models.RoleFree}
+
+// syncRole only changes roles the group mapping hands out, so e.g. a paid premium plan stays.
+func (s *SSOService) syncRole(user *models.User, groups []string) error {
+	if len(s.groupRoles) == 0 {
+		return nil
+	}
+
+	granted := make(map[models.Role]bool)
+	managed := make(map[models.Role]bool)
+	for _, role := range s.groupRoles {
+		managed[role] = true
+	}
+	for _, group := range groups {
+		if role, ok := s.groupRoles[group]; ok {
+			granted[role] = true
+		}
+	}
+
+	role := user.Role
+	if len(granted) > 0 {
+		for _, candidate := range ssoRolePriority {
+			if granted[candidate] {
+				role = candidate
+				break
+			}
+		}
+	} else if managed[user.Role] {
+		role = models.RoleFree
+	}
+	if role == user.Role {
+		return nil
+	}
+
+	if err := s.userStorage.UpdateRole(user.ID, role, &models.AuditEntry{
+		ActorID:      models.SystemActorID,
+		TargetUserID: user.ID,
+		Action:       models.AuditActionChangeRole,
+		Details:      fmt.Sprintf("%s -> %s (groups at %s)", user.Role, role, s.provider.Name()),
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return err
+	}
+	user.Role = role
+	return s.tokenService.RevokeUserTokens(user.ID)
+}
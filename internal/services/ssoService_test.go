@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/oidc"
+	"louderspace/internal/oidc/oidctest"
+	"louderspace/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestSSOProvisionsAndMapsGroups(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+
+	userStorage := repositories.NewMockUserStorage()
+	auditStorage := userStorage.AuditLog
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+	groupRoles := map[string]models.Role{"louderspace-admins": models.RoleAdmin, "data": models.RoleAnalyst}
+	service := NewSSOService(provider, repositories.NewMockIdentityStorage(), userStorage, tokenService, groupRoles)
+
+	login := func(claims map[string]interface{}) (*models.User, error) {
+		authURL, binding, err := service.StartLogin()
+		assert.NoError(t, err)
+		code, state, err := idp.Authorize(authURL, claims)
+		assert.NoError(t, err)
+		return service.CompleteLogin(state, binding, code)
+	}
+
+	claims := map[string]interface{}{
+		"sub":                "jane-1",
+		"email":              "jane@corp.example",
+		"email_verified":     true,
+		"preferred_username": "jane",
+		"groups":             []string{"data", "louderspace-admins"},
+	}
+	user, err := login(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", user.Username)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+	assert.Len(t, auditStorage.Entries, 1)
+	assert.Equal(t, models.SystemActorID, auditStorage.Entries[0].ActorID)
+
+	// The second login finds the linked identity instead of provisioning again.
+	claims["groups"] = []string{"data"}
+	again, err := login(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+	assert.Equal(t, models.RoleAnalyst, again.Role)
+
+	// Leaving every mapped group takes the mapped role away.
+	claims["groups"] = []string{"everyone"}
+	again, err = login(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleFree, again.Role)
+
+	users, err := userStorage.Users()
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	identities, err := service.Identities(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.Equal(t, "corp", identities[0].Provider)
+}
+
+func TestSSOLinksExistingAccounts(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+
+	userStorage := repositories.NewMockUserStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+	groupRoles := map[string]models.Role{"louderspace-admins": models.RoleAdmin, "data": models.RoleAnalyst}
+	service := NewSSOService(provider, repositories.NewMockIdentityStorage(), userStorage, tokenService, groupRoles)
+
+	login := func(claims map[string]interface{}) (*models.User, error) {
+		authURL, binding, err := service.StartLogin()
+		assert.NoError(t, err)
+		code, state, err := idp.Authorize(authURL, claims)
+		assert.NoError(t, err)
+		return service.CompleteLogin(state, binding, code)
+	}
+
+	existing := &models.User{Username: "jane", Email: "jane@corp.example", Role: models.RolePremium, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(existing))
+
+	// Without a verified email the provider cannot claim the account.
+	_, err = login(map[string]interface{}{"sub": "jane-1", "email": "jane@corp.example"})
+	assert.ErrorIs(t, err, ErrSSOAccountExists)
+
+	user, err := login(map[string]interface{}{"sub": "jane-1", "email": "jane@corp.example", "email_verified": true})
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, user.ID)
+	// Roles the mapping never grants are left alone.
+	assert.Equal(t, models.RolePremium, user.Role)
+
+	// A second provider account with a clashing username gets a suffix.
+	other, err := login(map[string]interface{}{"sub": "jane-2", "email": "jane@elsewhere.example", "preferred_username": "jane"})
+	assert.NoError(t, err)
+	assert.Equal(t, "jane-2", other.Username)
+}
+
+func TestSSORejectsReusedState(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+
+	userStorage := repositories.NewMockUserStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+	groupRoles := map[string]models.Role{"louderspace-admins": models.RoleAdmin, "data": models.RoleAnalyst}
+	service := NewSSOService(provider, repositories.NewMockIdentityStorage(), userStorage, tokenService, groupRoles)
+
+	authURL, binding, err := service.StartLogin()
+	assert.NoError(t, err)
+	code, state, err := idp.Authorize(authURL, map[string]interface{}{"sub": "jane-1", "email": "jane@corp.example"})
+	assert.NoError(t, err)
+
+	_, err = service.CompleteLogin("forged", binding, code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+
+	// A state is bound to the browser that started the login; another binding neither
+	// completes nor uses it up.
+	_, otherBinding, err := service.StartLogin()
+	assert.NoError(t, err)
+	_, err = service.CompleteLogin(state, otherBinding, code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+	_, err = service.CompleteLogin(state, "", code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+
+	_, err = service.CompleteLogin(state, binding, code)
+	assert.NoError(t, err)
+	_, err = service.CompleteLogin(state, binding, code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+}
+
+type failingEmailLookup struct {
+	*repositories.MockUserStorage
+}
+
+func (failingEmailLookup) UserByEmail(email string) (*models.User, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestSSOSurfacesEmailLookupErrors(t *testing.T) {
+	idp, err := oidctest.NewIdP("louderspace", "client-secret")
+	assert.NoError(t, err)
+	defer idp.Close()
+
+	userStorage := repositories.NewMockUserStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	provider := oidc.NewProvider(idp.Config("corp", "http://localhost:3000/sso/callback"), nil)
+	service := NewSSOService(provider, repositories.NewMockIdentityStorage(), failingEmailLookup{userStorage}, tokenService, nil)
+
+	authURL, binding, err := service.StartLogin()
+	assert.NoError(t, err)
+	code, state, err := idp.Authorize(authURL, map[string]interface{}{"sub": "jane-1", "email": "jane@corp.example"})
+	assert.NoError(t, err)
+	_, err = service.CompleteLogin(state, binding, code)
+	assert.EqualError(t, err, "connection refused")
+
+	users, err := userStorage.Users()
+	assert.NoError(t, err)
+	assert.Empty(t, users, "no account is provisioned when the lookup fails")
+}
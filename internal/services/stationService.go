@@ -1,19 +1,39 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"log"
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
 )
 
 type StationManagement interface {
-	CreateStation(name string, tags []string) (*models.Station, error)
-	UpdateStation(id int, name string, tags []string) (*models.Station, error)
+	CreateStation(name string, tags []string, premium bool) (*models.Station, error)
+	// UpdateStation leaves the premium flag as it is when premium is nil.
+	UpdateStation(id int, name string, tags []string, premium *bool) (*models.Station, error)
 	DeleteStation(id int) error
 	GetStation(id int) (*models.Station, error)
 	GetAllStations() ([]*models.Station, error)
+	// GetStationsForUser lists the shared stations and the user's personal stations.
+	GetStationsForUser(userID int) ([]*models.Station, error)
 	GetSongsForStation(stationID int) ([]*models.Song, error)
-	GetSongsForStationWithFeedback(stationID, userID int) ([]*models.SongWithFeedback, error)
+	GetSongsForStationWithFeedback(stationID int, user *models.User) ([]*models.SongWithFeedback, error)
+	CreatePersonalStation(user *models.User, name string, tags []string) (*models.Station, error)
+	DeletePersonalStation(userID, stationID int) error
+}
+
+var (
+	ErrStationNotFound = errors.New("station not found")
+	ErrStationNotOwned = errors.New("station belongs to another user")
+	ErrInvalidStation  = errors.New("station name and at least one tag are required")
+)
+
+func stationNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrStationNotFound
+	}
+	return err
 }
 
 type StationService struct {
@@ -26,8 +46,8 @@ func NewStationService(stationStorage repositories.StationStorage, feedbackStora
 	return &StationService{stationStorage, feedbackStorage, songStorage}
 }
 
-func (s *StationService) CreateStation(name string, tags []string) (*models.Station, error) {
-	station := &models.Station{Name: name, Tags: tags}
+func (s *StationService) CreateStation(name string, tags []string, premium bool) (*models.Station, error) {
+	station := &models.Station{Name: name, Tags: tags, Premium: premium}
 	if err := s.stationStorage.Create(station); err != nil {
 		log.Printf("Error creating station: %v", err)
 		return nil, err
@@ -36,8 +56,16 @@ func (s *StationService) CreateStation(name string, tags []string) (*models.Stat
 	return station, nil
 }
 
-func (s *StationService) UpdateStation(id int, name string, tags []string) (*models.Station, error) {
-	station := &models.Station{ID: id, Name: name, Tags: tags}
+func (s *StationService) UpdateStation(id int, name string, tags []string, premium *bool) (*models.Station, error) {
+	station, err := s.stationStorage.ByID(id)
+	if err != nil {
+		return nil, stationNotFound(err)
+	}
+	station.Name = name
+	station.Tags = tags
+	if premium != nil {
+		station.Premium = *premium
+	}
 	if err := s.stationStorage.Update(station); err != nil {
 		return nil, err
 	}
@@ -56,6 +84,10 @@ func (s *StationService) GetAllStations() ([]*models.Station, error) {
 	return s.stationStorage.All()
 }
 
+func (s *StationService) GetStationsForUser(userID int) ([]*models.Station, error) {
+	return s.stationStorage.Accessible(userID)
+}
+
 func (s *StationService) GetSongsForStation(stationID int) ([]*models.Song, error) {
 	station, err := s.stationStorage.ByID(stationID)
 	if err != nil {
@@ -64,7 +96,15 @@ func (s *StationService) GetSongsForStation(stationID int) ([]*models.Song, erro
 	return s.stationStorage.SongsByTags(station.Tags)
 }
 
-func (s *StationService) GetSongsForStationWithFeedback(stationID, userID int) ([]*models.SongWithFeedback, error) {
+func (s *StationService) GetSongsForStationWithFeedback(stationID int, user *models.User) ([]*models.SongWithFeedback, error) {
+	station, err := s.stationStorage.ByID(stationID)
+	if err != nil {
+		return nil, stationNotFound(err)
+	}
+	if err := checkStationAccess(user, station); err != nil {
+		return nil, err
+	}
+
 	songs, err := s.songStorage.ByStationID(stationID)
 	if err != nil {
 		return nil, err
@@ -77,7 +117,7 @@ func (s *StationService) GetSongsForStationWithFeedback(stationID, userID int) (
 	}
 
 	// Get feedback for the songs
-	feedbackMap, err := s.feedbackStorage.GetFeedbackForUserAndSongs(userID, songIDs)
+	feedbackMap, err := s.feedbackStorage.GetFeedbackForUserAndSongs(user.ID, songIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -97,3 +137,37 @@ func (s *StationService) GetSongsForStationWithFeedback(stationID, userID int) (
 
 	return songsWithFeedback, nil
 }
+
+// CreatePersonalStation returns an UpgradeRequiredError once the tier's limit is reached.
+func (s *StationService) CreatePersonalStation(user *models.User, name string, tags []string) (*models.Station, error) {
+	if name == "" || len(tags) == 0 {
+		return nil, ErrInvalidStation
+	}
+
+	if !user.Role.Has(models.PermissionStationsCreatePersonal) {
+		return nil, upgradeRequired(user, models.FeaturePersonalStations, 0)
+	}
+
+	limit := user.Role.Entitlements().PersonalStations
+	ownerID := user.ID
+	station := &models.Station{Name: name, Tags: tags, OwnerID: &ownerID}
+	created, err := s.stationStorage.CreatePersonal(station, limit)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, upgradeRequired(user, models.FeaturePersonalStations, limit)
+	}
+	return station, nil
+}
+
+func (s *StationService) DeletePersonalStation(userID, stationID int) error {
+	station, err := s.stationStorage.ByID(stationID)
+	if err != nil {
+		return stationNotFound(err)
+	}
+	if station.OwnerID == nil || *station.OwnerID != userID {
+		return ErrStationNotOwned
+	}
+	return s.stationStorage.Delete(stationID)
+}
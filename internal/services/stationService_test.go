@@ -4,14 +4,16 @@ import (
 	"github.com/stretchr/testify/assert"
 	"louderspace/internal/models"
 	"louderspace/internal/repositories"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
 func TestCreateStation(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
-	service := NewStationService(storage)
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
 
-	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"})
+	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"}, false)
 	assert.NoError(t, err)
 	assert.NotNil(t, station)
 	assert.Equal(t, "Chill Beats", station.Name)
@@ -20,23 +22,34 @@ func TestCreateStation(t *testing.T) {
 
 func TestUpdateStation(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
-	service := NewStationService(storage)
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
 
-	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"})
+	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"}, false)
 	assert.NoError(t, err)
 
-	updatedStation, err := service.UpdateStation(station.ID, "Chill Vibes", []string{"chill", "vibes"})
+	updatedStation, err := service.UpdateStation(station.ID, "Chill Vibes", []string{"chill", "vibes"}, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, updatedStation)
 	assert.Equal(t, "Chill Vibes", updatedStation.Name)
 	assert.ElementsMatch(t, []string{"chill", "vibes"}, updatedStation.Tags)
+
+	premium := true
+	_, err = service.UpdateStation(station.ID, "Chill Vibes", []string{"chill", "vibes"}, &premium)
+	assert.NoError(t, err)
+	// Leaving premium out keeps the stored flag.
+	updatedStation, err = service.UpdateStation(station.ID, "Deep Chill", []string{"chill"}, nil)
+	assert.NoError(t, err)
+	assert.True(t, updatedStation.Premium)
+
+	_, err = service.UpdateStation(999, "Nowhere", []string{"chill"}, nil)
+	assert.ErrorIs(t, err, ErrStationNotFound)
 }
 
 func TestDeleteStation(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
-	service := NewStationService(storage)
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
 
-	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"})
+	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"}, false)
 	assert.NoError(t, err)
 
 	err = service.DeleteStation(station.ID)
@@ -48,9 +61,9 @@ func TestDeleteStation(t *testing.T) {
 
 func TestGetStation(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
-	service := NewStationService(storage)
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
 
-	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"})
+	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"}, false)
 	assert.NoError(t, err)
 
 	fetchedStation, err := service.GetStation(station.ID)
@@ -62,11 +75,11 @@ func TestGetStation(t *testing.T) {
 
 func TestGetAllStations(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
-	service := NewStationService(storage)
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
 
-	_, err := service.CreateStation("Chill Beats", []string{"chill", "beats"})
+	_, err := service.CreateStation("Chill Beats", []string{"chill", "beats"}, false)
 	assert.NoError(t, err)
-	_, err = service.CreateStation("Lo-fi Hip Hop", []string{"lo-fi", "hip hop"})
+	_, err = service.CreateStation("Lo-fi Hip Hop", []string{"lo-fi", "hip hop"}, false)
 	assert.NoError(t, err)
 
 	stations, err := service.GetAllStations()
@@ -76,7 +89,7 @@ func TestGetAllStations(t *testing.T) {
 
 func TestGetSongsForStation(t *testing.T) {
 	storage := repositories.NewStationStorageMock()
-	service := NewStationService(storage)
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
 
 	storage.Songs = []*models.Song{
 		{ID: 1, Title: "Chill Song 1", Artist: "Artist 1", Genre: "chill, beats"},
@@ -84,7 +97,7 @@ func TestGetSongsForStation(t *testing.T) {
 		{ID: 3, Title: "Lo-fi Song 1", Artist: "Artist 3", Genre: "lo-fi, hip hop"},
 	}
 
-	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"})
+	station, err := service.CreateStation("Chill Beats", []string{"chill", "beats"}, false)
 	assert.NoError(t, err)
 
 	songs, err := service.GetSongsForStation(station.ID)
@@ -92,3 +105,56 @@ func TestGetSongsForStation(t *testing.T) {
 	assert.Len(t, songs, 2)
 	assert.ElementsMatch(t, []string{"Chill Song 1", "Chill Song 2"}, []string{songs[0].Title, songs[1].Title})
 }
+
+func TestCreatePersonalStation(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
+
+	_, err := service.CreatePersonalStation(&models.User{ID: 1, Role: models.RoleFree}, "Mine", []string{"chill"})
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+
+	premium := &models.User{ID: 2, Role: models.RolePremium}
+	limit := models.RolePremium.Entitlements().PersonalStations
+	for i := 0; i < limit; i++ {
+		_, err := service.CreatePersonalStation(premium, "Mine", []string{"chill"})
+		assert.NoError(t, err)
+	}
+	_, err = service.CreatePersonalStation(premium, "One too many", []string{"chill"})
+	assert.ErrorIs(t, err, ErrUpgradeRequired)
+
+	// Personal stations are only listed for their owner.
+	_, err = service.CreateStation("Shared", []string{"beats"}, false)
+	assert.NoError(t, err)
+	stations, err := service.GetStationsForUser(2)
+	assert.NoError(t, err)
+	assert.Len(t, stations, limit+1)
+	stations, err = service.GetStationsForUser(1)
+	assert.NoError(t, err)
+	assert.Len(t, stations, 1)
+
+	assert.ErrorIs(t, service.DeletePersonalStation(1, stations[0].ID), ErrStationNotOwned)
+	assert.ErrorIs(t, service.DeletePersonalStation(2, 999), ErrStationNotFound)
+	_, err = service.GetSongsForStationWithFeedback(999, premium)
+	assert.ErrorIs(t, err, ErrStationNotFound)
+}
+
+func TestCreatePersonalStationLimitIsAtomic(t *testing.T) {
+	storage := repositories.NewStationStorageMock()
+	service := NewStationService(storage, repositories.NewMockFeedbackStorage(), repositories.NewSongStorageMock())
+
+	premium := &models.User{ID: 2, Role: models.RolePremium}
+	limit := models.RolePremium.Entitlements().PersonalStations
+	var wg sync.WaitGroup
+	var created int32
+	for i := 0; i < 2*limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.CreatePersonalStation(premium, "Mine", []string{"chill"}); err == nil {
+				atomic.AddInt32(&created, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(limit), created)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"louderspace/internal/logger"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// sessionTouchInterval limits how often a session's last-seen time is written.
+const sessionTouchInterval = time.Minute
+
+type TokenManagement interface {
+	// IssueTokens starts a new session for the device described by session.
+	IssueTokens(user *models.User, session *models.Session) (*models.TokenPair, error)
+	Refresh(refreshToken string) (*models.TokenPair, *models.User, error)
+	Logout(claims *utils.Claims, refreshToken string) error
+	RevokeUserTokens(userID int) error
+	ValidateAccessToken(tokenString string) (*utils.Claims, error)
+	// TouchSession reports whether the token belongs to a live session and records its use.
+	TouchSession(claims *utils.Claims, ip string) (bool, error)
+	// Sessions lists the user's active sessions, flagging the one with currentSessionID.
+	Sessions(userID, currentSessionID int) ([]*models.Session, error)
+	RevokeSession(userID, sessionID int) error
+}
+
+type TokenService struct {
+	tokenStorage    repositories.TokenStorage
+	userStorage     repositories.UserStorage
+	keys            *utils.KeySet
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewTokenService(tokenStorage repositories.TokenStorage, userStorage repositories.UserStorage, keys *utils.KeySet, accessTokenTTL, refreshTokenTTL time.Duration) TokenManagement {
+	return &TokenService{tokenStorage, userStorage, keys, accessTokenTTL, refreshTokenTTL}
+}
+
+func (s *TokenService) IssueTokens(user *models.User, session *models.Session) (*models.TokenPair, error) {
+	now := time.Now()
+	session.UserID = user.ID
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	if err := s.tokenStorage.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.issue(user, session.ID)
+}
+
+func (s *TokenService) issue(user *models.User, sessionID int) (*models.TokenPair, error) {
+	generation, err := s.tokenStorage.TokenGeneration(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, claims, err := s.keys.GenerateToken(user, sessionID, generation, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokenStorage.SetSessionAccessToken(sessionID, claims.Id); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshExpiresAt := now.Add(s.refreshTokenTTL)
+	if err := s.tokenStorage.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: refreshExpiresAt,
+		CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Unix(claims.ExpiresAt, 0),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// Refresh rotates the refresh token. Presenting one that was already used revokes every
+// token of its owner, since it has most likely leaked.
+func (s *TokenService) Refresh(refreshToken string) (*models.TokenPair, *models.User, error) {
+	stored, err := s.tokenStorage.RefreshTokenByHash(utils.HashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A signed-out session's refresh tokens were revoked with it, which is not a sign of reuse.
+	session, err := s.tokenStorage.Session(stored.SessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrTokenRevoked
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, nil, ErrTokenRevoked
+	}
+
+	if stored.RevokedAt != nil {
+		logger.Error("Refresh token reused, revoking all tokens for user", stored.UserID)
+		if err := s.RevokeUserTokens(stored.UserID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrTokenRevoked
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	revoked, err := s.tokenStorage.RevokeRefreshToken(stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !revoked {
+		// Another request rotated this token first.
+		return nil, nil, ErrTokenRevoked
+	}
+
+	user, err := s.userStorage.UserByID(stored.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Suspended() {
+		return nil, nil, ErrTokenRevoked
+	}
+
+	pair, err := s.issue(user, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// Logout revokes the access token and, when given, its refresh token.
+func (s *TokenService) Logout(claims *utils.Claims, refreshToken string) error {
+	if err := s.tokenStorage.RevokeAccessToken(claims.Id, claims.UserID, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return err
+	}
+	if _, err := s.tokenStorage.RevokeSession(claims.SessionID, claims.UserID); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.tokenStorage.RefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil || stored.UserID != claims.UserID {
+		return ErrInvalidRefreshToken
+	}
+	_, err = s.tokenStorage.RevokeRefreshToken(stored.ID)
+	return err
+}
+
+// RevokeUserTokens should be called whenever the user's credentials or role change.
+func (s *TokenService) RevokeUserTokens(userID int) error {
+	if err := s.tokenStorage.RevokeRefreshTokensForUser(userID); err != nil {
+		return err
+	}
+	if err := s.tokenStorage.RevokeSessionsForUser(userID); err != nil {
+		return err
+	}
+	return s.tokenStorage.BumpTokenGeneration(userID, time.Now())
+}
+
+func (s *TokenService) ValidateAccessToken(tokenString string) (*utils.Claims, error) {
+	claims, err := s.keys.ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.tokenStorage.IsAccessTokenRevoked(claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	generation, err := s.tokenStorage.TokenGeneration(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Generation < generation {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// TouchSession rejects tokens of revoked sessions and tokens replaced by a refresh.
+func (s *TokenService) TouchSession(claims *utils.Claims, ip string) (bool, error) {
+	session, err := s.tokenStorage.Session(claims.SessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if session.UserID != claims.UserID || session.RevokedAt != nil || session.AccessTokenID != claims.Id {
+		return false, nil
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval && session.IP == ip {
+		return true, nil
+	}
+	return true, s.tokenStorage.TouchSession(session.ID, ip, now)
+}
+
+func (s *TokenService) Sessions(userID, currentSessionID int) ([]*models.Session, error) {
+	sessions, err := s.tokenStorage.ActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's devices out.
+func (s *TokenService) RevokeSession(userID, sessionID int) error {
+	revoked, err := s.tokenStorage.RevokeSession(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"testing"
+	"time"
+)
+
+func newTestKeySet(t *testing.T) *utils.KeySet {
+	key, err := utils.LoadSigningKey("test", "HS256", "test-secret", "", "")
+	assert.NoError(t, err)
+	keys, err := utils.NewKeySet("test", key)
+	assert.NoError(t, err)
+	return keys
+}
+
+func TestIssueTokens(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	tokens, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	claims, err := service.ValidateAccessToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.NotEmpty(t, claims.Id)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	tokens, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+
+	refreshed, refreshedUser, err := service.Refresh(tokens.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, refreshedUser.ID)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	_, _, err = service.Refresh("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshReuseRevokesAllTokens(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	tokens, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+
+	refreshed, _, err := service.Refresh(tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, _, err = service.Refresh(tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, _, err = service.Refresh(refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+type failingSessionLookup struct {
+	*repositories.MockTokenStorage
+}
+
+func (failingSessionLookup) Session(id int) (*models.Session, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRefreshSurfacesSessionLookupErrors(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(failingSessionLookup{repositories.NewMockTokenStorage()}, userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	tokens, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+
+	_, _, err = service.Refresh(tokens.RefreshToken)
+	assert.EqualError(t, err, "connection refused")
+	assert.NotErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	tokens, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+	claims, err := service.ValidateAccessToken(tokens.AccessToken)
+	assert.NoError(t, err)
+
+	err = service.Logout(claims, tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = service.ValidateAccessToken(tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, _, err = service.Refresh(tokens.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	tokens, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+
+	err = service.RevokeUserTokens(user.ID)
+	assert.NoError(t, err)
+
+	_, err = service.ValidateAccessToken(tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	fresh, err := service.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+	_, err = service.ValidateAccessToken(fresh.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestSessions(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+
+	laptop, err := service.IssueTokens(user, &models.Session{DeviceName: "Laptop", IP: "10.0.0.1"})
+	assert.NoError(t, err)
+	phone, err := service.IssueTokens(user, &models.Session{DeviceName: "Phone", IP: "10.0.0.2"})
+	assert.NoError(t, err)
+
+	laptopClaims, err := service.ValidateAccessToken(laptop.AccessToken)
+	assert.NoError(t, err)
+	phoneClaims, err := service.ValidateAccessToken(phone.AccessToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, laptopClaims.SessionID, phoneClaims.SessionID)
+
+	sessions, err := service.Sessions(user.ID, laptopClaims.SessionID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, session.ID == laptopClaims.SessionID, session.Current)
+	}
+
+	// Signing out the phone from the laptop ends the phone's session only.
+	assert.ErrorIs(t, service.RevokeSession(user.ID+1, phoneClaims.SessionID), ErrSessionNotFound)
+	assert.NoError(t, service.RevokeSession(user.ID, phoneClaims.SessionID))
+	live, err := service.TouchSession(phoneClaims, "10.0.0.2")
+	assert.NoError(t, err)
+	assert.False(t, live)
+	live, err = service.TouchSession(laptopClaims, "10.0.0.1")
+	assert.NoError(t, err)
+	assert.True(t, live)
+
+	_, _, err = service.Refresh(phone.RefreshToken)
+	assert.Error(t, err)
+	refreshed, _, err := service.Refresh(laptop.RefreshToken)
+	assert.NoError(t, err)
+
+	// Only the latest access token of a session is accepted.
+	live, err = service.TouchSession(laptopClaims, "10.0.0.1")
+	assert.NoError(t, err)
+	assert.False(t, live)
+	refreshedClaims, err := service.ValidateAccessToken(refreshed.AccessToken)
+	assert.NoError(t, err)
+	live, err = service.TouchSession(refreshedClaims, "10.0.0.1")
+	assert.NoError(t, err)
+	assert.True(t, live)
+
+	sessions, err = service.Sessions(user.ID, laptopClaims.SessionID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"strings"
+	"time"
+)
+
+const (
+	TwoFactorIssuer     = "Louderspace"
+	loginChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount   = 10
+	recoveryCodeEntropy = 5 // bytes, which encode to 8 base32 characters
+)
+
+var (
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotPending     = errors.New("no two-factor enrollment to confirm")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for this role")
+	ErrInvalidLoginChallenge   = errors.New("invalid or expired login challenge")
+)
+
+// TwoFactorEnrollment is what a user needs to add the account to an authenticator app.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// LoginChallenge is returned instead of tokens until the second factor is checked.
+type LoginChallenge struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// SetupRequired means the user's role requires 2FA and they must enroll first.
+	SetupRequired bool `json:"setup_required"`
+}
+
+type TwoFactorStatus struct {
+	Enabled  bool `json:"enabled"`
+	Required bool `json:"required"`
+}
+
+type TwoFactorManagement interface {
+	Status(user *models.User) (*TwoFactorStatus, error)
+	// Enroll only enables 2FA once Confirm accepts a code generated from the new secret.
+	Enroll(user *models.User) (*TwoFactorEnrollment, error)
+	// Confirm enables 2FA and returns the recovery codes, which are shown once.
+	Confirm(userID int, code string) ([]string, error)
+	// Disable turns 2FA off after checking a current code or recovery code.
+	Disable(user *models.User, code string) error
+	RegenerateRecoveryCodes(userID int, code string) ([]string, error)
+
+	// StartLogin returns nil when the password is enough.
+	StartLogin(user *models.User) (*LoginChallenge, error)
+	// ChallengeUser returns the user a pending challenge was issued for.
+	ChallengeUser(challenge string) (*models.User, error)
+	// EnrollForChallenge enrolls a user whose role requires 2FA during their login.
+	EnrollForChallenge(challenge string) (*TwoFactorEnrollment, error)
+	// CompleteLogin returns new recovery codes when the login also finished an enrollment.
+	CompleteLogin(challenge, code string) (*models.User, []string, error)
+
+	RequiredRoles() ([]models.Role, error)
+	SetRoleRequired(admin *models.User, role models.Role, required bool) error
+}
+
+type TwoFactorService struct {
+	twoFactorStorage repositories.TwoFactorStorage
+	tokenStorage     repositories.OneTimeTokenStorage
+	userStorage      repositories.UserStorage
+	auditStorage     repositories.AuditStorage
+}
+
+func NewTwoFactorService(twoFactorStorage repositories.TwoFactorStorage, tokenStorage repositories.OneTimeTokenStorage, userStorage repositories.UserStorage, auditStorage repositories.AuditStorage) TwoFactorManagement {
+	return &TwoFactorService{twoFactorStorage, tokenStorage, userStorage, auditStorage}
+}
+
+func (s *TwoFactorService) Status(user *models.User) (*TwoFactorStatus, error) {
+	twoFactor, err := s.twoFactorStorage.TwoFactor(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.required(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{Enabled: twoFactor.Enabled(), Required: required}, nil
+}
+
+func (s *TwoFactorService) Enroll(user *models.User) (*TwoFactorEnrollment, error) {
+	twoFactor, err := s.twoFactorStorage.TwoFactor(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorStorage.SavePending(&models.TwoFactor{UserID: user.ID, Secret: secret, CreatedAt: time.Now()}); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(TwoFactorIssuer, user.Username, secret),
+	}, nil
+}
+
+func (s *TwoFactorService) Confirm(userID int, code string) ([]string, error) {
+	twoFactor, err := s.twoFactorStorage.TwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, ErrTwoFactorNotPending
+	}
+	if twoFactor.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.verifyTOTP(twoFactor, code); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorStorage.Enable(userID, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	required, err := s.required(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if err := s.verify(user.ID, code); err != nil {
+		return err
+	}
+	return s.twoFactorStorage.Disable(user.ID)
+}
+
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.verify(userID, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+func (s *TwoFactorService) StartLogin(user *models.User) (*LoginChallenge, error) {
+	twoFactor, err := s.twoFactorStorage.TwoFactor(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	setupRequired := false
+	if !twoFactor.Enabled() {
+		required, err := s.required(user.Role)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		setupRequired = true
+	}
+
+	if err := s.tokenStorage.InvalidateForUser(user.ID, models.TokenPurposeLoginChallenge); err != nil {
+		return nil, err
+	}
+	challenge, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token := &models.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeLoginChallenge,
+		TokenHash: utils.HashToken(challenge),
+		ExpiresAt: now.Add(loginChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := s.tokenStorage.Create(token); err != nil {
+		return nil, err
+	}
+	return &LoginChallenge{Challenge: challenge, ExpiresAt: token.ExpiresAt, SetupRequired: setupRequired}, nil
+}
+
+func (s *TwoFactorService) ChallengeUser(challenge string) (*models.User, error) {
+	token, err := s.pendingChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return s.userStorage.UserByID(token.UserID)
+}
+
+func (s *TwoFactorService) EnrollForChallenge(challenge string) (*TwoFactorEnrollment, error) {
+	user, err := s.ChallengeUser(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return s.Enroll(user)
+}
+
+func (s *TwoFactorService) CompleteLogin(challenge, code string) (*models.User, []string, error) {
+	token, err := s.pendingChallenge(challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	twoFactor, err := s.twoFactorStorage.TwoFactor(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var recoveryCodes []string
+	switch {
+	case twoFactor == nil:
+		return nil, nil, ErrTwoFactorNotPending
+	case twoFactor.Enabled():
+		if err := s.verify(token.UserID, code); err != nil {
+			return nil, nil, err
+		}
+	default:
+		// The user enrolled during this login; the code also confirms the enrollment.
+		if recoveryCodes, err = s.Confirm(token.UserID, code); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	used, err := s.tokenStorage.MarkUsed(token.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, ErrInvalidLoginChallenge
+	}
+
+	user, err := s.userStorage.UserByID(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, recoveryCodes, nil
+}
+
+func (s *TwoFactorService) RequiredRoles() ([]models.Role, error) {
+	return s.twoFactorStorage.RequiredRoles()
+}
+
+// SetRoleRequired makes 2FA mandatory, or optional again, for everyone with the role.
+func (s *TwoFactorService) SetRoleRequired(admin *models.User, role models.Role, required bool) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	if err := s.twoFactorStorage.SetRoleRequired(role, required); err != nil {
+		return err
+	}
+	return s.auditStorage.Record(&models.AuditEntry{
+		ActorID:      admin.ID,
+		TargetUserID: admin.ID,
+		Action:       models.AuditActionTwoFactorRequirement,
+		Details:      fmt.Sprintf("%s: required=%t", role, required),
+		CreatedAt:    time.Now(),
+	})
+}
+
+func (s *TwoFactorService) required(role models.Role) (bool, error) {
+	roles, err := s.twoFactorStorage.RequiredRoles()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *TwoFactorService) pendingChallenge(challenge string) (*models.OneTimeToken, error) {
+	token, err := s.tokenStorage.ByHash(models.TokenPurposeLoginChallenge, utils.HashToken(challenge))
+	if err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	return token, nil
+}
+
+// verify accepts a TOTP code or an unused recovery code for an enabled enrollment.
+func (s *TwoFactorService) verify(userID int, code string) error {
+	twoFactor, err := s.twoFactorStorage.TwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	if !twoFactor.Enabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		return s.verifyTOTP(twoFactor, code)
+	}
+
+	used, err := s.twoFactorStorage.UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *TwoFactorService) verifyTOTP(twoFactor *models.TwoFactor, code string) error {
+	step, ok := utils.ValidateTOTP(twoFactor.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	fresh, err := s.twoFactorStorage.UseStep(twoFactor.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// issueRecoveryCodes replaces the user's recovery codes. Only their hashes are stored.
+func (s *TwoFactorService) issueRecoveryCodes(userID int) ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeEntropy)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = utils.HashToken(code)
+	}
+	if err := s.twoFactorStorage.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"louderspace/internal/utils"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// codeAt returns the TOTP code for the enrollment secret steps periods from now.
+func codeAt(t *testing.T, secret string, steps int64) string {
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now())+steps, utils.TOTPDigits)
+	assert.NoError(t, err)
+	return code
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+
+	enrollment, err := service.Enroll(user)
+	assert.NoError(t, err)
+	uri, err := url.Parse(enrollment.ProvisioningURI)
+	assert.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+
+	// Not enabled until confirmed, so logins do not need a code yet.
+	challenge, err := service.StartLogin(user)
+	assert.NoError(t, err)
+	assert.Nil(t, challenge)
+
+	_, err = service.Confirm(user.ID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	recoveryCodes, err := service.Confirm(user.ID, codeAt(t, enrollment.Secret, 0))
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	status, err := service.Status(user)
+	assert.NoError(t, err)
+	assert.True(t, status.Enabled)
+
+	_, err = service.Enroll(user)
+	assert.ErrorIs(t, err, ErrTwoFactorAlreadyEnabled)
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+	enrollment, _ := service.Enroll(user)
+	recoveryCodes, err := service.Confirm(user.ID, codeAt(t, enrollment.Secret, -1))
+	assert.NoError(t, err)
+
+	challenge, err := service.StartLogin(user)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, challenge) {
+		t.FailNow()
+	}
+	assert.False(t, challenge.SetupRequired)
+
+	// The code used to confirm the enrollment cannot be replayed.
+	_, _, err = service.CompleteLogin(challenge.Challenge, codeAt(t, enrollment.Secret, -1))
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	loggedIn, _, err := service.CompleteLogin(challenge.Challenge, codeAt(t, enrollment.Secret, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+
+	// A challenge is single use.
+	_, _, err = service.CompleteLogin(challenge.Challenge, codeAt(t, enrollment.Secret, 1))
+	assert.ErrorIs(t, err, ErrInvalidLoginChallenge)
+
+	// Recovery codes work once, with or without the dash.
+	challenge, _ = service.StartLogin(user)
+	_, _, err = service.CompleteLogin(challenge.Challenge, recoveryCodes[0])
+	assert.NoError(t, err)
+	challenge, _ = service.StartLogin(user)
+	_, _, err = service.CompleteLogin(challenge.Challenge, recoveryCodes[0])
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	_, _, err = service.CompleteLogin(challenge.Challenge, recoveryCodes[1][:4]+recoveryCodes[1][5:])
+	assert.NoError(t, err)
+}
+
+func TestTwoFactorRequiredForRole(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+	service := NewTwoFactorService(repositories.NewMockTwoFactorStorage(), repositories.NewMockOneTimeTokenStorage(), userStorage, repositories.NewMockAuditStorage())
+	admin := &models.User{ID: 99, Role: models.RoleAdmin}
+
+	assert.ErrorIs(t, service.SetRoleRequired(admin, "superuser", true), ErrInvalidRole)
+	assert.NoError(t, service.SetRoleRequired(admin, models.RoleAdmin, true))
+
+	challenge, err := service.StartLogin(user)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, challenge) {
+		t.FailNow()
+	}
+	assert.True(t, challenge.SetupRequired)
+
+	enrollment, err := service.EnrollForChallenge(challenge.Challenge)
+	assert.NoError(t, err)
+	loggedIn, recoveryCodes, err := service.CompleteLogin(challenge.Challenge, codeAt(t, enrollment.Secret, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.Len(t, recoveryCodes, 10)
+
+	// The requirement also keeps admins from turning 2FA off.
+	assert.ErrorIs(t, service.Disable(user, recoveryCodes[0]), ErrTwoFactorRequired)
+	assert.NoError(t, service.SetRoleRequired(admin, models.RoleAdmin, false))
+	assert.NoError(t, service.Disable(user, recoveryCodes[0]))
+}
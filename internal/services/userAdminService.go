@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"time"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+var (
+	ErrInvalidUserFilter = errors.New("invalid user filter")
+	ErrCannotSuspendSelf = errors.New("admins cannot suspend themselves")
+	ErrUserSuspended     = errors.New("account is suspended")
+)
+
+type UserAdministration interface {
+	// Search returns one page of the user directory. A zero limit uses the default page size.
+	Search(filter models.UserFilter) (*models.UserPage, error)
+	Suspend(admin *models.User, userID int, reason string) (*models.User, error)
+	Reactivate(admin *models.User, userID int) (*models.User, error)
+	AuditTrail(userID int) ([]*models.AuditEntry, error)
+	// IsSuspended lets middleware.WithUser reject requests of suspended users.
+	IsSuspended(userID int) (bool, error)
+}
+
+type UserAdminService struct {
+	userStorage  repositories.UserStorage
+	auditStorage repositories.AuditStorage
+	tokenService TokenManagement
+}
+
+func NewUserAdminService(userStorage repositories.UserStorage, auditStorage repositories.AuditStorage, tokenService TokenManagement) UserAdministration {
+	return &UserAdminService{userStorage, auditStorage, tokenService}
+}
+
+func (s *UserAdminService) Search(filter models.UserFilter) (*models.UserPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > maxUserPageSize || filter.Offset < 0 {
+		return nil, ErrInvalidUserFilter
+	}
+	if filter.Role != "" && !filter.Role.Valid() {
+		return nil, ErrInvalidUserFilter
+	}
+	switch filter.Sort {
+	case "", models.UserSortID, models.UserSortUsername, models.UserSortEmail, models.UserSortRole, models.UserSortCreatedAt:
+	default:
+		return nil, ErrInvalidUserFilter
+	}
+
+	users, total, err := s.userStorage.SearchUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []*models.User{}
+	}
+	return &models.UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// Suspend revokes the user's tokens so open sessions end with their next request.
+func (s *UserAdminService) Suspend(admin *models.User, userID int, reason string) (*models.User, error) {
+	if admin.ID == userID {
+		return nil, ErrCannotSuspendSelf
+	}
+
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return nil, userNotFound(err)
+	}
+	if user.Suspended() {
+		return user, nil
+	}
+
+	now := time.Now()
+	if err := s.userStorage.SetSuspended(userID, &now, &models.AuditEntry{
+		ActorID:      admin.ID,
+		TargetUserID: userID,
+		Action:       models.AuditActionSuspend,
+		Details:      reason,
+		CreatedAt:    now,
+	}); err != nil {
+		return nil, userNotFound(err)
+	}
+	user.SuspendedAt = &now
+
+	if err := s.tokenService.RevokeUserTokens(userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *UserAdminService) Reactivate(admin *models.User, userID int) (*models.User, error) {
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return nil, userNotFound(err)
+	}
+	if !user.Suspended() {
+		return user, nil
+	}
+
+	if err := s.userStorage.SetSuspended(userID, nil, &models.AuditEntry{
+		ActorID:      admin.ID,
+		TargetUserID: userID,
+		Action:       models.AuditActionReactivate,
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return nil, userNotFound(err)
+	}
+	user.SuspendedAt = nil
+	return user, nil
+}
+
+func (s *UserAdminService) AuditTrail(userID int) ([]*models.AuditEntry, error) {
+	if _, err := s.userStorage.UserByID(userID); err != nil {
+		return nil, userNotFound(err)
+	}
+	return s.auditStorage.EntriesForUser(userID)
+}
+
+func (s *UserAdminService) IsSuspended(userID int) (bool, error) {
+	user, err := s.userStorage.UserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.Suspended(), nil
+}
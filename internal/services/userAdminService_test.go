@@ -0,0 +1,96 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+	"louderspace/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestSearchUsers(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewUserAdminService(userStorage, userStorage.AuditLog, tokenService)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"carol", "alice", "bob", "alicia"} {
+		role := models.RoleFree
+		if name == "bob" {
+			role = models.RolePremium
+		}
+		assert.NoError(t, userStorage.Save(&models.User{Username: name, Email: name + "@example.com", Role: role, CreatedAt: start.AddDate(0, i, 0)}))
+	}
+
+	page, err := service.Search(models.UserFilter{Username: "ALI", Sort: models.UserSortUsername})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, "alice", page.Users[0].Username)
+	assert.Equal(t, "alicia", page.Users[1].Username)
+	assert.Equal(t, defaultUserPageSize, page.Limit)
+
+	page, err = service.Search(models.UserFilter{Role: models.RolePremium})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, "bob", page.Users[0].Username)
+
+	after, before := start.AddDate(0, 1, 0), start.AddDate(0, 3, 0)
+	page, err = service.Search(models.UserFilter{CreatedAfter: &after, CreatedBefore: &before})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+
+	page, err = service.Search(models.UserFilter{Sort: models.UserSortCreatedAt, Descending: true, Limit: 2, Offset: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, page.Total)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, "bob", page.Users[0].Username)
+	assert.Equal(t, "alice", page.Users[1].Username)
+
+	_, err = service.Search(models.UserFilter{Sort: "password"})
+	assert.ErrorIs(t, err, ErrInvalidUserFilter)
+	_, err = service.Search(models.UserFilter{Limit: maxUserPageSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidUserFilter)
+}
+
+func TestSuspendUser(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	tokenService := NewTokenService(repositories.NewMockTokenStorage(), userStorage, newTestKeySet(t), 15*time.Minute, time.Hour)
+	service := NewUserAdminService(userStorage, userStorage.AuditLog, tokenService)
+
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Role: models.RoleAdmin, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(admin))
+	user := &models.User{Username: "testuser", Email: "test@example.com", Role: models.RoleFree, CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	tokens, err := tokenService.IssueTokens(user, &models.Session{})
+	assert.NoError(t, err)
+
+	_, err = service.Suspend(admin, admin.ID, "")
+	assert.ErrorIs(t, err, ErrCannotSuspendSelf)
+
+	suspended, err := service.Suspend(admin, user.ID, "spam")
+	assert.NoError(t, err)
+	assert.True(t, suspended.Suspended())
+	isSuspended, err := service.IsSuspended(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, isSuspended)
+
+	_, _, err = tokenService.Refresh(tokens.RefreshToken)
+	assert.Error(t, err)
+
+	page, err := service.Search(models.UserFilter{Suspended: &isSuspended})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+
+	reactivated, err := service.Reactivate(admin, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, reactivated.Suspended())
+
+	trail, err := service.AuditTrail(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, trail, 2)
+	assert.Equal(t, models.AuditActionReactivate, trail[0].Action)
+	assert.Equal(t, models.AuditActionSuspend, trail[1].Action)
+	assert.Equal(t, "spam", trail[1].Details)
+	assert.Equal(t, admin.ID, trail[1].ActorID)
+}
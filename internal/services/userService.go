@@ -1,42 +1,57 @@
 package services
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"database/sql"
+	"errors"
+	"louderspace/internal/logger"
 	"louderspace/internal/models"
+	"louderspace/internal/passwords"
 	"louderspace/internal/repositories"
 	"time"
 )
 
+var (
+	ErrInvalidCredentials    = errors.New("invalid username or password")
+	ErrPasswordResetRequired = errors.New("the password of this account must be reset")
+	ErrUserNotFound          = errors.New("user not found")
+)
+
+// userNotFound maps sql.ErrNoRows to ErrUserNotFound.
+func userNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
 type UserManagement interface {
-	Register(username, password, email string, role models.Role) (*models.User, error)
+	// Register creates a user with the password, which must pass the password policy.
+	Register(username, password, email string) (*models.User, error)
+	// NewUser returns the free user Register would create, without saving it.
+	NewUser(username, password, email string) (*models.User, error)
+	// Login upgrades the stored hash when the hashing algorithm or cost has changed.
 	Login(username, password string) (*models.User, error)
 	User(userID int) (*models.User, error)
-	Users() ([]*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
 }
 
 type UserService struct {
-	userStorage repositories.UserStorage
+	userStorage    repositories.UserStorage
+	passwordPolicy *passwords.Policy
+	hasher         *passwords.Hasher
 }
 
-func NewUserService(userStorage repositories.UserStorage) UserManagement {
-	return &UserService{userStorage}
+func NewUserService(userStorage repositories.UserStorage, passwordPolicy *passwords.Policy, hasher *passwords.Hasher) UserManagement {
+	return &UserService{userStorage, passwordPolicy, hasher}
 }
 
-func (s *UserService) Register(username, password, email string, role models.Role) (*models.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// Register creates a free user. Other roles are granted through RoleManagement.
+func (s *UserService) Register(username, password, email string) (*models.User, error) {
+	user, err := s.NewUser(username, password, email)
 	if err != nil {
 		return nil, err
 	}
 
-	user := &models.User{
-		Username:  username,
-		Password:  string(hashedPassword),
-		Email:     email,
-		Role:      role,
-		CreatedAt: time.Now(),
-	}
-
 	if err := s.userStorage.Save(user); err != nil {
 		return nil, err
 	}
@@ -44,16 +59,59 @@ func (s *UserService) Register(username, password, email string, role models.Rol
 	return user, nil
 }
 
+func (s *UserService) NewUser(username, password, email string) (*models.User, error) {
+	if err := s.passwordPolicy.Validate(username, password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.User{
+		Username:  username,
+		Password:  hashedPassword,
+		Email:     email,
+		Role:      models.RoleFree,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// Login returns ErrPasswordResetRequired for accounts flagged by the double-hashing
+// migration, and unflags one whose password still matches.
 func (s *UserService) Login(username, password string) (*models.User, error) {
 	user, err := s.userStorage.UserByUsername(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	match, rehash, err := s.hasher.Verify(user.Password, password)
+	if err != nil {
 		return nil, err
 	}
+	if !match {
+		if user.PasswordResetRequired {
+			return nil, ErrPasswordResetRequired
+		}
+		return nil, ErrInvalidCredentials
+	}
 
+	if rehash || user.PasswordResetRequired {
+		// The password is right, so a failed upgrade only means trying again next time.
+		hashedPassword, err := s.hasher.Hash(password)
+		if err == nil {
+			err = s.userStorage.UpdatePassword(user.ID, hashedPassword)
+		}
+		if err != nil {
+			logger.Error("Failed to upgrade password hash for user", user.ID, err)
+		} else {
+			user.Password = hashedPassword
+			user.PasswordResetRequired = false
+		}
+	}
 	return user, nil
 }
 
@@ -61,10 +119,6 @@ func (s *UserService) User(userID int) (*models.User, error) {
 	return s.userStorage.UserByID(userID)
 }
 
-func (s *UserService) Users() ([]*models.User, error) {
-	return s.userStorage.Users()
-}
-
 func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 	return s.userStorage.UserByUsername(username)
 }
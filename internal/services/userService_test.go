@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"louderspace/internal/models"
+	"louderspace/internal/passwords"
 	"louderspace/internal/repositories"
 	"testing"
 	"time"
@@ -11,20 +12,21 @@ import (
 
 func TestRegister(t *testing.T) {
 	userStorage := repositories.NewMockUserStorage()
-	userService := NewUserService(userStorage)
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
 
-	user, err := userService.Register("testuser", "password123", "test@example.com", "free")
+	user, err := userService.Register("testuser", "Tr0ub4dor-and-3", "test@example.com")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.Equal(t, "testuser", user.Username)
 	assert.Equal(t, "test@example.com", user.Email)
-	assert.NotEqual(t, "password123", user.Password)
+	assert.NotEqual(t, "Tr0ub4dor-and-3", user.Password)
+	assert.Equal(t, models.RoleFree, user.Role)
 }
 
 func TestLogin(t *testing.T) {
 	userStorage := repositories.NewMockUserStorage()
-	userService := NewUserService(userStorage)
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	err := userStorage.Save(&models.User{
@@ -43,11 +45,80 @@ func TestLogin(t *testing.T) {
 	assert.NotNil(t, user)
 	assert.Equal(t, "testuser", user.Username)
 	assert.Equal(t, "test@example.com", user.Email)
+
+	_, err = userService.Login("testuser", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = userService.Login("nobody", "password123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestRegisterHashesOnce(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.NewHasher(passwords.NewBcrypt(bcrypt.MinCost)))
+
+	_, err := userService.Register("testuser", "Tr0ub4dor-and-3", "test@example.com")
+	assert.NoError(t, err)
+	_, err = userService.Login("testuser", "Tr0ub4dor-and-3")
+	assert.NoError(t, err)
+}
+
+func TestLoginUpgradesHash(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	argon2id := passwords.NewArgon2id(passwords.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1})
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.NewHasher(argon2id, passwords.NewBcrypt(bcrypt.MinCost)))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{Username: "testuser", Password: string(hashedPassword), Email: "test@example.com", CreatedAt: time.Now()}
+	assert.NoError(t, userStorage.Save(user))
+
+	_, err := userService.Login("testuser", "password123")
+	assert.NoError(t, err)
+	stored, _ := userStorage.UserByUsername("testuser")
+	assert.True(t, argon2id.Recognizes(stored.Password))
+
+	_, err = userService.Login("testuser", "password123")
+	assert.NoError(t, err)
+}
+
+func TestLoginRequiresPasswordReset(t *testing.T) {
+	userStorage := repositories.NewMockUserStorage()
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.NewHasher(passwords.NewBcrypt(bcrypt.MinCost)))
+
+	// How registration used to store passwords: a hash of a hash that was thrown away.
+	innerHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	doubleHashed, _ := bcrypt.GenerateFromPassword(innerHash, bcrypt.MinCost)
+	broken := &models.User{Username: "broken", Password: string(doubleHashed), Email: "broken@example.com", CreatedAt: time.Now().Add(-time.Hour)}
+	assert.NoError(t, userStorage.Save(broken))
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	seeded := &models.User{Username: "seeded", Password: string(hashedPassword), Email: "seeded@example.com", CreatedAt: time.Now().Add(-time.Hour)}
+	assert.NoError(t, userStorage.Save(seeded))
+	sso := &models.User{Username: "sso", Email: "sso@example.com", CreatedAt: time.Now().Add(-time.Hour)}
+	assert.NoError(t, userStorage.Save(sso))
+
+	flagged, err := userStorage.RequirePasswordReset(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, flagged)
+
+	_, err = userService.Login("broken", "password123")
+	assert.ErrorIs(t, err, ErrPasswordResetRequired)
+
+	// A correctly hashed account is unflagged by logging in.
+	_, err = userService.Login("seeded", "password123")
+	assert.NoError(t, err)
+	stored, _ := userStorage.UserByUsername("seeded")
+	assert.False(t, stored.PasswordResetRequired)
+	_, err = userService.Login("seeded", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// A new password clears the flag too.
+	assert.NoError(t, userStorage.UpdatePassword(broken.ID, string(hashedPassword)))
+	_, err = userService.Login("broken", "password123")
+	assert.NoError(t, err)
 }
 
 func TestUser(t *testing.T) {
 	userStorage := repositories.NewMockUserStorage()
-	userService := NewUserService(userStorage)
+	userService := NewUserService(userStorage, passwords.DefaultPolicy(), passwords.DefaultHasher())
 
 	savedUser := &models.User{
 		Username:  "testuser",
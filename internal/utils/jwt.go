@@ -1,38 +1,67 @@
 package utils
 
 import (
+	"fmt"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"louderspace/internal/models"
 )
 
-var jwtSecret = []byte("your_secret_key")
-
 type Claims struct {
 	UserID int         `json:"user_id"`
 	Role   models.Role `json:"role"`
+	// SessionID ties the token to the login it was issued for.
+	SessionID int `json:"sid,omitempty"`
+	// Generation is the user's token generation when the token was issued. Revoking the
+	// user's tokens bumps it, which rejects every token issued before, however recently.
+	Generation int `json:"gen,omitempty"`
 	jwt.StandardClaims
 }
 
-func GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+func (ks *KeySet) GenerateToken(user *models.User, sessionID, generation int, ttl time.Duration) (string, *Claims, error) {
+	jti, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
 	claims := &Claims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:     user.ID,
+		Role:       user.Role,
+		SessionID:  sessionID,
+		Generation: generation,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	signed, err := token.SignedString(ks.active.SignKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
+// ParseToken verifies a token with the key named by its "kid" header. The token's algorithm
+// must match the one configured for that key, so an RSA public key can never be used as an
+// HMAC secret.
+func (ks *KeySet) ParseToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.VerifyKey, nil
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"louderspace/internal/models"
+)
+
+func TestKeySetRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	keys := []*SigningKey{
+		{ID: "hmac", Method: jwt.SigningMethodHS256, SignKey: []byte("secret"), VerifyKey: []byte("secret")},
+		{ID: "rsa", Method: jwt.SigningMethodRS256, SignKey: rsaKey, VerifyKey: &rsaKey.PublicKey},
+		{ID: "ed", Method: SigningMethodEd25519, SignKey: edPrivate, VerifyKey: edPublic},
+	}
+	user := &models.User{ID: 7, Role: models.RolePremium}
+
+	for _, key := range keys {
+		ks, err := NewKeySet(key.ID, keys...)
+		assert.NoError(t, err)
+
+		token, _, err := ks.GenerateToken(user, 1, 0, time.Minute)
+		assert.NoError(t, err)
+
+		claims, err := ks.ParseToken(token)
+		assert.NoError(t, err, key.ID)
+		assert.Equal(t, 7, claims.UserID)
+		assert.Equal(t, models.RolePremium, claims.Role)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey := &SigningKey{ID: "old", Method: jwt.SigningMethodHS256, SignKey: []byte("old"), VerifyKey: []byte("old")}
+	newKey := &SigningKey{ID: "new", Method: jwt.SigningMethodHS256, SignKey: []byte("new"), VerifyKey: []byte("new")}
+	user := &models.User{ID: 1, Role: models.RoleFree}
+
+	before, err := NewKeySet("old", oldKey)
+	assert.NoError(t, err)
+	oldToken, _, err := before.GenerateToken(user, 1, 0, time.Minute)
+	assert.NoError(t, err)
+
+	// After rotation tokens signed with the old key stay valid while it is still listed.
+	after, err := NewKeySet("new", oldKey, newKey)
+	assert.NoError(t, err)
+	_, err = after.ParseToken(oldToken)
+	assert.NoError(t, err)
+
+	retired, err := NewKeySet("new", newKey)
+	assert.NoError(t, err)
+	_, err = retired.ParseToken(oldToken)
+	assert.Error(t, err)
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	verifyOnly := &SigningKey{ID: "rsa", Method: jwt.SigningMethodRS256, VerifyKey: &rsaKey.PublicKey}
+	signer := &SigningKey{ID: "hmac", Method: jwt.SigningMethodHS256, SignKey: []byte("secret"), VerifyKey: []byte("secret")}
+
+	_, err = NewKeySet("rsa", verifyOnly)
+	assert.Error(t, err)
+
+	ks, err := NewKeySet("hmac", signer, verifyOnly)
+	assert.NoError(t, err)
+
+	// An HS256 token claiming to be signed by the RSA key must not verify.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: 1})
+	forged.Header["kid"] = "rsa"
+	forgedString, err := forged.SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = ks.ParseToken(forgedString)
+	assert.Error(t, err)
+}
+
+func TestJWKSKeepsConfiguredOrder(t *testing.T) {
+	var keys []*SigningKey
+	for _, id := range []string{"c", "a", "d", "b"} {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		keys = append(keys, &SigningKey{ID: id, Method: SigningMethodEd25519, SignKey: private, VerifyKey: public})
+	}
+	keys = append(keys, &SigningKey{ID: "hmac", Method: jwt.SigningMethodHS256, SignKey: []byte("secret"), VerifyKey: []byte("secret")})
+	ks, err := NewKeySet("a", keys...)
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		var ids []string
+		for _, jwk := range ks.JWKS() {
+			ids = append(ids, jwk.KeyID)
+		}
+		assert.Equal(t, []string{"c", "a", "d", "b"}, ids)
+	}
+}
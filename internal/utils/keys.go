@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is one entry of a KeySet. Keys without a private part can only verify tokens,
+// which is how retired keys are kept around until the tokens they signed have expired.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.SignKey != nil
+}
+
+// KeySet signs tokens with its active key and verifies them with whichever key the token's
+// "kid" header names.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// ordered holds the keys in the order they were configured, which JWKS keeps.
+	ordered []*SigningKey
+}
+
+func NewKeySet(activeKeyID string, keys ...*SigningKey) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("signing key is missing an ID")
+		}
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate signing key ID %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.ordered = append(ks.ordered, key)
+	}
+
+	active, ok := ks.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found", activeKeyID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active signing key %q has no private key", activeKeyID)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+// LoadSigningKey builds a key for the given JWS algorithm. HS256 keys use secret; RS256 and
+// EdDSA keys are read from PEM files, and privateKeyFile may be empty for verify-only keys.
+func LoadSigningKey(id, alg, secret, privateKeyFile, publicKeyFile string) (*SigningKey, error) {
+	key := &SigningKey{ID: id}
+
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		if secret == "" {
+			return nil, fmt.Errorf("key %q: HS256 requires a secret", id)
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.SignKey = []byte(secret)
+		key.VerifyKey = []byte(secret)
+		return key, nil
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+	case SigningMethodEd25519.Alg():
+		key.Method = SigningMethodEd25519
+	default:
+		return nil, fmt.Errorf("key %q: unsupported algorithm %q", id, alg)
+	}
+
+	if privateKeyFile != "" {
+		privateKey, err := readPEMKey(privateKeyFile, x509.ParsePKCS8PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key %q: private key cannot sign", id)
+		}
+		key.SignKey = privateKey
+		key.VerifyKey = signer.Public()
+	}
+
+	if publicKeyFile != "" {
+		publicKey, err := readPEMKey(publicKeyFile, x509.ParsePKIXPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		key.VerifyKey = publicKey
+	}
+
+	if key.VerifyKey == nil {
+		return nil, fmt.Errorf("key %q: %s requires a private or public key file", id, alg)
+	}
+	if err := checkKeyType(key); err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+	return key, nil
+}
+
+func readPEMKey(path string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	return parse(block.Bytes)
+}
+
+func checkKeyType(key *SigningKey) error {
+	switch key.Method {
+	case jwt.SigningMethodRS256:
+		if _, ok := key.VerifyKey.(*rsa.PublicKey); !ok {
+			return errors.New("RS256 requires an RSA key")
+		}
+	case SigningMethodEd25519:
+		if _, ok := key.VerifyKey.(ed25519.PublicKey); !ok {
+			return errors.New("EdDSA requires an Ed25519 key")
+		}
+	}
+	return nil
+}
+
+// JWK is the public half of a signing key in RFC 7517 form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS lists the public keys of the set so other services can verify tokens without the
+// private keys, in the configured order so the document is stable. Symmetric keys are never
+// published.
+func (ks *KeySet) JWKS() []JWK {
+	jwks := []JWK{}
+	for _, key := range ks.ordered {
+		switch publicKey := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The SHA-1 test vectors from RFC 6238 appendix B.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, expected := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)), 8)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+
+	code, _ := TOTPCode(secret, TOTPStep(now), TOTPDigits)
+	step, ok := ValidateTOTP(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	// A code from the previous period is still accepted, one from two periods ago is not.
+	previous, _ := TOTPCode(secret, TOTPStep(now)-1, TOTPDigits)
+	_, ok = ValidateTOTP(secret, previous, now)
+	assert.True(t, ok)
+	stale, _ := TOTPCode(secret, TOTPStep(now)-2, TOTPDigits)
+	_, ok = ValidateTOTP(secret, stale, now)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("Louderspace", "alice", "JBSWY3DPEHPK3PXP"))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Louderspace:alice", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "Louderspace", uri.Query().Get("issuer"))
+}
@@ -1,12 +1,25 @@
+-- pg_trgm matches misspelled search terms by trigram similarity.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE IF NOT EXISTS users (
                                      id SERIAL PRIMARY KEY,
                                      username VARCHAR(50) UNIQUE NOT NULL,
-    password VARCHAR(100) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    password_reset_required BOOLEAN NOT NULL DEFAULT FALSE,
     email VARCHAR(100) UNIQUE NOT NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    role VARCHAR(50) NOT NULL DEFAULT 'free'
+    role VARCHAR(50) NOT NULL DEFAULT 'free',
+    suspended_at TIMESTAMP,
+    deleted_at TIMESTAMP
     );
 
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+
 CREATE TABLE IF NOT EXISTS songs (
                                      id SERIAL PRIMARY KEY,
                                      title VARCHAR(100) NOT NULL,
@@ -14,9 +27,36 @@ CREATE TABLE IF NOT EXISTS songs (
     genre VARCHAR(50),
     suno_id VARCHAR(50) UNIQUE NOT NULL,
     is_generated BOOLEAN NOT NULL DEFAULT FALSE,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    duration_seconds REAL NOT NULL DEFAULT 0,
+    audio_url TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    image_large_url TEXT NOT NULL DEFAULT '',
+    model_version VARCHAR(50) NOT NULL DEFAULT '',
+    bpm INT CHECK (bpm > 0),
+    musical_key VARCHAR(20) NOT NULL DEFAULT '',
+    energy REAL CHECK (energy BETWEEN 0 AND 1),
+    lyrics TEXT NOT NULL DEFAULT '',
+    search_vector TSVECTOR -- set by song_search_vector whenever the song or its tags change
     );
 
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS duration_seconds REAL NOT NULL DEFAULT 0;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS image_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS image_large_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS model_version VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS bpm INT CHECK (bpm > 0);
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS musical_key VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS energy REAL CHECK (energy BETWEEN 0 AND 1);
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS lyrics TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE INDEX IF NOT EXISTS idx_songs_title ON songs(title, id);
+CREATE INDEX IF NOT EXISTS idx_songs_created_at ON songs(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_songs_title_trgm ON songs USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_artist_trgm ON songs USING GIN (artist gin_trgm_ops);
+
 CREATE TABLE IF NOT EXISTS tags (
                                     id SERIAL PRIMARY KEY,
                                     name VARCHAR(50) UNIQUE NOT NULL
@@ -28,6 +68,21 @@ CREATE TABLE IF NOT EXISTS song_tags (
     tag_id INT NOT NULL REFERENCES tags(id)
     );
 
+CREATE INDEX IF NOT EXISTS idx_tags_name_trgm ON tags USING GIN (name gin_trgm_ops);
+
+-- song_search_vector weighs a song's title above its artist, and both above its genre and
+-- tag names. The 'simple' configuration does not stem, so names and tags match as written.
+CREATE OR REPLACE FUNCTION song_search_vector(song INT) RETURNS TSVECTOR AS $$
+SELECT setweight(to_tsvector('simple', s.title), 'A') ||
+       setweight(to_tsvector('simple', COALESCE(s.artist, '')), 'B') ||
+       setweight(to_tsvector('simple', COALESCE(s.genre, '') || ' ' || COALESCE(
+           (SELECT string_agg(t.name, ' ') FROM song_tags st JOIN tags t ON st.tag_id = t.id WHERE st.song_id = s.id), '')), 'C')
+FROM songs s
+WHERE s.id = song
+$$ LANGUAGE SQL STABLE;
+
+UPDATE songs SET search_vector = song_search_vector(id) WHERE search_vector IS NULL;
+
 CREATE TABLE IF NOT EXISTS feedback (
                                         id SERIAL PRIMARY KEY,
                                         user_id INT NOT NULL REFERENCES users(id),
@@ -54,9 +109,24 @@ CREATE TABLE IF NOT EXISTS playlists (
 CREATE TABLE IF NOT EXISTS stations (
                                         id SERIAL PRIMARY KEY,
                                         name VARCHAR(100) NOT NULL,
-    tags TEXT NOT NULL -- storing tags as a comma-separated string
+    tags TEXT NOT NULL, -- storing tags as a comma-separated string
+    premium BOOLEAN NOT NULL DEFAULT FALSE,
+    owner_id INT REFERENCES users(id), -- set on personal stations
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', replace(tags, ',', ' ')), 'C')
+    ) STORED
     );
 
+ALTER TABLE stations ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', replace(tags, ',', ' ')), 'C')
+) STORED;
+
+ALTER TABLE stations ADD COLUMN IF NOT EXISTS premium BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE stations ADD COLUMN IF NOT EXISTS owner_id INT REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_stations_search_vector ON stations USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_stations_name_trgm ON stations USING GIN (name gin_trgm_ops);
+
 CREATE TABLE plays (
                        id SERIAL PRIMARY KEY,
                        user_id INT REFERENCES users(id),
@@ -80,6 +150,23 @@ CREATE TABLE song_play_aggregates (
                                       PRIMARY KEY (song_id)
 );
 
+CREATE TABLE IF NOT EXISTS play_events (
+                                           id SERIAL PRIMARY KEY,
+                                           user_id INT REFERENCES users(id), -- NULL once the user deleted their account
+    song_id INT NOT NULL REFERENCES songs(id),
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    duration INT NOT NULL, -- duration in seconds
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS song_play_counts (
+                                                song_id INT PRIMARY KEY REFERENCES songs(id),
+    play_count INT NOT NULL DEFAULT 0,
+    total_duration INT NOT NULL DEFAULT 0,
+    last_played TIMESTAMP
+    );
+
 CREATE TABLE IF NOT EXISTS pomodoro_sessions (
                                                  id SERIAL PRIMARY KEY,
                                                  user_id INT NOT NULL REFERENCES users(id),
@@ -88,4 +175,175 @@ CREATE TABLE IF NOT EXISTS pomodoro_sessions (
     duration INT NOT NULL,
     break_duration INT NOT NULL,
     status VARCHAR(20) NOT NULL
-    );
\ No newline at end of file
+    );
+
+CREATE TABLE IF NOT EXISTS sessions (
+                                        id SERIAL PRIMARY KEY,
+                                        user_id INT NOT NULL REFERENCES users(id),
+    device_name VARCHAR(100) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    access_jti VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+    );
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS access_jti VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+                                              id SERIAL PRIMARY KEY,
+                                              user_id INT NOT NULL REFERENCES users(id),
+                                              session_id INT REFERENCES sessions(id),
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+    );
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id INT REFERENCES sessions(id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+                                              jti VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    expires_at TIMESTAMP NOT NULL
+    );
+
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+                                                      user_id INT PRIMARY KEY REFERENCES users(id),
+    revoked_at TIMESTAMP NOT NULL,
+    generation INT NOT NULL DEFAULT 0 -- carried in access tokens; bumped on every revocation
+    );
+
+ALTER TABLE user_token_revocations ADD COLUMN IF NOT EXISTS generation INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+                                         id SERIAL PRIMARY KEY,
+                                         actor_id INT REFERENCES users(id),
+    target_user_id INT NOT NULL REFERENCES users(id),
+    action VARCHAR(50) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+ALTER TABLE audit_log ALTER COLUMN actor_id DROP NOT NULL; -- NULL for changes no user made
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target_user_id ON audit_log(target_user_id);
+
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+                                               id SERIAL PRIMARY KEY,
+                                               user_id INT NOT NULL REFERENCES users(id),
+    purpose VARCHAR(30) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+                                              key VARCHAR(150) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS api_keys (
+                                        id SERIAL PRIMARY KEY,
+                                        user_id INT NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT NOT NULL, -- storing scopes as a comma-separated string
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS two_factor (
+                                          user_id INT PRIMARY KEY REFERENCES users(id),
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+                                              id SERIAL PRIMARY KEY,
+                                              user_id INT NOT NULL REFERENCES users(id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS two_factor_required_roles (
+                                                         role VARCHAR(50) PRIMARY KEY
+    );
+
+CREATE TABLE IF NOT EXISTS identities (
+                                          id SERIAL PRIMARY KEY,
+                                          user_id INT NOT NULL REFERENCES users(id),
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities(user_id);
+
+CREATE TABLE IF NOT EXISTS sso_login_states (
+                                                id SERIAL PRIMARY KEY,
+                                                provider VARCHAR(50) NOT NULL,
+    state_hash VARCHAR(64) UNIQUE NOT NULL,
+    binding_hash VARCHAR(64) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP
+    );
+
+ALTER TABLE sso_login_states ADD COLUMN IF NOT EXISTS binding_hash VARCHAR(64) NOT NULL DEFAULT ''; -- older states cannot be bound and fail
+
+CREATE TABLE IF NOT EXISTS invites (
+                                       id SERIAL PRIMARY KEY,
+                                       prefix VARCHAR(20) NOT NULL,
+    code_hash VARCHAR(64) UNIQUE NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'free',
+    note TEXT NOT NULL DEFAULT '',
+    max_uses INT NOT NULL DEFAULT 1, -- 0 means unlimited
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_by INT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+                                             id SERIAL PRIMARY KEY,
+                                             user_id INT NOT NULL REFERENCES users(id),
+    provider_subscription_id VARCHAR(255) UNIQUE NOT NULL,
+    provider_customer_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    premium_until TIMESTAMP NOT NULL,
+    canceled_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    last_event_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_lapsed ON subscriptions(premium_until) WHERE ended_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS billing_events (
+                                              id VARCHAR(255) PRIMARY KEY,
+                                              type VARCHAR(100) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
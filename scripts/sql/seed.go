@@ -29,7 +29,7 @@ func main() {
 		fmt.Println(value)
 	}
 
-	fmt.Printf("host=%s port=%d user=%s ", host, port, user)
+	fmt.Printf("host=%s port=%s user=%s ", host, port, user)
 
 	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s "+
 		"password=%s dbname=%s",
@@ -209,6 +209,11 @@ func seedSongTags(db *sql.DB) error {
 		}
 	}
 
+	// The app keeps search vectors up to date, but the seed inserts songs directly.
+	if _, err := db.Exec("UPDATE songs SET search_vector = song_search_vector(id)"); err != nil {
+		return fmt.Errorf("failed to index songs for search: %v", err)
+	}
+
 	return nil
 }
 
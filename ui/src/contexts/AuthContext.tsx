@@ -13,7 +13,7 @@ interface User {
 interface AuthContextType {
     user: User | null;
     login: (username: string, password: string) => Promise<void>;
-    register: (username: string, password: string, email: string, role: string) => Promise<void>;
+    register: (username: string, password: string, email: string) => Promise<void>;
     logout: () => void;
     loading: boolean;
 }
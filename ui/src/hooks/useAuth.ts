@@ -1,8 +1,8 @@
 // src/hooks/useAuth.ts
 import { useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { login as loginApi, register as registerApi } from '../services/authApi';
-import api from '../services/api';
+import { login as loginApi, register as registerApi, logout as logoutApi } from '../services/authApi';
+import api, { csrfToken } from '../services/api';
 
 interface User {
     id: number;
@@ -18,8 +18,10 @@ const useAuth = () => {
 
     useEffect(() => {
         const token = localStorage.getItem('token');
-        if (token) {
-            api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
+        if (token || csrfToken()) {
+            if (token) {
+                api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
+            }
             api.get('/me')
                 .then(response => {
                     setUser(response.data);
@@ -27,6 +29,7 @@ const useAuth = () => {
                 })
                 .catch(() => {
                     localStorage.removeItem('token');
+                    localStorage.removeItem('refresh_token');
                     setLoading(false); // Ensure this is set even on error
                 });
         } else {
@@ -36,14 +39,18 @@ const useAuth = () => {
 
     const login = async (username: string, password: string) => {
         const response = await loginApi(username, password);
-        localStorage.setItem('token', response.data.token);
-        api.defaults.headers.common['Authorization'] = `Bearer ${response.data.token}`;
+        // In cookie mode the tokens are not in the response; the browser holds them as cookies.
+        if (response.data.token) {
+            localStorage.setItem('token', response.data.token);
+            localStorage.setItem('refresh_token', response.data.refresh_token);
+            api.defaults.headers.common['Authorization'] = `Bearer ${response.data.token}`;
+        }
         setUser(response.data.user);
         navigate('/');
     };
 
-    const register = async (username: string, password: string, email: string, role: string) => {
-        const response = await registerApi(username, password, email, role);
+    const register = async (username: string, password: string, email: string) => {
+        const response = await registerApi(username, password, email);
         localStorage.setItem('token', response.data.token);
         api.defaults.headers.common['Authorization'] = `Bearer ${response.data.token}`;
         setUser(response.data.user);
@@ -51,7 +58,9 @@ const useAuth = () => {
     };
 
     const logout = () => {
+        logoutApi(localStorage.getItem('refresh_token')).catch(() => undefined);
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         delete api.defaults.headers.common['Authorization'];
         setUser(null);
         navigate('/login');
@@ -32,14 +32,21 @@ const useSongs = () => {
 
             setLoading(true);
             try {
-                let songsResponse;
                 if (stationId) {
-                    songsResponse = await getSongsByStation(stationId, userId);
+                    const songsResponse = await getSongsByStation(stationId, userId);
+                    setSongs(songsResponse.data);
                 } else {
-                    songsResponse = await getSongs();
+                    // /songs returns one page at a time; follow next_cursor until the last page.
+                    let allSongs: Song[] = [];
+                    let cursor: string | undefined;
+                    do {
+                        const songsResponse = await getSongs(cursor);
+                        allSongs = allSongs.concat(songsResponse.data.songs);
+                        cursor = songsResponse.data.next_cursor;
+                    } while (cursor);
+                    setSongs(allSongs);
                 }
                 const tagsResponse = await getTags();
-                setSongs(songsResponse.data);
                 setTags(tagsResponse.data);
             } catch (error) {
                 setError('Failed to fetch songs or tags');
@@ -1,15 +1,22 @@
 // src/hooks/useUsers.ts
 import { useEffect, useState } from 'react';
-import { getUsers } from '../services/userApi';
+import { getUsers, updateUserRole } from '../services/userApi';
+
+export interface User {
+    id: number;
+    username: string;
+    email: string;
+    role: string;
+}
 
 const useUsers = () => {
-    const [users, setUsers] = useState([]);
+    const [users, setUsers] = useState<User[]>([]);
     const [loading, setLoading] = useState(true);
 
     useEffect(() => {
         getUsers()
             .then(response => {
-                setUsers(response.data);
+                setUsers(response.data.users);
                 setLoading(false);
             })
             .catch(error => {
@@ -18,7 +25,16 @@ const useUsers = () => {
             });
     }, []);
 
-    return { users, loading };
+    const changeRole = async (id: number, role: string) => {
+        try {
+            const response = await updateUserRole(id, role);
+            setUsers(users.map(user => (user.id === id ? response.data : user)));
+        } catch (error) {
+            console.error('Failed to change user role', error);
+        }
+    };
+
+    return { users, loading, changeRole };
 };
 
 export default useUsers;
@@ -1,11 +1,14 @@
 // src/pages/UsersPage.tsx
 import React from 'react';
-import Navbar from '../components/layout/Navbar';
 import useUsers from '../hooks/useUsers';
-import { CircularProgress, List, ListItem, ListItemText } from '@mui/material';
+import { useAuthContext } from '../contexts/AuthContext';
+import { CircularProgress, List, ListItem, ListItemText, MenuItem, Select } from '@mui/material';
+
+const roles = ['free', 'premium', 'analyst', 'admin'];
 
 const UsersPage: React.FC = () => {
-    const { users, loading } = useUsers();
+    const { users, loading, changeRole } = useUsers();
+    const { user: currentUser } = useAuthContext();
 
     return (
         <div>
@@ -15,9 +18,19 @@ const UsersPage: React.FC = () => {
         <CircularProgress />
     ) : (
         <List>
-            {users.map((user: any) => (
+            {users.map(user => (
                     <ListItem key={user.id}>
                     <ListItemText primary={user.username} secondary={user.email} />
+                    <Select
+                        size="small"
+                        value={user.role}
+                        disabled={currentUser?.id === user.id}
+                        onChange={event => changeRole(user.id, event.target.value as string)}
+                    >
+                        {roles.map(role => (
+                            <MenuItem key={role} value={role}>{role}</MenuItem>
+                        ))}
+                    </Select>
     </ListItem>
     ))}
         </List>
@@ -3,6 +3,50 @@ import axios from 'axios';
 
 const api = axios.create({
     baseURL: 'http://localhost:8080', // Adjust the base URL as needed
+    withCredentials: true, // send the session cookies when the server runs in cookie mode
 });
 
+// In cookie mode the server sets a readable csrf_token cookie that has to be echoed back on
+// every state-changing request.
+export const csrfToken = (): string | null => {
+    const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]+)/);
+    return match ? decodeURIComponent(match[1]) : null;
+};
+
+api.interceptors.request.use(config => {
+    const token = csrfToken();
+    if (token && config.method && !['get', 'head', 'options'].includes(config.method.toLowerCase())) {
+        config.headers['X-CSRF-Token'] = token;
+    }
+    return config;
+});
+
+// Access tokens are short-lived: on a 401, exchange the refresh token once and retry. In
+// cookie mode the refresh token travels as an HttpOnly cookie instead of the body.
+api.interceptors.response.use(
+    response => response,
+    async error => {
+        const original = error.config;
+        const refreshToken = localStorage.getItem('refresh_token');
+        if (error.response?.status !== 401 || original._retry || (!refreshToken && !csrfToken()) || original.url === '/token/refresh') {
+            return Promise.reject(error);
+        }
+        original._retry = true;
+        try {
+            const response = await api.post('/token/refresh', refreshToken ? { refresh_token: refreshToken } : {});
+            if (response.data.token) {
+                localStorage.setItem('token', response.data.token);
+                localStorage.setItem('refresh_token', response.data.refresh_token);
+                api.defaults.headers.common['Authorization'] = `Bearer ${response.data.token}`;
+                original.headers['Authorization'] = `Bearer ${response.data.token}`;
+            }
+            return api(original);
+        } catch (refreshError) {
+            localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
+            return Promise.reject(refreshError);
+        }
+    }
+);
+
 export default api;
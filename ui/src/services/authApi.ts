@@ -4,6 +4,8 @@ import api from './api';
 export const login = (username: string, password: string) =>
     api.post('/login', { username, password });
 
-export const register = (username: string, password: string, email: string, role: string) =>
-    api.post('/register', { username, password, email, role });
+export const register = (username: string, password: string, email: string) =>
+    api.post('/register', { username, password, email });
 
+export const logout = (refreshToken: string | null) =>
+    api.post('/logout', { refresh_token: refreshToken ?? '' });
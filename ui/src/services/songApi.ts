@@ -1,7 +1,7 @@
 import api from './api';
 
 // API calls related to songs
-export const getSongs = () => api.get('/songs');
+export const getSongs = (cursor?: string) => api.get('/songs', { params: cursor ? { cursor } : {} });
 export const getSongsByStation = (stationId: string, userId?: number) => {
     const url = userId ? `/stations/${stationId}/songs?user_id=${userId}` : `/stations/${stationId}/songs`;
     return api.get(url);
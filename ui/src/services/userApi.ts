@@ -3,4 +3,6 @@ import api from './api';
 
 export const getUsers = () => api.get('/admin/users');
 
+export const updateUserRole = (id: number, role: string) => api.put(`/admin/users/${id}/role`, { role });
+
 // You can add more user-related API calls here